@@ -0,0 +1,25 @@
+package config_handlers
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// AirQualityProvidersConfig is the air_quality section of config.yaml.
+// Providers are tried in order until one returns a forecast.
+type AirQualityProvidersConfig struct {
+	AirQuality struct {
+		Providers []string `yaml:"providers"`
+	} `yaml:"air_quality"`
+}
+
+func LoadAirQualityProvidersConfig(filePath string) (AirQualityProvidersConfig, error) {
+
+	var config AirQualityProvidersConfig
+	yamlFile, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return config, err
+	}
+	err = yaml.Unmarshal(yamlFile, &config)
+	return config, err
+}
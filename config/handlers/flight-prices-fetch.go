@@ -0,0 +1,24 @@
+package config_handlers
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// FlightPricesFetchConfig is the flight_prices section of config.yaml
+type FlightPricesFetchConfig struct {
+	FlightPrices struct {
+		CallBudget int `yaml:"call_budget"`
+	} `yaml:"flight_prices"`
+}
+
+func LoadFlightPricesFetchConfig(filePath string) (FlightPricesFetchConfig, error) {
+
+	var config FlightPricesFetchConfig
+	yamlFile, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return config, err
+	}
+	err = yaml.Unmarshal(yamlFile, &config)
+	return config, err
+}
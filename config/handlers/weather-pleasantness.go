@@ -3,14 +3,66 @@ package config_handlers
 // Configs and input handlers go here
 
 import (
+	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"sort"
 )
 
 type WeatherPleasantnessConfig struct {
+	Conditions     map[string]float64    `yaml:"conditions"`
+	DefaultProfile string                `yaml:"default_profile"`
+	Profiles       map[string]WPIProfile `yaml:"profiles"`
+}
+
+// WPIProfile is one complete weather pleasantness model. Every component scores
+// 0 to 10 and the WPI is their weighted average.
+type WPIProfile struct {
+	Label         string       `yaml:"label"`
+	Weights       WPIWeights   `yaml:"weights"`
+	Temperature   []CurvePoint `yaml:"temperature"`   // °C
+	Wind          []CurvePoint `yaml:"wind"`          // m/s
+	Precipitation []CurvePoint `yaml:"precipitation"` // probability, 0 to 100
+	Humidity      []CurvePoint `yaml:"humidity"`      // relative humidity, 0 to 100
+	CloudCover    []CurvePoint `yaml:"cloud_cover"`   // percent of sky, 0 to 100
+	// Overrides the shared condition scores for this profile only
 	Conditions map[string]float64 `yaml:"conditions"`
 }
 
+type WPIWeights struct {
+	Temperature   float64 `yaml:"temperature"`
+	Wind          float64 `yaml:"wind"`
+	Condition     float64 `yaml:"condition"`
+	Precipitation float64 `yaml:"precipitation"`
+	Humidity      float64 `yaml:"humidity"`
+	CloudCover    float64 `yaml:"cloud_cover"`
+}
+
+// CurvePoint is a breakpoint of a piecewise linear score curve.
+// Scores are interpolated between points and held flat beyond the first and last.
+type CurvePoint struct {
+	Value float64 `yaml:"value"`
+	Score float64 `yaml:"score"`
+}
+
+// DefaultWPIProfile is the model used when weatherPleasantness.yaml defines no profiles
+func DefaultWPIProfile() WPIProfile {
+	return WPIProfile{
+		Weights: WPIWeights{Temperature: 5, Wind: 1, Condition: 2},
+		Temperature: []CurvePoint{
+			{Value: 5, Score: 0},
+			{Value: 18, Score: 7},
+			{Value: 22, Score: 10},
+			{Value: 26, Score: 10},
+			{Value: 40, Score: 0},
+		},
+		Wind: []CurvePoint{
+			{Value: 0, Score: 10},
+			{Value: 13.8, Score: 0},
+		},
+	}
+}
+
 func LoadWeatherPleasantnessConfig(filePath string) (WeatherPleasantnessConfig, error) {
 
 	var config WeatherPleasantnessConfig
@@ -19,5 +71,121 @@ func LoadWeatherPleasantnessConfig(filePath string) (WeatherPleasantnessConfig,
 		return config, err
 	}
 	err = yaml.Unmarshal(yamlFile, &config)
-	return config, err
+	if err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+// ProfileNames lists the configured profiles in alphabetical order
+func (c WeatherPleasantnessConfig) ProfileNames() []string {
+	if len(c.Profiles) == 0 {
+		return []string{"default"}
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile with the shared condition scores merged in.
+// An empty name selects default_profile.
+func (c WeatherPleasantnessConfig) Profile(name string) (WPIProfile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+
+	var profile WPIProfile
+	if len(c.Profiles) == 0 {
+		if name != "" && name != "default" {
+			return profile, fmt.Errorf("unknown weather profile %q", name)
+		}
+		profile = DefaultWPIProfile()
+	} else {
+		var ok bool
+		profile, ok = c.Profiles[name]
+		if !ok {
+			return profile, fmt.Errorf("unknown weather profile %q", name)
+		}
+	}
+
+	conditions := make(map[string]float64, len(c.Conditions)+len(profile.Conditions))
+	for cond, score := range c.Conditions {
+		conditions[cond] = score
+	}
+	for cond, score := range profile.Conditions {
+		conditions[cond] = score
+	}
+	profile.Conditions = conditions
+	return profile, nil
+}
+
+// Validate checks that every profile can produce a WPI between 0 and 10
+func (c WeatherPleasantnessConfig) Validate() error {
+	if len(c.Profiles) > 0 {
+		if c.DefaultProfile == "" {
+			return fmt.Errorf("default_profile must be set when profiles are defined")
+		}
+		if _, ok := c.Profiles[c.DefaultProfile]; !ok {
+			return fmt.Errorf("default_profile %q is not defined", c.DefaultProfile)
+		}
+	}
+	for cond, score := range c.Conditions {
+		if score < 0 || score > 10 {
+			return fmt.Errorf("condition %s: score %.1f is outside 0 to 10", cond, score)
+		}
+	}
+
+	for name, profile := range c.Profiles {
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (p WPIProfile) Validate() error {
+	w := p.Weights
+	for _, weight := range []float64{w.Temperature, w.Wind, w.Condition, w.Precipitation, w.Humidity, w.CloudCover} {
+		if weight < 0 {
+			return fmt.Errorf("weights must not be negative")
+		}
+	}
+	if w.Temperature+w.Wind+w.Condition+w.Precipitation+w.Humidity+w.CloudCover == 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+
+	curves := []struct {
+		name   string
+		weight float64
+		points []CurvePoint
+	}{
+		{"temperature", w.Temperature, p.Temperature},
+		{"wind", w.Wind, p.Wind},
+		{"precipitation", w.Precipitation, p.Precipitation},
+		{"humidity", w.Humidity, p.Humidity},
+		{"cloud_cover", w.CloudCover, p.CloudCover},
+	}
+	for _, curve := range curves {
+		if curve.weight > 0 && len(curve.points) == 0 {
+			return fmt.Errorf("%s has a weight but no curve", curve.name)
+		}
+		for i, point := range curve.points {
+			if point.Score < 0 || point.Score > 10 {
+				return fmt.Errorf("%s: score %.1f is outside 0 to 10", curve.name, point.Score)
+			}
+			if i > 0 && point.Value <= curve.points[i-1].Value {
+				return fmt.Errorf("%s: breakpoints must be in increasing order", curve.name)
+			}
+		}
+	}
+
+	for cond, score := range p.Conditions {
+		if score < 0 || score > 10 {
+			return fmt.Errorf("condition %s: score %.1f is outside 0 to 10", cond, score)
+		}
+	}
+	return nil
 }
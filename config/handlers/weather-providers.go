@@ -0,0 +1,26 @@
+package config_handlers
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// WeatherProvidersConfig is the weather section of config.yaml.
+// Providers are tried in order until one returns a forecast.
+type WeatherProvidersConfig struct {
+	Weather struct {
+		Providers   []string `yaml:"providers"`
+		FixturesDir string   `yaml:"fixtures_dir"`
+	} `yaml:"weather"`
+}
+
+func LoadWeatherProvidersConfig(filePath string) (WeatherProvidersConfig, error) {
+
+	var config WeatherProvidersConfig
+	yamlFile, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return config, err
+	}
+	err = yaml.Unmarshal(yamlFile, &config)
+	return config, err
+}
@@ -47,6 +47,12 @@ func main() {
 	cleanup := SetupServer("./data/compiled/main.db", fileLogger)
 	defer cleanup()
 
+	// Which destinations visitors are shown, so the price fetcher can price those first
+	if err := backend.OpenSearchStats(backend.SearchStatsPath); err != nil {
+		log.Printf("Not recording search statistics: %v", err)
+	}
+	defer backend.CloseSearchStats()
+
 	// On web server, every 2 hours, check for a new database delivery, and swap dbs accordingly
 	fmt.Printf("Flag? Value: %v\n", *webFlag)
 	if *webFlag {
@@ -72,6 +78,11 @@ func SetupServer(db_path string, logger io.Writer) func() {
 	// Load city-country pairs into memory searchbar to use
 	backend.LoadCityCountryPairs(db)
 
+	// Weather profiles visitors can rank destinations by, scored as the compiled WPI is
+	if err := backend.LoadWeatherProfiles("./config/weatherPleasantness.yaml"); err != nil {
+		log.Fatalf("Failed to load weather profiles: %v", err)
+	}
+
 	cleanup := func() {
 		if db != nil {
 			db.Close()
@@ -113,12 +124,20 @@ func filterRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Personal weather profile from the form, or the one saved in the session
+	input.WeatherProfile, err = backend.WeatherProfileFromRequest(r, session)
+	if err != nil {
+		backend.HandleHTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Execute Main Query to Populate Destination Cards
 	flights, err := backend.ExecuteMainQuery(input)
 	if err != nil {
 		backend.HandleHTTPError(w, "Error executing main query", http.StatusInternalServerError)
 		return
 	}
+	backend.RecordSearchAppearances(flights)
 
 	//  Execute Second Query to Populate Accommodation Price Slider Histogram
 	allAccomPrices, err := backend.ExecuteAccommodationPricesHistogramQuery(input)
@@ -144,11 +163,9 @@ func filterRequestHandler(w http.ResponseWriter, r *http.Request) {
 
 		for _, flight := range flightsPriceHistogramData {
 			if flight.UrlCity1 == city {
+				// Flights without a price have nothing to add to the histogram
 				if flight.PriceCity1.Valid {
 					flightPricesForCity = append(flightPricesForCity, flight.PriceCity1.Float64)
-				} else {
-					// Optionally append 0.0 or skip if invalid.
-					flightPricesForCity = append(flightPricesForCity, 0.0)
 				}
 			}
 		}
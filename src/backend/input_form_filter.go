@@ -13,8 +13,75 @@ type FilterInput struct {
 	LogicalOperators      []string
 	MaxFlightPrices       []float64
 	MaxAccommodationPrice float64
+	MaxAQI                int     // 1 Good to 5 Very Poor; 0 for no limit
+	MinDaylightHours      float64 // Average over the forecast days; 0 for no limit
+	OnlyObservedPrices    bool    // Leave out flights whose price is only a prediction
+	PriceBound            PriceBound
+	AccommodationTier     AccommodationTier
 	OrderClause           string
 	LogicalExpression     Expression
+	WeatherProfile        WeatherProfile
+}
+
+// PriceBound is which end of an estimated price's range the flight price limits are matched against
+type PriceBound string
+
+const (
+	PriceBoundEstimate     PriceBound = "estimate"     // The predicted price itself
+	PriceBoundOptimistic   PriceBound = "optimistic"   // The low end, to see everything that might be affordable
+	PriceBoundConservative PriceBound = "conservative" // The high end, to see only what very likely is
+)
+
+// column is the flight price to compare with the limits. Observed prices and predictions
+// without a range are compared as they are.
+func (b PriceBound) column() string {
+	switch b {
+	case PriceBoundOptimistic:
+		return "COALESCE(f.price_low, f.price_next_week)"
+	case PriceBoundConservative:
+		return "COALESCE(f.price_high, f.price_next_week)"
+	default:
+		return "f.price_next_week"
+	}
+}
+
+// AccommodationTier is which of a destination's accommodation prices the limits, trip totals
+// and histograms use
+type AccommodationTier string
+
+const (
+	AccommodationTierStandard AccommodationTier = "standard"  // Places reviewed above 7, booking_pppn
+	AccommodationTierBudget   AccommodationTier = "budget"    // The cheapest third of places
+	AccommodationTierMidRange AccommodationTier = "mid_range" // The middle third, reviewed 8 or more
+	AccommodationTierPremium  AccommodationTier = "premium"   // The dearest third, reviewed 8 or more
+)
+
+// column is the tier's per-night price in the accommodation table
+func (t AccommodationTier) column() string {
+	switch t {
+	case AccommodationTierBudget:
+		return "a.booking_pppn_budget"
+	case AccommodationTierMidRange:
+		return "a.booking_pppn_mid_range"
+	case AccommodationTierPremium:
+		return "a.booking_pppn_premium"
+	default:
+		return "a.booking_pppn"
+	}
+}
+
+// fnafColumn is the trip total with five nights at the tier's price
+func (t AccommodationTier) fnafColumn() string {
+	switch t {
+	case AccommodationTierBudget:
+		return "fnf.price_fnaf_budget"
+	case AccommodationTierMidRange:
+		return "fnf.price_fnaf_mid_range"
+	case AccommodationTierPremium:
+		return "fnf.price_fnaf_premium"
+	default:
+		return "fnf.price_fnaf"
+	}
 }
 
 // parseAndValidateFilterInputs parses and validates filter-related inputs from the HTTP request
@@ -24,6 +91,11 @@ func ParseAndValidateFilterInputs(r *http.Request) (*FilterInput, error) {
 	maxFlightPriceLinearStrs := r.URL.Query()["maxFlightPriceLinear[]"]
 	maxAccomPriceLinearStrs := r.URL.Query()["maxAccommodationPrice[]"]
 	sortOption := r.URL.Query().Get("sort")
+	maxAQIStr := r.URL.Query().Get("max_aqi")
+	minDaylightStr := r.URL.Query().Get("min_daylight")
+	onlyObservedStr := r.URL.Query().Get("only_observed")
+	priceBoundStr := r.URL.Query().Get("price_bound")
+	accommodationTierStr := r.URL.Query().Get("accommodation_tier")
 
 	if len(cities) == 0 || len(cities) != len(logicalOperators)+1 || len(cities) != len(maxFlightPriceLinearStrs) {
 		return nil, fmt.Errorf("mismatched input lengths. Cities: %d, Operators: %d, Prices: %d",
@@ -62,12 +134,64 @@ func ParseAndValidateFilterInputs(r *http.Request) (*FilterInput, error) {
 		maxAccommodationPrice = 70.0 // Default value
 	}
 
+	var maxAQI int
+	if maxAQIStr != "" {
+		maxAQI, err = strconv.Atoi(maxAQIStr)
+		if err != nil || maxAQI < 0 || maxAQI > 5 {
+			return nil, fmt.Errorf("invalid max_aqi parameter")
+		}
+	}
+
+	var minDaylightHours float64
+	if minDaylightStr != "" {
+		minDaylightHours, err = strconv.ParseFloat(minDaylightStr, 64)
+		if err != nil || minDaylightHours < 0 || minDaylightHours > 24 {
+			return nil, fmt.Errorf("invalid min_daylight parameter")
+		}
+	}
+
+	var onlyObservedPrices bool
+	if onlyObservedStr != "" {
+		// Checkboxes send "on"
+		onlyObservedPrices = onlyObservedStr == "on"
+		if !onlyObservedPrices {
+			onlyObservedPrices, err = strconv.ParseBool(onlyObservedStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid only_observed parameter")
+			}
+		}
+	}
+
+	priceBound := PriceBoundEstimate
+	if priceBoundStr != "" {
+		priceBound = PriceBound(priceBoundStr)
+		if priceBound != PriceBoundEstimate && priceBound != PriceBoundOptimistic && priceBound != PriceBoundConservative {
+			return nil, fmt.Errorf("invalid price_bound parameter")
+		}
+	}
+
+	accommodationTier := AccommodationTierStandard
+	if accommodationTierStr != "" {
+		accommodationTier = AccommodationTier(accommodationTierStr)
+		switch accommodationTier {
+		case AccommodationTierStandard, AccommodationTierBudget, AccommodationTierMidRange, AccommodationTierPremium:
+		default:
+			return nil, fmt.Errorf("invalid accommodation_tier parameter")
+		}
+	}
+
 	return &FilterInput{
 		Cities:                cities,
 		LogicalOperators:      logicalOperators,
 		MaxFlightPrices:       maxFlightPrices,
 		MaxAccommodationPrice: maxAccommodationPrice,
+		MaxAQI:                maxAQI,
+		MinDaylightHours:      minDaylightHours,
+		OnlyObservedPrices:    onlyObservedPrices,
+		PriceBound:            priceBound,
+		AccommodationTier:     accommodationTier,
 		OrderClause:           orderClause,
 		LogicalExpression:     expr,
+		WeatherProfile:        DefaultWeatherProfile(),
 	}, nil
 }
@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+	"github.com/gorilla/sessions"
+)
+
+const CustomWeatherProfile = "custom"
+
+// CurvePoint is a breakpoint of a piecewise linear score curve, as in weatherPleasantness.yaml
+type CurvePoint = config_handlers.CurvePoint
+
+type WeatherWeights struct {
+	Temperature   float64 `json:"temperature"`
+	Wind          float64 `json:"wind"`
+	Condition     float64 `json:"condition"`
+	Precipitation float64 `json:"precipitation"`
+	Humidity      float64 `json:"humidity"`
+	CloudCover    float64 `json:"cloud_cover"`
+}
+
+// WeatherProfile is one visitor taste in weather, scored against the raw daily
+// components in the weather table when the query runs
+type WeatherProfile struct {
+	Name          string
+	Label         string
+	Weights       WeatherWeights
+	Temperature   []CurvePoint
+	Wind          []CurvePoint
+	Precipitation []CurvePoint
+	Humidity      []CurvePoint
+	CloudCover    []CurvePoint
+	Conditions    map[string]float64
+}
+
+// Loaded at startup by LoadWeatherProfiles; the default profile is always first
+var weatherProfiles []WeatherProfile
+
+// LoadWeatherProfiles reads the profiles visitors can choose from weatherPleasantness.yaml,
+// the same file process/calculate/weather scores the compiled WPI with
+func LoadWeatherProfiles(filePath string) error {
+	config, err := config_handlers.LoadWeatherPleasantnessConfig(filePath)
+	if err != nil {
+		return err
+	}
+
+	defaultProfile, err := config.Profile("")
+	if err != nil {
+		return err
+	}
+	defaultName := config.DefaultProfile
+	if defaultName == "" {
+		defaultName = "default"
+	}
+	profiles := []WeatherProfile{newWeatherProfile(defaultName, defaultProfile)}
+
+	for _, name := range config.ProfileNames() {
+		if name == defaultName {
+			continue
+		}
+		profile, err := config.Profile(name)
+		if err != nil {
+			return err
+		}
+		profiles = append(profiles, newWeatherProfile(name, profile))
+	}
+
+	weatherProfiles = profiles
+	return nil
+}
+
+// newWeatherProfile names a profile from weatherPleasantness.yaml, its shared condition
+// scores already merged in
+func newWeatherProfile(name string, p config_handlers.WPIProfile) WeatherProfile {
+	label := p.Label
+	if label == "" {
+		label = name
+	}
+	return WeatherProfile{
+		Name:  name,
+		Label: label,
+		Weights: WeatherWeights{
+			Temperature:   p.Weights.Temperature,
+			Wind:          p.Weights.Wind,
+			Condition:     p.Weights.Condition,
+			Precipitation: p.Weights.Precipitation,
+			Humidity:      p.Weights.Humidity,
+			CloudCover:    p.Weights.CloudCover,
+		},
+		Temperature:   p.Temperature,
+		Wind:          p.Wind,
+		Precipitation: p.Precipitation,
+		Humidity:      p.Humidity,
+		CloudCover:    p.CloudCover,
+		Conditions:    p.Conditions,
+	}
+}
+
+// WeatherProfiles lists the profiles offered on the form, default first
+func WeatherProfiles() []WeatherProfile {
+	return weatherProfiles
+}
+
+// DefaultWeatherProfile is the profile used when a visitor hasn't chosen one
+func DefaultWeatherProfile() WeatherProfile {
+	return weatherProfiles[0]
+}
+
+// isDefaultWeatherProfile reports whether profile is the default one, which the compiled
+// WPI is scored with
+func isDefaultWeatherProfile(profile WeatherProfile) bool {
+	return profile.Name != CustomWeatherProfile && profile.Name == DefaultWeatherProfile().Name
+}
+
+func findWeatherProfile(name string) (WeatherProfile, bool) {
+	for _, profile := range weatherProfiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return WeatherProfile{}, false
+}
+
+// Custom profiles keep the default curves and only change the weights.
+// The default profile may not score precipitation, so custom ones fall back to this curve.
+var customPrecipitationCurve = []CurvePoint{{Value: 0, Score: 10}, {Value: 80, Score: 0}}
+
+// customWeightFields are the form fields of the custom weight sliders, each 0 to 10
+var customWeightFields = []string{"weight_temperature", "weight_wind", "weight_condition", "weight_precipitation"}
+
+// WeatherProfileFromRequest picks the visitor's weather profile from the form, falling back to
+// the one saved in their session. Custom weights are saved in the session for later searches.
+func WeatherProfileFromRequest(r *http.Request, session *sessions.Session) (WeatherProfile, error) {
+	name := r.URL.Query().Get("weather_profile")
+	fromSession := false
+	if name == "" {
+		if saved, ok := session.Values["weather_profile"].(string); ok {
+			name = saved
+			fromSession = true
+		}
+	}
+	if name == "" {
+		return DefaultWeatherProfile(), nil
+	}
+
+	if name != CustomWeatherProfile {
+		profile, ok := findWeatherProfile(name)
+		if !ok && fromSession {
+			// Saved before the profile was taken out of weatherPleasantness.yaml
+			log.Printf("Saved weather profile %q no longer exists, using the default profile", name)
+			delete(session.Values, "weather_profile")
+			return DefaultWeatherProfile(), nil
+		}
+		if !ok {
+			return WeatherProfile{}, fmt.Errorf("unknown weather profile")
+		}
+		session.Values["weather_profile"] = name
+		return profile, nil
+	}
+
+	weights := make(map[string]float64, len(customWeightFields))
+	for _, field := range customWeightFields {
+		if str := r.URL.Query().Get(field); str != "" {
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil || value < 0 || value > 10 {
+				return WeatherProfile{}, fmt.Errorf("invalid %s parameter", field)
+			}
+			weights[field] = value
+			session.Values[field] = value
+		} else if saved, ok := session.Values[field].(float64); ok {
+			weights[field] = saved
+		}
+	}
+	session.Values["weather_profile"] = CustomWeatherProfile
+
+	profile := DefaultWeatherProfile()
+	profile.Name = CustomWeatherProfile
+	profile.Label = "Custom"
+	profile.Weights = WeatherWeights{
+		Temperature:   weights["weight_temperature"],
+		Wind:          weights["weight_wind"],
+		Condition:     weights["weight_condition"],
+		Precipitation: weights["weight_precipitation"],
+	}
+	if len(profile.Precipitation) == 0 {
+		profile.Precipitation = customPrecipitationCurve
+	}
+	if profile.Weights == (WeatherWeights{}) {
+		log.Printf("Custom weather profile has no weights, using the default weights")
+		profile.Weights = DefaultWeatherProfile().Weights
+	}
+	return profile, nil
+}
@@ -29,9 +29,8 @@ func UpdateMaxValue(currentMax, newValue sql.NullFloat64) sql.NullFloat64 {
 
 // Helper function to update min value
 func UpdateMinValue(currentMin, newValue sql.NullFloat64) sql.NullFloat64 {
-	// HOTFIX Check if newValue is valid and greater than or equal to 0.1
-	// This ensures we don't include flight prices which are zero because no price was found
-	if newValue.Valid && newValue.Float64 >= 0.1 {
+	// Missing prices are NULL, so only valid values count
+	if newValue.Valid {
 		// Update currentMin if it's not valid or if newValue is smaller
 		if !currentMin.Valid || newValue.Float64 < currentMin.Float64 {
 			return newValue
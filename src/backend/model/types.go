@@ -2,6 +2,9 @@ package model
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type Weather struct {
@@ -10,10 +13,58 @@ type Weather struct {
 	WeatherIcon    string
 	GoogleUrl      string
 	AvgDaytimeWpi  sql.NullFloat64
+	Source         string // "forecast", or "climate" for days beyond the forecast horizon
+	// 0 to 1, how accurate the city's forecasts have been this far ahead
+	ForecastConfidence sql.NullFloat64
+	// Worst air quality of the day, 1 Good to 5 Very Poor; NULL where there is no forecast
+	AQI sql.NullInt64
+	// Local times; NULL during polar day or night
+	Sunrise       sql.NullString
+	Sunset        sql.NullString
+	DaylightHours sql.NullFloat64
+}
+
+// DaylightLabel describes the day's sunrise, sunset and day length, for tooltips
+func (w Weather) DaylightLabel() string {
+	if !w.DaylightHours.Valid {
+		return ""
+	}
+	if !w.Sunrise.Valid || !w.Sunset.Valid {
+		return fmt.Sprintf("%.0fh daylight", w.DaylightHours.Float64)
+	}
+	return fmt.Sprintf("%.1fh daylight, sunrise %s, sunset %s", w.DaylightHours.Float64, w.Sunrise.String, w.Sunset.String)
+}
+
+// IsClimate reports whether the day comes from climate normals rather than a forecast
+func (w Weather) IsClimate() bool {
+	return w.Source == "climate"
+}
+
+// aqiLabels name the air quality index levels, as OpenWeatherMap does
+var aqiLabels = []string{"", "Good", "Fair", "Moderate", "Poor", "Very Poor"}
+
+// AQILabel names the day's air quality, or is empty when it isn't known
+func (w Weather) AQILabel() string {
+	if !w.AQI.Valid || w.AQI.Int64 < 1 || int(w.AQI.Int64) >= len(aqiLabels) {
+		return ""
+	}
+	return aqiLabels[w.AQI.Int64]
+}
+
+// SourceLabel describes where the day's weather comes from, for tooltips
+func (w Weather) SourceLabel() string {
+	if w.IsClimate() {
+		return "Climate average for this time of year"
+	}
+	if w.ForecastConfidence.Valid {
+		return fmt.Sprintf("Forecast, %.0f%% confidence", w.ForecastConfidence.Float64*100)
+	}
+	return "Forecast"
 }
 
 type Flight struct {
 	DestinationCityName  string
+	DestinationCountry   string
 	RandomImageURL       string
 	PriceCity1           sql.NullFloat64
 	UrlCity1             string
@@ -21,11 +72,206 @@ type Flight struct {
 	AvgWpi               sql.NullFloat64
 	BookingUrl           sql.NullString
 	BookingPppn          sql.NullFloat64
+	BookingPppnSource    string // "observed" on booking.com, or "estimated" without a recent price there
+	BookingPppnP10       sql.NullFloat64
+	BookingPppnP90       sql.NullFloat64
+	AccommodationTier    string // Which price BookingPppn is: "standard", "budget", "mid_range" or "premium"
 	FiveNightsFlights    sql.NullFloat64
 	DurationMins         sql.NullInt64
 	DurationHours        sql.NullInt64
 	DurationHoursRounded sql.NullInt64
 	DurationHourDotMins  sql.NullFloat64
+	// "observed", "stale-observed" or "predicted" by the regression model
+	PriceSource     string
+	PriceObservedAt sql.NullString // UTC, "2006-01-02 15:04:05"
+	// The range an estimated price is likely in
+	PriceLow  sql.NullFloat64
+	PriceHigh sql.NullFloat64
+	// The destination's weather compared with each origin city
+	HomeComparisons []HomeComparison
+	// The cheapest day to fly each way, from the origin with the cheapest round trip
+	LegsOrigin string
+	Outbound   FlightLeg
+	Return     FlightLeg
+	// How the cheapest origin's latest price compares with the last 30 days
+	PriceTrend PriceTrend
+}
+
+// PriceTrend summarises a route's round trip price over the last 30 days
+type PriceTrend struct {
+	Origin          string
+	LatestPrice     sql.NullFloat64
+	Min30d          sql.NullFloat64
+	Median30d       sql.NullFloat64
+	Observations30d int
+	Direction       string // "up", "down" or "flat"
+}
+
+// A route needs this many days of prices before it can be cheaper than usual
+const minTrendObservations = 5
+
+// CheaperThanUsual reports whether the latest price is at least 10% below the 30 day median
+func (t PriceTrend) CheaperThanUsual() bool {
+	return t.LatestPrice.Valid && t.Median30d.Valid && t.Observations30d >= minTrendObservations &&
+		t.LatestPrice.Float64 <= t.Median30d.Float64*0.9
+}
+
+// Label reads e.g. "€40 below the usual €200 from Berlin, lowest in 30 days €150"
+func (t PriceTrend) Label() string {
+	if !t.LatestPrice.Valid || !t.Median30d.Valid || !t.Min30d.Valid {
+		return ""
+	}
+	return fmt.Sprintf("€%.0f below the usual €%.0f from %s, lowest in 30 days €%.0f",
+		t.Median30d.Float64-t.LatestPrice.Float64, t.Median30d.Float64, t.Origin, t.Min30d.Float64)
+}
+
+// IsEstimatedPrice reports whether nobody has seen the flight for sale at this price
+func (f Flight) IsEstimatedPrice() bool {
+	return f.PriceSource == "predicted"
+}
+
+// HasPriceRange reports whether the price is an estimate with a range to show instead
+func (f Flight) HasPriceRange() bool {
+	return f.IsEstimatedPrice() && f.PriceCity1.Valid && f.PriceLow.Valid && f.PriceHigh.Valid
+}
+
+// PriceRangeLabel reads e.g. "~€80–120"
+func (f Flight) PriceRangeLabel() string {
+	return fmt.Sprintf("~€%.0f–%.0f", f.PriceLow.Float64, f.PriceHigh.Float64)
+}
+
+// IsEstimatedHotelPrice reports whether the hotel price is estimated, booking.com having
+// no recent price for the destination
+func (f Flight) IsEstimatedHotelPrice() bool {
+	return f.BookingPppnSource == "estimated"
+}
+
+// HotelPriceLabel reads e.g. "€80", or "~€80" for an estimate
+func (f Flight) HotelPriceLabel() string {
+	if f.IsEstimatedHotelPrice() {
+		return fmt.Sprintf("~€%.0f", f.BookingPppn.Float64)
+	}
+	return fmt.Sprintf("€%.0f", f.BookingPppn.Float64)
+}
+
+// HotelPriceSourceLabel explains where the hotel price comes from, for tooltips
+func (f Flight) HotelPriceSourceLabel() string {
+	var label string
+	switch {
+	case f.IsEstimatedHotelPrice():
+		label = "Estimated from similar cities and past prices; not recently seen on booking.com"
+	case f.AccommodationTier == "budget":
+		label = "Median of the cheapest third of places on booking.com"
+	case f.AccommodationTier == "mid_range":
+		label = "Median of mid-priced places reviewed 8 or more on booking.com"
+	case f.AccommodationTier == "premium":
+		label = "Median of the dearest third of places reviewed 8 or more on booking.com"
+	default:
+		label = "Median of well reviewed places on booking.com"
+	}
+	if f.BookingPppnP10.Valid && f.BookingPppnP90.Valid {
+		label += fmt.Sprintf(". Most places €%.0f–%.0f a night", f.BookingPppnP10.Float64, f.BookingPppnP90.Float64)
+	}
+	return label
+}
+
+// PriceUnavailable reports whether no flight was found for the dates searched
+func (f Flight) PriceUnavailable() bool {
+	return !f.PriceCity1.Valid
+}
+
+// PriceSourceLabel explains where the price comes from, for tooltips
+func (f Flight) PriceSourceLabel() string {
+	if f.PriceUnavailable() {
+		return "No flights found for the dates searched"
+	}
+	if f.IsEstimatedPrice() {
+		return "Estimated from similar routes; not yet seen for sale"
+	}
+	observed, err := time.Parse("2006-01-02 15:04:05", f.PriceObservedAt.String)
+	if !f.PriceObservedAt.Valid || err != nil {
+		return "Seen for sale, some time ago"
+	}
+	label := "Seen for sale today"
+	if days := int(time.Since(observed).Hours() / 24); days == 1 {
+		label = "Seen for sale yesterday"
+	} else if days > 1 {
+		label = fmt.Sprintf("Seen for sale %d days ago", days)
+	}
+	if f.PriceSource == "stale-observed" {
+		label += ", may have changed"
+	}
+	return label
+}
+
+// FlightLeg is the cheapest one-way flight found for one leg of a trip
+type FlightLeg struct {
+	Date         sql.NullString
+	Price        sql.NullFloat64
+	DurationMins sql.NullInt64
+}
+
+// Label reads e.g. "Thu 29 Oct, €60, 2h 35m", or is empty when the leg wasn't priced
+func (l FlightLeg) Label() string {
+	if !l.Date.Valid || !l.Price.Valid {
+		return ""
+	}
+	date, err := time.Parse("2006-01-02", strings.Split(l.Date.String, "T")[0])
+	if err != nil {
+		return ""
+	}
+	label := fmt.Sprintf("%s, €%.0f", date.Format("Mon 2 Jan"), l.Price.Float64)
+	if l.DurationMins.Valid && l.DurationMins.Int64 > 0 {
+		label += fmt.Sprintf(", %dh %02dm", l.DurationMins.Int64/60, l.DurationMins.Int64%60)
+	}
+	return label
+}
+
+// HomeComparison is how much warmer and nicer a destination's forecast is than an origin's,
+// averaged over the days both have a forecast
+type HomeComparison struct {
+	Origin    string
+	TempDelta sql.NullFloat64 // °C
+	WPIDelta  sql.NullFloat64
+}
+
+// Label reads e.g. "+6°C, +2.1 weather score vs Berlin"
+func (c HomeComparison) Label() string {
+	if !c.TempDelta.Valid || !c.WPIDelta.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%+.0f°C, %+.1f weather score vs %s", c.TempDelta.Float64, c.WPIDelta.Float64, c.Origin)
+}
+
+// ForecastConfidence averages the confidence of the destination's forecast days
+func (f Flight) ForecastConfidence() sql.NullFloat64 {
+	var sum float64
+	var count int
+	for _, w := range f.WeatherForecast {
+		if w.ForecastConfidence.Valid {
+			sum += w.ForecastConfidence.Float64
+			count++
+		}
+	}
+	if count == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: sum / float64(count), Valid: true}
+}
+
+// ForecastConfidenceLabel is "High", "Medium" or "Low", or empty when there is no forecast history yet
+func (f Flight) ForecastConfidenceLabel() string {
+	confidence := f.ForecastConfidence()
+	switch {
+	case !confidence.Valid:
+		return ""
+	case confidence.Float64 >= 0.75:
+		return "High"
+	case confidence.Float64 >= 0.5:
+		return "Medium"
+	default:
+		return "Low"
+	}
 }
 
 type FlightsData struct {
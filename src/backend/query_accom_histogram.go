@@ -8,7 +8,7 @@ import (
 
 func ExecuteAccommodationPricesHistogramQuery(input *FilterInput) ([]float64, error) {
 	// Build query with fixed maxAccommodationPrice = 550.0
-	allPricesQuery, allPricesArgs := BuildMainQuery(input.LogicalExpression, config.MaxAccomPrice, input.Cities, input.OrderClause)
+	allPricesQuery, allPricesArgs := BuildMainQuery(input.LogicalExpression, config.MaxAccomPrice, input.MaxAQI, input.MinDaylightHours, input.OnlyObservedPrices, input.PriceBound, input.AccommodationTier, input.Cities, input.OrderClause, input.WeatherProfile)
 
 	if !config.MutePrints {
 		fmt.Println("Generated SQL Query (ACCOMMODATION HISTOGRAM PRICES):")
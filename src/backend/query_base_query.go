@@ -1,23 +1,60 @@
 package backend
 
-const BaseQuery = `
+import "fmt"
+
+// BaseQuery is the core of the main query. The WPI is scored with the visitor's
+// weather profile: per day for the forecast icons, and averaged over the forecast days
+// for ranking. With the default profile this is the compiled WPI, so avg_wpi equals
+// location.avg_wpi; other profiles are scored at query time.
+// It expects the HomeWeather CTE, to rank by improvement over the origin cities.
+// booking_pppn and price_fnaf are the accommodation tier's.
+func BaseQuery(profile WeatherProfile, tier AccommodationTier) string {
+	return fmt.Sprintf(baseQueryFormat, WPIExpression(profile, "w."), tier.column(), WPIExpression(profile, ""), forecastWeightSQL, homeComparisonSQL(profile), tier.fnafColumn())
+}
+
+// forecastWeightSQL is how much a forecast day counts towards avg_wpi. Days forecast with
+// little confidence count for as little as half as much, as in process/compile/main/locations.
+const forecastWeightSQL = "(0.5 + 0.5 * COALESCE(forecast_confidence, 1))"
+
+const baseQueryFormat = `
     SELECT 
         ds.destination_city_name,
+        ds.destination_country,
         MIN(f.price_next_week) AS price_city1,
         MIN(f.skyscanner_url_next_week) AS url_city1,
         w.date,
         w.avg_daytime_temp,
         w.weather_icon,
         w.google_url,
-        l.avg_wpi,
+        w.source,
+        w.forecast_confidence,
+        aq.aqi,
+        w.sunrise,
+        w.sunset,
+        w.daylight_hours,
+        %s AS day_wpi,
+        pw.avg_wpi AS avg_wpi,
         l.image_1,
         a.booking_url,
-        a.booking_pppn,
+        %s AS booking_pppn,
+        COALESCE(a.booking_pppn_source, 'observed') AS booking_pppn_source,
+        a.booking_pppn_p10,
+        a.booking_pppn_p90,
         fnf.price_fnaf,
         MIN(f.duration_in_minutes) AS duration_mins,
         MIN(f.duration_in_hours) AS duration_hours,
         MIN(f.duration_in_hours_rounded) AS duration_hours_rounded,
-        MIN(f.duration_hour_dot_mins) AS duration_hour_dot_mins
+        MIN(f.duration_hour_dot_mins) AS duration_hour_dot_mins,
+        -- Where the cheapest price comes from, and when the newest of the observed prices was seen
+        CASE
+            WHEN MIN(f.price_next_week) = MIN(CASE WHEN f.price_source = 'observed' THEN f.price_next_week END) THEN 'observed'
+            WHEN MIN(f.price_next_week) = MIN(CASE WHEN f.price_source = 'stale-observed' THEN f.price_next_week END) THEN 'stale-observed'
+            ELSE 'predicted'
+        END AS price_source,
+        MAX(CASE WHEN f.price_source <> 'predicted' THEN f.price_observed_at END) AS price_observed_at,
+        -- The range of the cheapest estimated price; observed prices have none
+        MIN(f.price_low) AS price_low,
+        MIN(f.price_high) AS price_high
     FROM DestinationSet ds
     JOIN flight f ON ds.destination_city_name = f.destination_city_name 
                    AND ds.destination_country = f.destination_country
@@ -25,6 +62,28 @@ const BaseQuery = `
                      AND ds.destination_country = l.country
     JOIN weather w ON w.city = ds.destination_city_name 
                     AND w.country = ds.destination_country
+    JOIN (
+        SELECT city, country, SUM(wpi * weight) / NULLIF(SUM(CASE WHEN wpi IS NULL THEN 0 ELSE weight END), 0) AS avg_wpi,
+            AVG(daylight_hours) AS avg_daylight
+        FROM (
+            SELECT city, country, %s AS wpi, %s AS weight, daylight_hours
+            FROM weather
+            WHERE source = 'forecast'
+        )
+        GROUP BY city, country
+    ) pw ON pw.city = ds.destination_city_name
+          AND pw.country = ds.destination_country
+    LEFT JOIN (
+        -- The improvement every selected origin gets, so no traveller is worse off
+        SELECT city, country, MIN(wpi_delta) AS home_improvement
+        FROM (%s
+        )
+        GROUP BY city, country
+    ) hi ON hi.city = ds.destination_city_name
+          AND hi.country = ds.destination_country
+    LEFT JOIN air_quality aq ON aq.city = w.city
+                              AND aq.country = w.country
+                              AND aq.date = w.date
     LEFT JOIN accommodation a ON a.city = ds.destination_city_name 
                                AND a.country = ds.destination_country
     LEFT JOIN (
@@ -33,15 +92,15 @@ const BaseQuery = `
             fnf.origin_country,
             fnf.destination_city,
             fnf.destination_country,
-            MIN(fnf.price_fnaf) AS price_fnaf
+            MIN(%s) AS price_fnaf
         FROM five_nights_and_flights fnf
         GROUP BY fnf.origin_city, fnf.origin_country, fnf.destination_city, fnf.destination_country
     ) fnf ON fnf.destination_city = ds.destination_city_name
            AND fnf.destination_country = ds.destination_country
            AND fnf.origin_city = f.origin_city_name
            AND fnf.origin_country = f.origin_country
-    WHERE l.avg_wpi BETWEEN 1.0 AND 10.0 
+    WHERE pw.avg_wpi BETWEEN 1.0 AND 10.0 
       AND w.date >= date('now')
-      AND f.price_next_week < ?
+      AND (f.price_next_week IS NULL OR f.price_next_week < ?)
       AND f.origin_city_name IN
 `
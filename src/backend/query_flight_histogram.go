@@ -49,7 +49,7 @@ func adjustExpressionForActive(expr Expression, active CityInput, globalThreshol
 	}
 }
 
-func buildQueryForActiveOrigin(active CityInput, cities []string, logicalOperators []string, maxPrices []float64, globalThreshold, accomLimit float64) (string, []interface{}, error) {
+func buildQueryForActiveOrigin(active CityInput, cities []string, logicalOperators []string, maxPrices []float64, globalThreshold, accomLimit float64, priceBound PriceBound, tier AccommodationTier) (string, []interface{}, error) {
 
 	expr, err := ParseLogicalExpression(cities, logicalOperators, maxPrices)
 	if err != nil {
@@ -58,11 +58,11 @@ func buildQueryForActiveOrigin(active CityInput, cities []string, logicalOperato
 
 	adjustedExpr := adjustExpressionForActive(expr, active, globalThreshold)
 
-	subquery, subArgs := BuildFlightOriginsSubquery(adjustedExpr)
+	subquery, subArgs := BuildFlightOriginsSubquery(adjustedExpr, priceBound)
 
 	withClause := fmt.Sprintf("WITH DestinationSet AS (\n%s\n)", subquery)
 
-	mainQuery := `
+	mainQuery := fmt.Sprintf(`
 SELECT DISTINCT 
   ds.destination_city_name,
   ds.destination_country,
@@ -84,9 +84,9 @@ LEFT JOIN accommodation a
 WHERE f.origin_city_name = ? 
   AND f.origin_country = ?
   AND f.price_next_week < ?
-  AND a.booking_pppn IS NOT NULL
-  AND a.booking_pppn <= ?
-`
+  AND %[1]s IS NOT NULL
+  AND %[1]s <= ?
+`, tier.column())
 	finalQuery := withClause + "\n" + mainQuery
 
 	fullArgs := append(subArgs, active.Name, active.Name, active.Country, globalThreshold, accomLimit)
@@ -128,7 +128,7 @@ func ExecuteFlightPricesHistogramQuery(input *FilterInput) ([]model.Flight, erro
 	var flights []model.Flight
 
 	for _, active := range activeOrigins {
-		query, args, err := buildQueryForActiveOrigin(active, input.Cities, input.LogicalOperators, input.MaxFlightPrices, globalFlightPriceThreshold, input.MaxAccommodationPrice)
+		query, args, err := buildQueryForActiveOrigin(active, input.Cities, input.LogicalOperators, input.MaxFlightPrices, globalFlightPriceThreshold, input.MaxAccommodationPrice, input.PriceBound, input.AccommodationTier)
 		if err != nil {
 			return nil, fmt.Errorf("error building query for active origin %s: %w", active.Name, err)
 		}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Tris20/FairFareFinder/src/backend/model"
+)
+
+// flightLegs is the cheapest priced round trip to a destination and its two legs
+type flightLegs struct {
+	origin   string
+	price    float64
+	outbound model.FlightLeg
+	ret      model.FlightLeg
+}
+
+// ExecuteFlightLegsQuery returns the legs of the cheapest round trip to every destination
+// from the origin cities, keyed by destinationKey
+func ExecuteFlightLegsQuery(originCities []string) (map[string]flightLegs, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is not initialized")
+	}
+	if len(originCities) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(originCities)), ", ")
+	query := fmt.Sprintf(`
+    SELECT destination_city_name, destination_country, origin_city_name, price_next_week,
+        outbound_date, outbound_price, outbound_duration_mins,
+        return_date, return_price, return_duration_mins
+    FROM flight
+    WHERE origin_city_name IN (%s)
+      AND price_next_week IS NOT NULL
+      AND outbound_price IS NOT NULL
+      AND return_price IS NOT NULL`, placeholders)
+	args := make([]interface{}, 0, len(originCities))
+	for _, city := range originCities {
+		args = append(args, city)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cheapest := make(map[string]flightLegs)
+	for rows.Next() {
+		var city, country string
+		var legs flightLegs
+		err := rows.Scan(&city, &country, &legs.origin, &legs.price,
+			&legs.outbound.Date, &legs.outbound.Price, &legs.outbound.DurationMins,
+			&legs.ret.Date, &legs.ret.Price, &legs.ret.DurationMins)
+		if err != nil {
+			return nil, err
+		}
+		key := destinationKey(city, country)
+		if current, ok := cheapest[key]; !ok || legs.price < current.price {
+			cheapest[key] = legs
+		}
+	}
+	return cheapest, rows.Err()
+}
+
+// addFlightLegs attaches the outbound and return flights to each destination. Cards fall
+// back to the round trip price without them, so a failure is only logged.
+func addFlightLegs(flights []model.Flight, originCities []string) {
+	legs, err := ExecuteFlightLegsQuery(originCities)
+	if err != nil {
+		log.Printf("Error querying flight legs: %v", err)
+		return
+	}
+	for i := range flights {
+		if l, ok := legs[destinationKey(flights[i].DestinationCityName, flights[i].DestinationCountry)]; ok {
+			flights[i].LegsOrigin = l.origin
+			flights[i].Outbound = l.outbound
+			flights[i].Return = l.ret
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Tris20/FairFareFinder/src/backend/model"
+)
+
+// homeWeatherCTE scores the forecast of the selected origin cities with the visitor's
+// weather profile. Takes one argument per origin city.
+// Origins are matched through the flight table, since the form only sends city names.
+func homeWeatherCTE(profile WeatherProfile, originCount int) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", originCount), ", ")
+	return fmt.Sprintf(`HomeWeather AS (
+    SELECT hw.city, hw.country, hw.date, hw.avg_daytime_temp AS temp, %s AS wpi
+    FROM weather hw
+    WHERE hw.source = 'forecast'
+      AND hw.date >= date('now')
+      AND hw.city IN (%s)
+      AND EXISTS (
+          SELECT 1 FROM flight hf
+          WHERE hf.origin_city_name = hw.city
+            AND hf.origin_country = hw.country
+      )
+)`, WPIExpression(profile, "hw."), placeholders)
+}
+
+// homeComparisonSQL compares each destination with each home city on the days both have
+// a forecast, so a sunny weekend at home isn't weighed against a rainy week away.
+// Positive deltas mean the destination is warmer or nicer.
+func homeComparisonSQL(profile WeatherProfile) string {
+	return fmt.Sprintf(`
+    SELECT dw.city, dw.country, hw.city AS home_city,
+        AVG(dw.avg_daytime_temp - hw.temp) AS temp_delta,
+        AVG((%s) - hw.wpi) AS wpi_delta
+    FROM weather dw
+    JOIN HomeWeather hw ON hw.date = dw.date
+    WHERE dw.source = 'forecast'
+    GROUP BY dw.city, dw.country, hw.city, hw.country`, WPIExpression(profile, "dw."))
+}
+
+// ExecuteHomeComparisonQuery returns every destination's weather compared with each
+// origin city, keyed by destinationKey
+func ExecuteHomeComparisonQuery(originCities []string, profile WeatherProfile) (map[string][]model.HomeComparison, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is not initialized")
+	}
+	if len(originCities) == 0 {
+		return nil, nil
+	}
+
+	query := "WITH " + homeWeatherCTE(profile, len(originCities)) + homeComparisonSQL(profile) + "\n    ORDER BY hw.city"
+	args := make([]interface{}, 0, len(originCities))
+	for _, city := range originCities {
+		args = append(args, city)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comparisons := make(map[string][]model.HomeComparison)
+	for rows.Next() {
+		var city, country string
+		var comparison model.HomeComparison
+		if err := rows.Scan(&city, &country, &comparison.Origin, &comparison.TempDelta, &comparison.WPIDelta); err != nil {
+			return nil, err
+		}
+		key := destinationKey(city, country)
+		comparisons[key] = append(comparisons[key], comparison)
+	}
+	return comparisons, rows.Err()
+}
+
+func destinationKey(city, country string) string {
+	return city + "|" + country
+}
+
+// addHomeComparisons attaches the comparison with home to each destination. Cards still
+// render without them, so a failure is only logged.
+func addHomeComparisons(flights []model.Flight, originCities []string, profile WeatherProfile) {
+	comparisons, err := ExecuteHomeComparisonQuery(originCities, profile)
+	if err != nil {
+		log.Printf("Error comparing weather with origin cities: %v", err)
+		return
+	}
+	for i := range flights {
+		flights[i].HomeComparisons = comparisons[destinationKey(flights[i].DestinationCityName, flights[i].DestinationCountry)]
+	}
+}
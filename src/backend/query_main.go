@@ -10,7 +10,7 @@ import (
 
 func ExecuteMainQuery(input *FilterInput) ([]model.Flight, error) {
 
-	query, args := BuildMainQuery(input.LogicalExpression, input.MaxAccommodationPrice, input.Cities, input.OrderClause)
+	query, args := BuildMainQuery(input.LogicalExpression, input.MaxAccommodationPrice, input.MaxAQI, input.MinDaylightHours, input.OnlyObservedPrices, input.PriceBound, input.AccommodationTier, input.Cities, input.OrderClause, input.WeatherProfile)
 
 	if !config.MutePrints {
 		fmt.Println("Generated SQL Query (MAIN):")
@@ -44,6 +44,12 @@ func ExecuteMainQuery(input *FilterInput) ([]model.Flight, error) {
 		log.Printf("Error processing flight rows: %v", err)
 		return nil, err
 	}
+	for i := range flights {
+		flights[i].AccommodationTier = string(input.AccommodationTier)
+	}
+	addHomeComparisons(flights, input.Cities, input.WeatherProfile)
+	addFlightLegs(flights, input.Cities)
+	addPriceTrends(flights, input.Cities)
 
 	return flights, nil
 }
@@ -56,36 +62,66 @@ func ExecuteMainQuery(input *FilterInput) ([]model.Flight, error) {
 
 // Unified Query Builder
 
-func BuildMainQuery(expr Expression, maxAccommodationPrice float64, originCities []string, orderClause string) (string, []interface{}) {
+func BuildMainQuery(expr Expression, maxAccommodationPrice float64, maxAQI int, minDaylightHours float64, onlyObservedPrices bool, priceBound PriceBound, tier AccommodationTier, originCities []string, orderClause string, profile WeatherProfile) (string, []interface{}) {
 	var queryBuilder strings.Builder
 	var args []interface{}
+
+	// The origin cities appear in two IN clauses below
+	if len(originCities) == 0 {
+		return "", nil // If no origin cities, return an empty query
+	}
+
 	// Begin the query with the DestinationSet CTE
 	queryBuilder.WriteString("WITH DestinationSet AS (\n")
 
 	// Build the subquery based on the logical expression
-	subquery, subqueryArgs := BuildFlightOriginsSubquery(expr)
+	subquery, subqueryArgs := BuildFlightOriginsSubquery(expr, priceBound)
 	queryBuilder.WriteString(subquery)
 	queryBuilder.WriteString("\n)")
 	args = append(args, subqueryArgs...)
 
-	// This is where the core part of the sql query comes from
-	queryBuilder.WriteString(BaseQuery)
-
-	// Build the IN clause dynamically based on the number of origin cities
-	if len(originCities) == 0 {
-		return "", nil // If no origin cities, return an empty query
+	// The origins' own weather, to compare destinations with home
+	queryBuilder.WriteString(",\n")
+	queryBuilder.WriteString(homeWeatherCTE(profile, len(originCities)))
+	for _, city := range originCities {
+		args = append(args, city)
 	}
 
+	// This is where the core part of the sql query comes from
+	queryBuilder.WriteString(BaseQuery(profile, tier))
+
 	placeholders := make([]string, len(originCities))
 	for i := range originCities {
 		placeholders[i] = "?"
 	}
 	inClause := fmt.Sprintf("(%s)", strings.Join(placeholders, ", "))
 	queryBuilder.WriteString(inClause)
-	queryBuilder.WriteString(`
-      AND a.booking_pppn IS NOT NULL
-      AND a.booking_pppn <= ?
-   GROUP BY f.destination_city_name, w.date, f.destination_country, l.avg_wpi
+	// Destinations without a price for the tier are left out, like those without any
+	queryBuilder.WriteString(fmt.Sprintf(`
+      AND %[1]s IS NOT NULL
+      AND %[1]s <= ?
+`, tier.column()))
+
+	// Leave out destinations forecast to have worse air than the visitor accepts on any day.
+	// Cities without an air quality forecast are kept.
+	if maxAQI > 0 {
+		queryBuilder.WriteString(`      AND NOT EXISTS (
+          SELECT 1 FROM air_quality aq_max
+          WHERE aq_max.city = ds.destination_city_name
+            AND aq_max.country = ds.destination_country
+            AND aq_max.date >= date('now')
+            AND aq_max.aqi > ?
+      )
+`)
+	}
+	if minDaylightHours > 0 {
+		queryBuilder.WriteString("      AND pw.avg_daylight >= ?\n")
+	}
+	// Flights priced by the regression model are left out; stale observations are still real fares
+	if onlyObservedPrices {
+		queryBuilder.WriteString("      AND f.price_source <> 'predicted'\n")
+	}
+	queryBuilder.WriteString(`   GROUP BY f.destination_city_name, w.date, f.destination_country, pw.avg_wpi
     `)
 
 	// Add the dynamic order clause
@@ -102,29 +138,35 @@ func BuildMainQuery(expr Expression, maxAccommodationPrice float64, originCities
 	}
 
 	args = append(args, maxAccommodationPrice)
+	if maxAQI > 0 {
+		args = append(args, maxAQI)
+	}
+	if minDaylightHours > 0 {
+		args = append(args, minDaylightHours)
+	}
 
 	return queryBuilder.String(), args
 }
 
-// Maps to "city-rows"
-func BuildFlightOriginsSubquery(expr Expression) (string, []interface{}) {
+// Maps to "city-rows". Each origin's price limit is matched against the priceBound end of estimated prices.
+func BuildFlightOriginsSubquery(expr Expression, priceBound PriceBound) (string, []interface{}) {
 	switch e := expr.(type) {
 	case *CityCondition:
 		// Return the subquery for a city condition
-		subquery := `
+		subquery := fmt.Sprintf(`
             SELECT 
                 f.destination_city_name,
                 f.destination_country
             FROM flight f
-            WHERE f.origin_city_name = ? AND f.price_next_week < ?
+            WHERE f.origin_city_name = ? AND (f.price_next_week IS NULL OR %s < ?)
             GROUP BY f.destination_city_name, f.destination_country
-        `
+        `, priceBound.column())
 		args := []interface{}{e.City.Name, e.City.PriceLimit}
 		return subquery, args
 	case *LogicalExpression:
 		// Build the left and right subqueries
-		leftSubquery, leftArgs := BuildFlightOriginsSubquery(e.Left)
-		rightSubquery, rightArgs := BuildFlightOriginsSubquery(e.Right)
+		leftSubquery, leftArgs := BuildFlightOriginsSubquery(e.Left, priceBound)
+		rightSubquery, rightArgs := BuildFlightOriginsSubquery(e.Right, priceBound)
 		var operator string
 		if e.Operator == AndOperator {
 			operator = "INTERSECT"
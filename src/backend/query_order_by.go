@@ -1,16 +1,20 @@
 package backend
 
+// Flights and trips without a price sort last either way; SQLite puts NULL first when ascending.
+// booking_pppn is the price of the selected accommodation tier, as BaseQuery names it.
 var orderByClauses = map[string]string{
-	"cheapest_fnaf":         "ORDER BY fnf.price_fnaf ASC",
+	"cheapest_fnaf":         "ORDER BY fnf.price_fnaf IS NULL, fnf.price_fnaf ASC",
 	"most_expensive_fnaf":   "ORDER BY fnf.price_fnaf DESC",
 	"best_weather":          "ORDER BY avg_wpi DESC",
 	"worst_weather":         "ORDER BY avg_wpi ASC",
-	"cheapest_hotel":        "ORDER BY a.booking_pppn ASC",
-	"most_expensive_hotel":  "ORDER BY a.booking_pppn DESC",
+	"cheapest_hotel":        "ORDER BY booking_pppn ASC",
+	"most_expensive_hotel":  "ORDER BY booking_pppn DESC",
 	"shortest_flight":       "ORDER BY f.duration_hour_dot_mins ASC",
 	"longest_flight":        "ORDER BY f.duration_hour_dot_mins DESC",
-	"cheapest_flight":       "ORDER BY f.price_this_week ASC",
+	"cheapest_flight":       "ORDER BY f.price_this_week IS NULL, f.price_this_week ASC",
 	"most_expensive_flight": "ORDER BY f.price_this_week DESC",
+	"most_daylight":         "ORDER BY pw.avg_daylight DESC",
+	"best_over_home":        "ORDER BY hi.home_improvement DESC",
 }
 
 func determineOrderClause(sortOption string) string {
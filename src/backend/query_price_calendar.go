@@ -0,0 +1,223 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Flights are searched departing Wednesday to Saturday and returning Sunday to Wednesday.
+// Savings are shown against the days most people would pick.
+const (
+	usualOutboundDay = time.Friday
+	usualReturnDay   = time.Sunday
+)
+
+type DatePrice struct {
+	Date  string  `json:"date"`
+	Price float64 `json:"price"`
+}
+
+// CheapestTrip is the cheapest outbound and return pair, returning no earlier than it leaves
+type CheapestTrip struct {
+	Origin       string  `json:"origin"`
+	OutboundDate string  `json:"outbound_date"`
+	ReturnDate   string  `json:"return_date"`
+	Price        float64 `json:"price"`
+}
+
+// PriceSuggestion is a cheaper day to fly one leg than the usual day
+type PriceSuggestion struct {
+	Leg       string  `json:"leg"`
+	Date      string  `json:"date"`
+	InsteadOf string  `json:"instead_of"`
+	Saving    float64 `json:"saving"`
+	Label     string  `json:"label"`
+}
+
+// RoutePriceCalendar is the price of every day searched between one origin and the destination
+type RoutePriceCalendar struct {
+	Origin     string           `json:"origin"`
+	Outbound   []DatePrice      `json:"outbound"`
+	Return     []DatePrice      `json:"return"`
+	Cheapest   *CheapestTrip    `json:"cheapest"`
+	Suggestion *PriceSuggestion `json:"suggestion"`
+}
+
+type PriceCalendar struct {
+	City    string               `json:"city"`
+	Country string               `json:"country"`
+	Routes  []RoutePriceCalendar `json:"routes"`
+	// The cheapest trip from any of the origins
+	Cheapest *CheapestTrip `json:"cheapest"`
+}
+
+// ExecutePriceCalendarQuery returns the per-date prices to a destination from each origin
+// city. from and to optionally limit the dates, as "2006-01-02".
+func ExecutePriceCalendarQuery(originCities []string, city, country, from, to string) (PriceCalendar, error) {
+	calendar := PriceCalendar{City: city, Country: country, Routes: []RoutePriceCalendar{}}
+	if db == nil {
+		return calendar, fmt.Errorf("database connection is not initialized")
+	}
+
+	query := `
+    SELECT leg, date, MIN(price)
+    FROM route_price_by_date
+    WHERE origin_city_name = ?
+      AND destination_city_name = ?
+      AND destination_country = ?
+      AND price IS NOT NULL
+      AND date >= date('now')
+      AND (? = '' OR date >= ?)
+      AND (? = '' OR date <= ?)
+    GROUP BY leg, date
+    ORDER BY date`
+
+	for _, origin := range originCities {
+		rows, err := db.Query(query, origin, city, country, from, from, to, to)
+		if err != nil {
+			log.Printf("Error querying price calendar: %v", err)
+			return calendar, err
+		}
+
+		route := RoutePriceCalendar{Origin: origin, Outbound: []DatePrice{}, Return: []DatePrice{}}
+		for rows.Next() {
+			var leg string
+			var dp DatePrice
+			if err := rows.Scan(&leg, &dp.Date, &dp.Price); err != nil {
+				rows.Close()
+				return calendar, err
+			}
+			dp.Date = strings.Split(dp.Date, "T")[0]
+			switch leg {
+			case "outbound":
+				route.Outbound = append(route.Outbound, dp)
+			case "return":
+				route.Return = append(route.Return, dp)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return calendar, err
+		}
+		if len(route.Outbound) == 0 && len(route.Return) == 0 {
+			continue
+		}
+
+		route.Cheapest = cheapestTrip(origin, route.Outbound, route.Return)
+		route.Suggestion = priceSuggestion(route.Outbound, route.Return)
+		calendar.Routes = append(calendar.Routes, route)
+	}
+
+	for _, route := range calendar.Routes {
+		if route.Cheapest != nil && (calendar.Cheapest == nil || route.Cheapest.Price < calendar.Cheapest.Price) {
+			calendar.Cheapest = route.Cheapest
+		}
+	}
+	return calendar, nil
+}
+
+// cheapestTrip pairs each outbound day with the cheapest return on or after it.
+// Dates are "2006-01-02", so they compare as strings.
+func cheapestTrip(origin string, outbound, ret []DatePrice) *CheapestTrip {
+	var best *CheapestTrip
+	for _, out := range outbound {
+		for _, back := range ret {
+			if back.Date < out.Date {
+				continue
+			}
+			if price := out.Price + back.Price; best == nil || price < best.Price {
+				best = &CheapestTrip{Origin: origin, OutboundDate: out.Date, ReturnDate: back.Date, Price: price}
+			}
+		}
+	}
+	return best
+}
+
+// priceSuggestion finds the day that saves most on either leg compared with the usual
+// day of the same week, if there is one
+func priceSuggestion(outbound, ret []DatePrice) *PriceSuggestion {
+	best := legSuggestion("outbound", outbound, usualOutboundDay)
+	if s := legSuggestion("return", ret, usualReturnDay); s != nil && (best == nil || s.Saving > best.Saving) {
+		best = s
+	}
+	return best
+}
+
+func legSuggestion(leg string, prices []DatePrice, usualDay time.Weekday) *PriceSuggestion {
+	var best *PriceSuggestion
+	for _, usual := range prices {
+		usualDate, err := time.Parse("2006-01-02", usual.Date)
+		if err != nil || usualDate.Weekday() != usualDay {
+			continue
+		}
+		// The search window for a leg never spans more than four days
+		for _, other := range prices {
+			otherDate, err := time.Parse("2006-01-02", other.Date)
+			if err != nil || otherDate.Equal(usualDate) {
+				continue
+			}
+			if gap := otherDate.Sub(usualDate); gap < -72*time.Hour || gap > 72*time.Hour {
+				continue
+			}
+			saving := usual.Price - other.Price
+			if saving < 1 || (best != nil && saving <= best.Saving) {
+				continue
+			}
+			verb := "Fly"
+			if leg == "return" {
+				verb = "Return"
+			}
+			best = &PriceSuggestion{
+				Leg:       leg,
+				Date:      other.Date,
+				InsteadOf: usual.Date,
+				Saving:    saving,
+				Label:     fmt.Sprintf("%s %s instead of %s and save €%.0f", verb, otherDate.Weekday(), usualDay, saving),
+			}
+		}
+	}
+	return best
+}
+
+// PriceCalendarHandler serves the per-date prices to a destination as JSON, from each
+// origin[] city. from and to optionally limit the dates searched for the cheapest trip.
+func PriceCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	city := params.Get("city")
+	country := params.Get("country")
+	origins := params["origin[]"]
+	if city == "" || country == "" || len(origins) == 0 {
+		HandleHTTPError(w, "origin[], city and country are required", http.StatusBadRequest)
+		return
+	}
+
+	from, to := params.Get("from"), params.Get("to")
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			HandleHTTPError(w, fmt.Sprintf("Invalid date %q, expected YYYY-MM-DD", date), http.StatusBadRequest)
+			return
+		}
+	}
+
+	calendar, err := ExecutePriceCalendarQuery(origins, city, country, from, to)
+	if err != nil {
+		HandleHTTPError(w, "Error executing price calendar query", http.StatusInternalServerError)
+		return
+	}
+	if len(calendar.Routes) == 0 {
+		HandleHTTPError(w, "No prices for this destination", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(calendar); err != nil {
+		http.Error(w, "Failed to encode price calendar", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Tris20/FairFareFinder/src/backend/model"
+)
+
+// RoutePriceTrend is route_price_trend as JSON
+type RoutePriceTrend struct {
+	LatestDate      string   `json:"latest_date"`
+	LatestPrice     float64  `json:"latest_price"`
+	Min7d           *float64 `json:"min_7d"`
+	Median7d        *float64 `json:"median_7d"`
+	Min30d          *float64 `json:"min_30d"`
+	Median30d       *float64 `json:"median_30d"`
+	Observations30d int      `json:"observations_30d"`
+	Direction       string   `json:"direction"`
+}
+
+// RoutePriceHistory is the cheapest round trip seen each day from one origin
+type RoutePriceHistory struct {
+	Origin string           `json:"origin"`
+	Series []DatePrice      `json:"series"`
+	Trend  *RoutePriceTrend `json:"trend"`
+}
+
+type PriceHistory struct {
+	City    string              `json:"city"`
+	Country string              `json:"country"`
+	Routes  []RoutePriceHistory `json:"routes"`
+}
+
+// ExecutePriceHistoryQuery returns the price history of a destination from each origin city
+func ExecutePriceHistoryQuery(originCities []string, city, country string) (PriceHistory, error) {
+	history := PriceHistory{City: city, Country: country, Routes: []RoutePriceHistory{}}
+	if db == nil {
+		return history, fmt.Errorf("database connection is not initialized")
+	}
+
+	for _, origin := range originCities {
+		route := RoutePriceHistory{Origin: origin, Series: []DatePrice{}}
+		rows, err := db.Query(`
+    SELECT date, MIN(price)
+    FROM route_price_history
+    WHERE origin_city_name = ? AND destination_city_name = ? AND destination_country = ?
+    GROUP BY date
+    ORDER BY date`, origin, city, country)
+		if err != nil {
+			log.Printf("Error querying price history: %v", err)
+			return history, err
+		}
+		for rows.Next() {
+			var dp DatePrice
+			if err := rows.Scan(&dp.Date, &dp.Price); err != nil {
+				rows.Close()
+				return history, err
+			}
+			dp.Date = strings.Split(dp.Date, "T")[0]
+			route.Series = append(route.Series, dp)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return history, err
+		}
+		if len(route.Series) == 0 {
+			continue
+		}
+
+		var trend RoutePriceTrend
+		var min7d, median7d, min30d, median30d sql.NullFloat64
+		err = db.QueryRow(`
+    SELECT latest_date, latest_price, min_7d, median_7d, min_30d, median_30d, observations_30d, direction
+    FROM route_price_trend
+    WHERE origin_city_name = ? AND destination_city_name = ? AND destination_country = ?
+    ORDER BY latest_price
+    LIMIT 1`, origin, city, country).Scan(&trend.LatestDate, &trend.LatestPrice, &min7d, &median7d, &min30d, &median30d,
+			&trend.Observations30d, &trend.Direction)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return history, err
+		default:
+			trend.LatestDate = strings.Split(trend.LatestDate, "T")[0]
+			trend.Min7d, trend.Median7d = nullFloatPtr(min7d), nullFloatPtr(median7d)
+			trend.Min30d, trend.Median30d = nullFloatPtr(min30d), nullFloatPtr(median30d)
+			route.Trend = &trend
+		}
+		history.Routes = append(history.Routes, route)
+	}
+	return history, nil
+}
+
+// PriceHistoryHandler serves the price history of a destination as JSON, from each origin[] city
+func PriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	city := params.Get("city")
+	country := params.Get("country")
+	origins := params["origin[]"]
+	if city == "" || country == "" || len(origins) == 0 {
+		HandleHTTPError(w, "origin[], city and country are required", http.StatusBadRequest)
+		return
+	}
+
+	history, err := ExecutePriceHistoryQuery(origins, city, country)
+	if err != nil {
+		HandleHTTPError(w, "Error executing price history query", http.StatusInternalServerError)
+		return
+	}
+	if len(history.Routes) == 0 {
+		HandleHTTPError(w, "No price history for this destination", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		http.Error(w, "Failed to encode price history", http.StatusInternalServerError)
+	}
+}
+
+// addPriceTrends attaches the price trend of the cheapest origin to each destination.
+// The badge is optional, so a failure is only logged.
+func addPriceTrends(flights []model.Flight, originCities []string) {
+	if len(originCities) == 0 {
+		return
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(originCities)), ", ")
+	args := make([]interface{}, 0, len(originCities))
+	for _, city := range originCities {
+		args = append(args, city)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+    SELECT destination_city_name, destination_country, origin_city_name,
+        latest_price, min_30d, median_30d, observations_30d, direction
+    FROM route_price_trend
+    WHERE origin_city_name IN (%s)`, placeholders), args...)
+	if err != nil {
+		log.Printf("Error querying price trends: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	cheapest := make(map[string]model.PriceTrend)
+	for rows.Next() {
+		var city, country string
+		var trend model.PriceTrend
+		if err := rows.Scan(&city, &country, &trend.Origin, &trend.LatestPrice, &trend.Min30d, &trend.Median30d,
+			&trend.Observations30d, &trend.Direction); err != nil {
+			log.Printf("Error scanning price trend: %v", err)
+			return
+		}
+		key := destinationKey(city, country)
+		if current, ok := cheapest[key]; !ok || trend.LatestPrice.Float64 < current.LatestPrice.Float64 {
+			cheapest[key] = trend
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading price trends: %v", err)
+		return
+	}
+
+	for i := range flights {
+		flights[i].PriceTrend = cheapest[destinationKey(flights[i].DestinationCityName, flights[i].DestinationCountry)]
+	}
+}
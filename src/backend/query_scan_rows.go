@@ -52,6 +52,7 @@ func ProcessFlightRows(rows *sql.Rows) ([]model.Flight, error) {
 	for rows.Next() {
 		var flight model.Flight
 		var weather model.Weather
+		var weatherSource sql.NullString
 		var imageUrl sql.NullString
 		var bookingUrl sql.NullString
 		var priceFnaf sql.NullFloat64
@@ -62,27 +63,48 @@ func ProcessFlightRows(rows *sql.Rows) ([]model.Flight, error) {
 
 		err := rows.Scan(
 			&flight.DestinationCityName,
+			&flight.DestinationCountry,
 			&flight.PriceCity1,
 			&flight.UrlCity1,
 			&weather.Date,
 			&weather.AvgDaytimeTemp,
 			&weather.WeatherIcon,
 			&weather.GoogleUrl,
+			&weatherSource,
+			&weather.ForecastConfidence,
+			&weather.AQI,
+			&weather.Sunrise,
+			&weather.Sunset,
+			&weather.DaylightHours,
+			&weather.AvgDaytimeWpi,
 			&flight.AvgWpi,
 			&imageUrl,
 			&bookingUrl,
 			&flight.BookingPppn,
+			&flight.BookingPppnSource,
+			&flight.BookingPppnP10,
+			&flight.BookingPppnP90,
 			&priceFnaf,
 			&duration_mins,
 			&duration_hours,
 			&duration_hours_rounded,
 			&duration_hour_dot_mins,
+			&flight.PriceSource,
+			&flight.PriceObservedAt,
+			&flight.PriceLow,
+			&flight.PriceHigh,
 		)
 		if err != nil {
 			log.Printf("Error scanning row: %v", err)
 			return nil, err
 		}
 
+		// Rows without a source predate climate normals and are all forecasts
+		weather.Source = "forecast"
+		if weatherSource.Valid {
+			weather.Source = weatherSource.String
+		}
+
 		SetFlightDurationInt(&flight, duration_mins, &flight.DurationMins, "Duration: %d minutes for flight to %s")
 		SetFlightDurationInt(&flight, duration_hours, &flight.DurationHours, "Duration: %d hours for flight to %s")
 		SetFlightDurationInt(&flight, duration_hours_rounded, &flight.DurationHoursRounded, "Duration: %d rounded hours for flight to %s")
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WPIExpression builds the SQL that scores one row of the weather table with a profile.
+// prefix qualifies the columns, e.g. "w." inside a join.
+// The default profile reads the avg_daytime_wpi compiled into the row, so it ranks exactly
+// as location.avg_wpi does; the daily columns are averages that can't reproduce it.
+// Other profiles are scored from those columns, approximating weatherPleasantness in
+// process/calculate/weather. Components whose column is NULL, like humidity on climate
+// days, are left out of the weighted average. Profile values are written as literals: they
+// come from weatherPleasantness.yaml or are float64s parsed from the form, so they are safe to inline.
+func WPIExpression(profile WeatherProfile, prefix string) string {
+	if profile.Weights == (WeatherWeights{}) || isDefaultWeatherProfile(profile) {
+		return prefix + "avg_daytime_wpi"
+	}
+
+	type component struct {
+		weight float64
+		score  string
+	}
+	components := []component{
+		{profile.Weights.Temperature, curveSQL(prefix+"avg_daytime_temp", profile.Temperature)},
+		{profile.Weights.Wind, curveSQL(prefix+"avg_wind_speed", profile.Wind)},
+		{profile.Weights.Condition, conditionSQL(prefix+"weather_condition", profile.Conditions)},
+		{profile.Weights.Precipitation, curveSQL(prefix+"avg_precipitation_probability", profile.Precipitation)},
+		{profile.Weights.Humidity, curveSQL(prefix+"avg_humidity", profile.Humidity)},
+		{profile.Weights.CloudCover, curveSQL(prefix+"avg_cloud_cover", profile.CloudCover)},
+	}
+
+	var sum, totalWeight []string
+	for _, c := range components {
+		if c.weight <= 0 || c.score == "" {
+			continue
+		}
+		weight := sqlNumber(c.weight)
+		sum = append(sum, fmt.Sprintf("COALESCE(%s * (%s), 0)", weight, c.score))
+		totalWeight = append(totalWeight, fmt.Sprintf("CASE WHEN (%s) IS NULL THEN 0 ELSE %s END", c.score, weight))
+	}
+	if len(sum) == 0 {
+		return "NULL"
+	}
+
+	return fmt.Sprintf("((%s) / NULLIF(%s, 0))", strings.Join(sum, " + "), strings.Join(totalWeight, " + "))
+}
+
+// curveSQL reads a score off a piecewise linear curve, holding the end scores flat
+func curveSQL(column string, points []CurvePoint) string {
+	if len(points) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CASE WHEN %s IS NULL THEN NULL", column)
+	fmt.Fprintf(&b, " WHEN %s <= %s THEN %s", column, sqlNumber(points[0].Value), sqlNumber(points[0].Score))
+	for i := 1; i < len(points); i++ {
+		p1, p2 := points[i-1], points[i]
+		slope := (p2.Score - p1.Score) / (p2.Value - p1.Value)
+		fmt.Fprintf(&b, " WHEN %s <= %s THEN %s + (%s - %s) * %s",
+			column, sqlNumber(p2.Value), sqlNumber(p1.Score), column, sqlNumber(p1.Value), sqlNumber(slope))
+	}
+	fmt.Fprintf(&b, " ELSE %s END", sqlNumber(points[len(points)-1].Score))
+	return b.String()
+}
+
+// conditionSQL looks up the condition score; unknown conditions score 0 like in process/calculate/weather
+func conditionSQL(column string, conditions map[string]float64) string {
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CASE WHEN %s IS NULL THEN NULL", column)
+	for _, name := range names {
+		fmt.Fprintf(&b, " WHEN %s = '%s' THEN %s", column, strings.ReplaceAll(name, "'", "''"), sqlNumber(conditions[name]))
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
+
+func sqlNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
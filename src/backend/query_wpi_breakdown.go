@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// WPIScores are the 0 to 10 component scores behind a WPI; null when the day has no
+// measurement for the component or the profile doesn't score it
+type WPIScores struct {
+	Temperature   *float64 `json:"temperature"`
+	Wind          *float64 `json:"wind"`
+	Condition     *float64 `json:"condition"`
+	Precipitation *float64 `json:"precipitation"`
+	Humidity      *float64 `json:"humidity"`
+	CloudCover    *float64 `json:"cloud_cover"`
+}
+
+type WPIBreakdownDay struct {
+	Date                     string    `json:"date"`
+	Source                   string    `json:"source"`
+	Temperature              *float64  `json:"temperature"`
+	WindSpeed                *float64  `json:"wind_speed"`
+	Condition                string    `json:"condition"`
+	PrecipitationProbability *float64  `json:"precipitation_probability"`
+	Humidity                 *float64  `json:"humidity"`
+	CloudCover               *float64  `json:"cloud_cover"`
+	Scores                   WPIScores `json:"scores"`
+	WPI                      *float64  `json:"wpi"`
+	// How accurate the city's forecasts have been this far ahead, 0 to 1
+	ForecastConfidence *float64 `json:"forecast_confidence"`
+	// Only forecast days make up avg_wpi; climate days are shown but not ranked on.
+	// Days forecast with little confidence count for less.
+	CountsTowardsAvg bool    `json:"counts_towards_avg"`
+	AvgWeight        float64 `json:"avg_weight"`
+}
+
+// WPIBreakdown explains a destination's avg_wpi: each day's WPI is the weighted average
+// of its component scores, and avg_wpi is the mean WPI of the forecast days, weighted
+// by forecast confidence
+type WPIBreakdown struct {
+	City         string            `json:"city"`
+	Country      string            `json:"country"`
+	Profile      string            `json:"profile"`
+	ProfileLabel string            `json:"profile_label"`
+	Weights      WeatherWeights    `json:"weights"`
+	Days         []WPIBreakdownDay `json:"days"`
+	ForecastDays int               `json:"forecast_days"`
+	AvgWPI       *float64          `json:"avg_wpi"`
+}
+
+// wpiBreakdownQuery scores every component of every day with the profile, using the same
+// SQL as BaseQuery so the breakdown adds up to the WPI the destination was ranked by.
+// With the default profile the day's WPI is the compiled one, which the component
+// scores, taken from daily averages, only approximate.
+func wpiBreakdownQuery(profile WeatherProfile) string {
+	scores := []string{
+		curveSQL("avg_daytime_temp", profile.Temperature),
+		curveSQL("avg_wind_speed", profile.Wind),
+		conditionSQL("weather_condition", profile.Conditions),
+		curveSQL("avg_precipitation_probability", profile.Precipitation),
+		curveSQL("avg_humidity", profile.Humidity),
+		curveSQL("avg_cloud_cover", profile.CloudCover),
+	}
+	for i, score := range scores {
+		if score == "" {
+			scores[i] = "NULL"
+		}
+	}
+
+	return fmt.Sprintf(`
+    SELECT date, COALESCE(source, 'forecast'), avg_daytime_temp, avg_wind_speed, COALESCE(weather_condition, ''),
+           avg_precipitation_probability, avg_humidity, avg_cloud_cover,
+           %s,
+           %s AS day_wpi,
+           forecast_confidence, %s AS avg_weight
+    FROM weather
+    WHERE city = ? AND country = ?
+    ORDER BY date
+`, strings.Join(scores, ",\n           "), WPIExpression(profile, ""), forecastWeightSQL)
+}
+
+// ExecuteWPIBreakdownQuery returns the daily WPI breakdown of one destination
+func ExecuteWPIBreakdownQuery(city, country string, profile WeatherProfile) (WPIBreakdown, error) {
+	breakdown := WPIBreakdown{
+		City:         city,
+		Country:      country,
+		Profile:      profile.Name,
+		ProfileLabel: profile.Label,
+		Weights:      profile.Weights,
+		Days:         []WPIBreakdownDay{},
+	}
+
+	rows, err := db.Query(wpiBreakdownQuery(profile), city, country)
+	if err != nil {
+		log.Printf("Error querying WPI breakdown: %v", err)
+		return breakdown, err
+	}
+	defer rows.Close()
+
+	var wpiSum, weightSum float64
+	for rows.Next() {
+		var day WPIBreakdownDay
+		var temp, wind, precipitation, humidity, cloudCover, wpi, confidence sql.NullFloat64
+		var scores [6]sql.NullFloat64
+		var weight float64
+		err := rows.Scan(&day.Date, &day.Source, &temp, &wind, &day.Condition, &precipitation, &humidity, &cloudCover,
+			&scores[0], &scores[1], &scores[2], &scores[3], &scores[4], &scores[5], &wpi, &confidence, &weight)
+		if err != nil {
+			log.Printf("Error scanning WPI breakdown row: %v", err)
+			return breakdown, err
+		}
+
+		day.Date = strings.Split(day.Date, "T")[0]
+		day.Temperature = nullFloatPtr(temp)
+		day.WindSpeed = nullFloatPtr(wind)
+		day.PrecipitationProbability = nullFloatPtr(precipitation)
+		day.Humidity = nullFloatPtr(humidity)
+		day.CloudCover = nullFloatPtr(cloudCover)
+		day.Scores = WPIScores{
+			Temperature:   nullFloatPtr(scores[0]),
+			Wind:          nullFloatPtr(scores[1]),
+			Condition:     nullFloatPtr(scores[2]),
+			Precipitation: nullFloatPtr(scores[3]),
+			Humidity:      nullFloatPtr(scores[4]),
+			CloudCover:    nullFloatPtr(scores[5]),
+		}
+		day.WPI = nullFloatPtr(wpi)
+		day.ForecastConfidence = nullFloatPtr(confidence)
+
+		// Same days as the avg_wpi subquery in BaseQuery
+		if day.Source == "forecast" && wpi.Valid {
+			day.CountsTowardsAvg = true
+			day.AvgWeight = weight
+			wpiSum += wpi.Float64 * weight
+			weightSum += weight
+			breakdown.ForecastDays++
+		}
+		breakdown.Days = append(breakdown.Days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return breakdown, err
+	}
+
+	if weightSum > 0 {
+		avg := wpiSum / weightSum
+		breakdown.AvgWPI = &avg
+	}
+	return breakdown, nil
+}
+
+func nullFloatPtr(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
+// WPIBreakdownHandler serves the WPI breakdown of a destination as JSON, scored with the
+// visitor's weather profile. It takes the same weather_profile parameters as /filter.
+func WPIBreakdownHandler(w http.ResponseWriter, r *http.Request, session *sessions.Session) {
+	city := r.URL.Query().Get("city")
+	country := r.URL.Query().Get("country")
+	if city == "" || country == "" {
+		HandleHTTPError(w, "city and country are required", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := WeatherProfileFromRequest(r, session)
+	if err != nil {
+		HandleHTTPError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := ExecuteWPIBreakdownQuery(city, country, profile)
+	if err != nil {
+		HandleHTTPError(w, "Error executing WPI breakdown query", http.StatusInternalServerError)
+		return
+	}
+	if len(breakdown.Days) == 0 {
+		HandleHTTPError(w, "No weather for this destination", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(breakdown); err != nil {
+		http.Error(w, "Failed to encode WPI breakdown", http.StatusInternalServerError)
+	}
+}
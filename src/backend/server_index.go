@@ -35,6 +35,7 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 		"MaxAccomPrice":     config.MaxAccomPrice,
 		"DefaultAccomPrice": config.DefaultAccomPrice,
 		"DefaultSortOption": config.DefaultSortOption,
+		"WeatherProfiles":   WeatherProfiles(),
 	})
 	if err != nil {
 		log.Printf("Error executing template: %v", err)
@@ -30,6 +30,16 @@ func SetupRoutes(store *sessions.CookieStore, db *sql.DB, tmpl *template.Templat
 
 	// API routes
 	http.HandleFunc("/city-country-pairs", CityCountryHandler)
+	http.HandleFunc("/price-calendar", PriceCalendarHandler)
+	http.HandleFunc("/price-history", PriceHistoryHandler)
+	http.HandleFunc("/wpi-breakdown", func(w http.ResponseWriter, r *http.Request) {
+		session, err := GetUserSession(store, r)
+		if err != nil {
+			HandleHTTPError(w, "Session retrieval error", http.StatusInternalServerError)
+			return
+		}
+		WPIBreakdownHandler(w, r, session)
+	})
 
 	// Footer routes
 	http.HandleFunc("/privacy-policy", func(w http.ResponseWriter, r *http.Request) {
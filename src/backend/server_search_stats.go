@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Tris20/FairFareFinder/src/backend/model"
+)
+
+// SearchStatsPath is where the server counts the destinations it shows. It is kept apart
+// from main.db, which is replaced by every delivery.
+const SearchStatsPath = "./data/raw/searches/searches.db"
+
+// searchStats is nil when search statistics are not being recorded
+var searchStats *sql.DB
+
+// OpenSearchStats opens the search statistics database, creating it if needed.
+// Searching works the same without it.
+func OpenSearchStats(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %v", path, err)
+	}
+	statsDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	// Searches are recorded one at a time, so concurrent requests never hold competing locks
+	statsDB.SetMaxOpenConns(1)
+
+	_, err = statsDB.Exec(`
+    CREATE TABLE IF NOT EXISTS destination_appearance (
+        date TEXT NOT NULL,
+        city TEXT NOT NULL,
+        country TEXT NOT NULL,
+        appearances INTEGER NOT NULL,
+        PRIMARY KEY (date, city, country)
+    )`)
+	if err != nil {
+		statsDB.Close()
+		return fmt.Errorf("failed to create destination_appearance: %v", err)
+	}
+	searchStats = statsDB
+	return nil
+}
+
+// CloseSearchStats stops recording search statistics
+func CloseSearchStats() {
+	if searchStats != nil {
+		searchStats.Close()
+		searchStats = nil
+	}
+}
+
+// RecordSearchAppearances counts one appearance today for each destination in a search's results
+func RecordSearchAppearances(flights []model.Flight) {
+	if searchStats == nil || len(flights) == 0 {
+		return
+	}
+	tx, err := searchStats.Begin()
+	if err != nil {
+		log.Printf("Error recording search appearances: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	seen := make(map[string]bool)
+	for _, f := range flights {
+		key := f.DestinationCityName + "|" + f.DestinationCountry
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		_, err := tx.Exec(`
+        INSERT INTO destination_appearance (date, city, country, appearances)
+        VALUES (date('now'), ?, ?, 1)
+        ON CONFLICT (date, city, country) DO UPDATE SET appearances = appearances + 1`,
+			f.DestinationCityName, f.DestinationCountry)
+		if err != nil {
+			log.Printf("Error recording search appearance of %s: %v", f.DestinationCityName, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error recording search appearances: %v", err)
+	}
+}
@@ -20,3 +20,124 @@ function closeModalOnOutsideClick(event, destinationCity) {
     closeModal(destinationCity); // Close the modal
   }
 }
+
+// --------------------- Weather Score Breakdown ---------------------
+// Explains a destination's weather score in its tooltip, loaded the first time it is hovered
+const wpiComponentLabels = {
+  temperature: "Temperature",
+  wind: "Wind",
+  condition: "Sky",
+  precipitation: "Rain chance",
+  humidity: "Humidity",
+  cloud_cover: "Cloud cover",
+};
+
+function weatherProfileParams() {
+  const params = new URLSearchParams();
+  const profile = document.getElementById("weather-profile");
+  if (profile) {
+    params.set("weather_profile", profile.value);
+    if (profile.value === "custom") {
+      document
+        .querySelectorAll("#custom-weather-weights input[type=range]")
+        .forEach((input) => params.set(input.name, input.value));
+    }
+  }
+  return params;
+}
+
+function formatWpiBreakdown(breakdown) {
+  const lines = [`Weather score for "${breakdown.profile_label}"`];
+  breakdown.days.forEach((day) => {
+    if (day.wpi === null) return;
+    const parts = Object.keys(wpiComponentLabels)
+      .filter((key) => day.scores[key] !== null && breakdown.weights[key] > 0)
+      .map(
+        (key) =>
+          `${wpiComponentLabels[key]} ${day.scores[key].toFixed(1)} ×${breakdown.weights[key]}`,
+      );
+    const note = day.counts_towards_avg ? "" : " (climate average, not in the score)";
+    lines.push(`${day.date}: ${day.wpi.toFixed(1)} = ${parts.join(", ")}${note}`);
+  });
+  if (breakdown.avg_wpi !== null) {
+    lines.push(
+      `Average of ${breakdown.forecast_days} forecast days, weighted by forecast confidence: ${breakdown.avg_wpi.toFixed(1)}`,
+    );
+  }
+  return lines.join("\n");
+}
+
+document.addEventListener("mouseover", (event) => {
+  const score = event.target.closest(".wpi-score");
+  if (!score || score.dataset.loaded) return;
+  score.dataset.loaded = "true";
+
+  const params = weatherProfileParams();
+  params.set("city", score.dataset.city);
+  params.set("country", score.dataset.country);
+  fetch(`/wpi-breakdown?${params}`)
+    .then((response) => {
+      if (!response.ok) throw new Error(response.statusText);
+      return response.json();
+    })
+    .then((breakdown) => {
+      score.title = formatWpiBreakdown(breakdown);
+    })
+    .catch((error) => {
+      console.error("Error loading weather score breakdown:", error);
+      delete score.dataset.loaded;
+    });
+});
+
+// --------------------- Price Calendar ---------------------
+// Suggests a cheaper day to fly under each card, from the prices of every day searched
+function originCityParams() {
+  const params = new URLSearchParams();
+  document
+    .querySelectorAll("#city-rows input[name='city[]']")
+    .forEach((input) => {
+      if (input.value.trim()) params.append("origin[]", input.value.trim());
+    });
+  return params;
+}
+
+function formatCheapestTrip(cheapest) {
+  return `Cheapest trip: out ${cheapest.outbound_date}, back ${cheapest.return_date} from ${cheapest.origin}, €${cheapest.price.toFixed(0)}`;
+}
+
+function loadPriceCalendars() {
+  const origins = originCityParams();
+  if (!origins.toString()) return;
+
+  // The card and its modal share one request
+  const requests = new Map();
+  document.querySelectorAll(".price-calendar").forEach((element) => {
+    const params = new URLSearchParams(origins);
+    params.set("city", element.dataset.city);
+    params.set("country", element.dataset.country);
+    const url = `/price-calendar?${params}`;
+    if (!requests.has(url)) {
+      requests.set(
+        url,
+        fetch(url).then((response) => {
+          if (response.status === 404) return null;
+          if (!response.ok) throw new Error(response.statusText);
+          return response.json();
+        }),
+      );
+    }
+    requests
+      .get(url)
+      .then((calendar) => {
+        if (!calendar) return;
+        const route = calendar.routes.find((r) => r.suggestion);
+        if (route) element.textContent = route.suggestion.label;
+        if (calendar.cheapest) element.title = formatCheapestTrip(calendar.cheapest);
+      })
+      .catch((error) => console.error("Error loading price calendar:", error));
+  });
+}
+
+document.addEventListener("htmx:afterSwap", (event) => {
+  if (event.detail.target.id === "flight-table") loadPriceCalendars();
+});
@@ -354,3 +354,27 @@ document.body.addEventListener("htmx:afterSwap", function (event) {
 
 
 
+
+// Show the custom weight sliders only for the custom weather profile.
+// Disabled sliders are left out of the form, so presets aren't sent custom weights.
+function toggleCustomWeatherWeights() {
+  const profileSelect = document.getElementById("weather-profile");
+  const weights = document.getElementById("custom-weather-weights");
+  if (!profileSelect || !weights) {
+    return;
+  }
+
+  const isCustom = profileSelect.value === "custom";
+  weights.classList.toggle("hidden", !isCustom);
+  weights.querySelectorAll("input").forEach((slider) => {
+    slider.disabled = !isCustom;
+  });
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  const profileSelect = document.getElementById("weather-profile");
+  if (profileSelect) {
+    profileSelect.addEventListener("change", toggleCustomWeatherWeights);
+    toggleCustomWeatherWeights();
+  }
+});
@@ -0,0 +1,52 @@
+// Package dbschema migrates SQLite tables created before columns were added to them.
+// SQLite has no "ADD COLUMN IF NOT EXISTS", so the columns are checked with table_info first.
+package dbschema
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Column is a column added to a table after it was first created
+type Column struct {
+	Name       string
+	Definition string // Type and constraints, e.g. "TEXT DEFAULT 'observed'"
+}
+
+// Columns returns the names of table's columns
+func Columns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan columns of %s: %v", table, err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// EnsureColumns adds the columns table does not have yet
+func EnsureColumns(db *sql.DB, table string, columns []Column) error {
+	existing, err := Columns(db, table)
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if existing[column.Name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column.Name, column.Definition)); err != nil {
+			return fmt.Errorf("failed to add %s to %s: %v", column.Name, table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
+)
+
+// AirQualityData is one hourly air quality reading
+type AirQualityData struct {
+	Date string // UTC, "2006-01-02 15:04:05"
+	// 1 Good, 2 Fair, 3 Moderate, 4 Poor, 5 Very Poor, as OpenWeatherMap's air pollution index
+	AQI int
+	// Concentrations in μg/m³
+	PM25 float64
+	PM10 float64
+	O3   float64
+	NO2  float64
+	// Set by ProviderChain to the provider that answered
+	Provider string
+}
+
+type AirQualityBatch struct {
+	Airport  AirportInfo
+	Readings []AirQualityData
+}
+
+func storeAirQualityBatch(db *sql.DB, batch []AirQualityBatch) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO all_air_quality
+		(city_name, country_code, iata, date, timezone, aqi, pm2_5, pm10, o3, no2, provider, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %v", err)
+	}
+	defer stmt.Close()
+
+	fetchedAt := freshness.Now()
+	for _, b := range batch {
+		for _, r := range b.Readings {
+			_, err := stmt.Exec(b.Airport.City, b.Airport.Country, b.Airport.IATA, r.Date, timezoneOf(b.Airport),
+				r.AQI, r.PM25, r.PM10, r.O3, r.NO2, r.Provider, fetchedAt)
+			if err != nil {
+				return fmt.Errorf("failed to insert air quality for %s: %v", b.Airport.IATA, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// AirportInfo is the location an air quality forecast is fetched for
+type AirportInfo struct {
+	City     string
+	Country  string
+	IATA     string
+	Lat      float64
+	Lon      float64
+	Timezone sql.NullString // IANA zone, used by the compile stage to find local days
+}
+
+// fetchAirports retrieves the airports of included cities that have coordinates;
+// air quality forecasts are looked up by position, not by city name
+func fetchAirports(db *sql.DB) ([]AirportInfo, error) {
+	query := `SELECT a.city, a.country, a.iata, a.lat, a.lon, a.tz
+FROM airport a
+JOIN city c ON LOWER(TRIM(a.city)) = LOWER(TRIM(c.city_ascii))
+            AND LOWER(TRIM(a.country)) = LOWER(TRIM(c.iso2))
+WHERE a.iata IS NOT NULL
+AND a.iata != ''
+AND a.city IS NOT NULL
+AND a.country IS NOT NULL
+AND a.lat IS NOT NULL
+AND a.lon IS NOT NULL
+AND c.include_tf = 1;
+`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var airports []AirportInfo
+	for rows.Next() {
+		var ai AirportInfo
+		if err := rows.Scan(&ai.City, &ai.Country, &ai.IATA, &ai.Lat, &ai.Lon, &ai.Timezone); err != nil {
+			return nil, err
+		}
+		airports = append(airports, ai)
+	}
+
+	return airports, rows.Err()
+}
+
+// initAirQualityDB creates the air quality database and table if they don't exist
+func initAirQualityDB(dbPath string) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), os.ModePerm); err != nil {
+		log.Fatalf("Error creating air quality data directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		log.Fatalf("Error opening air-quality.db: %v", err)
+	}
+	defer db.Close()
+
+	createTableSQL := `CREATE TABLE IF NOT EXISTS all_air_quality (
+		air_quality_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		city_name TEXT NOT NULL,
+		country_code TEXT NOT NULL,
+		iata TEXT NOT NULL,
+		date TEXT NOT NULL,
+		timezone TEXT,
+		aqi INTEGER NOT NULL,
+		pm2_5 REAL,
+		pm10 REAL,
+		o3 REAL,
+		no2 REAL,
+		provider TEXT,
+		fetched_at TEXT
+	);`
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		log.Fatalf("Error creating air quality table: %v", err)
+	}
+}
+
+// fetchLastFetched returns the most recent fetched_at of every airport in all_air_quality
+func fetchLastFetched(db *sql.DB) (map[string]sql.NullString, error) {
+	rows, err := db.Query(`SELECT iata, MAX(fetched_at) FROM all_air_quality GROUP BY iata`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lastFetched := make(map[string]sql.NullString)
+	for rows.Next() {
+		var iata string
+		var fetchedAt sql.NullString
+		if err := rows.Scan(&iata, &fetchedAt); err != nil {
+			return nil, err
+		}
+		lastFetched[iata] = fetchedAt
+	}
+
+	return lastFetched, rows.Err()
+}
+
+// timezoneOf is the zone the compile stage takes the airport's days in; UTC when unknown
+func timezoneOf(airport AirportInfo) string {
+	if airport.Timezone.Valid && airport.Timezone.String != "" {
+		return airport.Timezone.String
+	}
+	return "UTC"
+}
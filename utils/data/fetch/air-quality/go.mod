@@ -0,0 +1,22 @@
+module update-air-quality-db
+
+go 1.23.1
+
+require (
+	github.com/Tris20/FairFareFinder v0.0.1
+	github.com/mattn/go-sqlite3 v1.14.23
+)
+
+require (
+	github.com/Tris20/FairFareFinder/utils/common/model v0.0.1 // indirect
+	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
+	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/schollz/progressbar/v3 v3.14.2 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+	golang.org/x/term v0.25.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+replace github.com/Tris20/FairFareFinder => ../../../../
+
+replace github.com/Tris20/FairFareFinder/utils/common/model => ../../../../utils/common/model
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/pool"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	// Air quality forecasts change slower than the weather; refetch twice a day
+	policy := freshness.RegisterFlags(11 * time.Hour)
+	workers := pool.RegisterFlags(4)
+	flag.Parse()
+
+	locationsDB, err := sql.Open("sqlite3", "../../../../data/raw/locations/locations.db")
+	if err != nil {
+		log.Fatalf("Error opening locations.db: %v", err)
+	}
+	defer locationsDB.Close()
+
+	airQualityDBPath := "../../../../data/raw/air-quality/air-quality.db"
+	initAirQualityDB(airQualityDBPath)
+
+	db, err := sql.Open("sqlite3", airQualityDBPath)
+	if err != nil {
+		log.Fatalf("Failed to open the database: %v", err)
+	}
+	defer db.Close()
+
+	allAirports, err := fetchAirports(locationsDB)
+	if err != nil {
+		log.Fatalf("Error fetching airports: %v", err)
+	}
+
+	// Only refetch airports whose forecast is older than the freshness policy
+	lastFetched, err := fetchLastFetched(db)
+	if err != nil {
+		log.Fatalf("Error reading fetched_at from air-quality.db: %v", err)
+	}
+	airportsByIATA := make(map[string]AirportInfo)
+	var items []freshness.Item
+	for _, airport := range allAirports {
+		if _, seen := airportsByIATA[airport.IATA]; seen {
+			continue
+		}
+		airportsByIATA[airport.IATA] = airport
+		items = append(items, freshness.Item{Key: airport.IATA, FetchedAt: lastFetched[airport.IATA], Calls: 1})
+	}
+	plan := policy.BuildPlan(items)
+
+	if policy.DryRun {
+		freshness.PrintPlan("Air quality", plan)
+		return
+	}
+	fmt.Printf("Fetching air quality for %d airports, %d still fresh\n", len(plan.Fetch), len(plan.Skipped))
+
+	var iatas []string
+	for _, item := range plan.Fetch {
+		iatas = append(iatas, item.Key)
+	}
+
+	providersConfig, err := config_handlers.LoadAirQualityProvidersConfig("../../../../config/config.yaml")
+	if err != nil {
+		log.Fatalf("Error loading air quality providers from config.yaml: %v", err)
+	}
+
+	// Shares OpenWeatherMap's free tier of 60 calls per minute with fetch/weather, which runs separately
+	limits := pool.NewHostLimits(map[string]int{openWeatherMapHost: 50})
+	chain, err := newProviderChain(providersConfig.AirQuality.Providers, limits)
+	if err != nil {
+		log.Fatalf("Error configuring air quality providers: %v", err)
+	}
+
+	fetch := func(iata string) (AirQualityBatch, error) {
+		airport := airportsByIATA[iata]
+		readings, err := chain.Forecast(airport)
+		if err != nil {
+			return AirQualityBatch{}, fmt.Errorf("%s, %s: %v", airport.City, airport.Country, err)
+		}
+		return AirQualityBatch{Airport: airport, Readings: readings}, nil
+	}
+
+	store := func(results []pool.Result[AirQualityBatch]) error {
+		batch := make([]AirQualityBatch, 0, len(results))
+		for _, r := range results {
+			batch = append(batch, r.Value)
+		}
+		return storeAirQualityBatch(db, batch)
+	}
+
+	summary := pool.Run(pool.Pool{Workers: *workers, BatchSize: 50, Description: "Air quality"}, iatas, fetch, store)
+	summary.Print()
+}
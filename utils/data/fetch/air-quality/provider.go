@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/pool"
+)
+
+// AirQualityProvider returns hourly air quality forecasts for an airport.
+// Every provider maps its own index onto the 1 to 5 scale of AirQualityData.AQI.
+type AirQualityProvider interface {
+	Name() string
+	Host() string
+	Forecast(airport AirportInfo) ([]AirQualityData, error)
+}
+
+// newAirQualityProvider creates a provider from its name in config.yaml
+func newAirQualityProvider(name string) (AirQualityProvider, error) {
+	switch strings.ToLower(name) {
+	case "openweathermap":
+		return &openWeatherMap{}, nil
+	default:
+		return nil, fmt.Errorf("unknown air quality provider %q", name)
+	}
+}
+
+// ProviderChain tries each provider in order and returns the first forecast that succeeds
+type ProviderChain struct {
+	providers []AirQualityProvider
+	limits    *pool.HostLimits
+}
+
+// newProviderChain builds the fallback chain configured in config.yaml
+func newProviderChain(names []string, limits *pool.HostLimits) (*ProviderChain, error) {
+	if len(names) == 0 {
+		names = []string{"openweathermap"}
+	}
+
+	chain := &ProviderChain{limits: limits}
+	for _, name := range names {
+		provider, err := newAirQualityProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		chain.providers = append(chain.providers, provider)
+	}
+	return chain, nil
+}
+
+// Forecast returns the forecast from the first provider that answers, tagged with that provider's name
+func (c *ProviderChain) Forecast(airport AirportInfo) ([]AirQualityData, error) {
+	var errs []string
+	for _, provider := range c.providers {
+		c.limits.Wait(provider.Host())
+		readings, err := provider.Forecast(airport)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+		for i := range readings {
+			readings[i].Provider = provider.Name()
+		}
+		return readings, nil
+	}
+	return nil, fmt.Errorf("all air quality providers failed: %s", strings.Join(errs, "; "))
+}
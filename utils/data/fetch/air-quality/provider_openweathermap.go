@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+)
+
+const openWeatherMapHost = "api.openweathermap.org"
+
+// AirPollutionResponse is the part of OpenWeatherMap's air pollution forecast we use
+type AirPollutionResponse struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			AQI int `json:"aqi"`
+		} `json:"main"`
+		Components struct {
+			PM25 float64 `json:"pm2_5"`
+			PM10 float64 `json:"pm10"`
+			O3   float64 `json:"o3"`
+			NO2  float64 `json:"no2"`
+		} `json:"components"`
+	} `json:"list"`
+}
+
+// openWeatherMap reads OpenWeatherMap's hourly air pollution forecast, about 4 days ahead
+type openWeatherMap struct{}
+
+func (o *openWeatherMap) Name() string { return "openweathermap" }
+
+func (o *openWeatherMap) Host() string { return openWeatherMapHost }
+
+func (o *openWeatherMap) Forecast(airport AirportInfo) ([]AirQualityData, error) {
+	apiKey, err := config_handlers.LoadApiKey("../../../../ignore/secrets.yaml", "openweathermap.org")
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("https://%s/data/2.5/air_pollution/forecast?lat=%.4f&lon=%.4f&appid=%s", openWeatherMapHost, airport.Lat, airport.Lon, apiKey)
+
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("received non-200 status code from air pollution API: %d, response: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResp AirPollutionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, err
+	}
+
+	var result []AirQualityData
+	for _, item := range apiResp.List {
+		if item.Main.AQI < 1 || item.Main.AQI > 5 {
+			continue
+		}
+		result = append(result, AirQualityData{
+			Date: time.Unix(item.Dt, 0).UTC().Format("2006-01-02 15:04:05"),
+			AQI:  item.Main.AQI,
+			PM25: item.Components.PM25,
+			PM10: item.Components.PM10,
+			O3:   item.Components.O3,
+			NO2:  item.Components.NO2,
+		})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no air quality forecast for %s", airport.IATA)
+	}
+	return result, nil
+}
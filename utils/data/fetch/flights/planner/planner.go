@@ -0,0 +1,154 @@
+package planner
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
+)
+
+/*
+The planner decides which routes a price fetch spends its API calls on. Every stale route
+is scored by the expected value of a real observation: how unsure the predicted price is,
+how much the route matters to visitors, and how far an old observation has drifted. Routes
+are then taken best value per call first until the call budget runs out.
+*/
+
+// defaultUncertainty is the relative width of a route without a prediction interval,
+// wider than most intervals because nothing at all is known about its price
+const defaultUncertainty = 2.0
+
+// observationLifetime is how long an observed price takes to be worth no more than a
+// prediction. Older observations are as uncertain as never having looked.
+const observationLifetime = 7 * 24 * time.Hour
+
+// Route is a route the fetcher may price
+type Route struct {
+	freshness.Item
+	OriginIATA      string
+	DestinationIATA string
+	DestinationCity string
+}
+
+// Score is why a route is worth pricing. Value is the product of the others.
+type Score struct {
+	Uncertainty float64 // Width of the prediction interval relative to the predicted price
+	Demand      float64 // Grows with the route's frequency and how often visitors are shown it
+	Staleness   float64 // 0 for a price just seen, 1 for one never seen or a week old
+	Value       float64
+}
+
+// ScoredRoute is a route with its score
+type ScoredRoute struct {
+	Route
+	Score
+}
+
+// valuePerCall ranks routes that cost different numbers of calls fairly
+func (r ScoredRoute) valuePerCall() float64 {
+	if r.Calls <= 0 {
+		return r.Value
+	}
+	return r.Value / float64(r.Calls)
+}
+
+// Plan is what a price fetch will and will not price
+type Plan struct {
+	Budget   int           // API calls the fetch may spend; 0 for no limit
+	Fetch    []ScoredRoute // Best value per call first
+	Deferred []ScoredRoute // Stale, but over budget
+	Fresh    []Route       // Priced recently enough to skip
+}
+
+// Calls returns the number of API calls the plan spends
+func (p Plan) Calls() int {
+	total := 0
+	for _, r := range p.Fetch {
+		total += r.Calls
+	}
+	return total
+}
+
+// ScoreRoute weighs what a real price for r would tell us that the signals do not
+func ScoreRoute(r Route, signals Signals, now time.Time) Score {
+	s := Score{Uncertainty: defaultUncertainty, Staleness: 1}
+
+	prediction, predicted := signals.Predictions[r.OriginIATA+"|"+r.DestinationIATA]
+	if predicted && prediction.Low.Valid && prediction.High.Valid && prediction.Price.Valid && prediction.Price.Float64 > 0 {
+		s.Uncertainty = (prediction.High.Float64 - prediction.Low.Float64) / prediction.Price.Float64
+	}
+
+	// Popular routes and destinations are worth more, but with diminishing returns so a
+	// handful of hubs cannot take the whole budget
+	s.Demand = (1 + math.Log1p(float64(prediction.RouteFrequency))) *
+		(1 + math.Log1p(float64(signals.Appearances[r.DestinationCity])))
+
+	if r.FetchedAt.Valid {
+		if fetchedAt, err := time.Parse(freshness.TimeLayout, r.FetchedAt.String); err == nil {
+			s.Staleness = math.Min(1, math.Max(0, now.UTC().Sub(fetchedAt).Hours()/observationLifetime.Hours()))
+		}
+	}
+
+	s.Value = s.Uncertainty * s.Demand * s.Staleness
+	return s
+}
+
+// Build leaves out the routes the policy considers fresh, scores the rest, and fills the
+// budget with the best value per call. A route that does not fit is deferred, but cheaper
+// routes after it may still fit.
+func Build(routes []Route, signals Signals, policy freshness.Policy, budget int, now time.Time) Plan {
+	plan := Plan{Budget: budget}
+	var stale []ScoredRoute
+	for _, r := range routes {
+		if !policy.IsStale(r.FetchedAt, now) {
+			plan.Fresh = append(plan.Fresh, r)
+			continue
+		}
+		stale = append(stale, ScoredRoute{Route: r, Score: ScoreRoute(r, signals, now)})
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].valuePerCall() > stale[j].valuePerCall()
+	})
+
+	spent := 0
+	for _, r := range stale {
+		if budget > 0 && spent+r.Calls > budget {
+			plan.Deferred = append(plan.Deferred, r)
+			continue
+		}
+		spent += r.Calls
+		plan.Fetch = append(plan.Fetch, r)
+	}
+	return plan
+}
+
+// PrintPlan writes a dry-run summary of the plan to stdout
+func PrintPlan(name string, plan Plan) {
+	budget := "no limit"
+	if plan.Budget > 0 {
+		budget = fmt.Sprintf("%d", plan.Budget)
+	}
+	fmt.Printf("\n%s dry run: %d to fetch, %d deferred, %d still fresh, %d API calls of %s\n",
+		name, len(plan.Fetch), len(plan.Deferred), len(plan.Fresh), plan.Calls(), budget)
+	fmt.Printf("  %-40s %8s %11s %7s %9s %6s  %s\n", "Route", "Value", "Uncertainty", "Demand", "Staleness", "Calls", "Last fetched")
+	for _, r := range plan.Fetch {
+		fmt.Printf("  %-40s %8.2f %11.2f %7.2f %9.2f %6d  %s\n", r.Key, r.Value, r.Uncertainty, r.Demand, r.Staleness, r.Calls, lastFetched(r.FetchedAt))
+	}
+	if len(plan.Deferred) > 0 {
+		fmt.Printf("  Deferred to a later run:\n")
+		for _, r := range plan.Deferred {
+			fmt.Printf("  %-40s %8.2f %11.2f %7.2f %9.2f %6d  %s\n", r.Key, r.Value, r.Uncertainty, r.Demand, r.Staleness, r.Calls, lastFetched(r.FetchedAt))
+		}
+	}
+}
+
+func lastFetched(fetchedAt sql.NullString) string {
+	if fetchedAt.Valid {
+		return fetchedAt.String
+	}
+	return "never"
+}
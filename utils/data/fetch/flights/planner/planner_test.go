@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
+)
+
+var now = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func fetchedAt(ago time.Duration) sql.NullString {
+	return sql.NullString{String: now.Add(-ago).Format(freshness.TimeLayout), Valid: true}
+}
+
+func route(key string, fetched sql.NullString, calls int) Route {
+	return Route{Item: freshness.Item{Key: key, FetchedAt: fetched, Calls: calls}}
+}
+
+func keys(routes []ScoredRoute) []string {
+	var ks []string
+	for _, r := range routes {
+		ks = append(ks, r.Key)
+	}
+	return ks
+}
+
+// TestBuild fills the budget best value per call first, and defers a route that does
+// not fit without stopping cheaper routes after it
+func TestBuild(t *testing.T) {
+	// Without signals every stale route is as unsure and in as much demand, so value per
+	// call falls with calls and with how recently the route was priced
+	routes := []Route{
+		route("fresh", fetchedAt(time.Hour), 1),
+		route("three-calls", sql.NullString{}, 3),
+		route("one-call", sql.NullString{}, 1),
+		route("five-calls", sql.NullString{}, 5),
+		route("recent", fetchedAt(observationLifetime/4), 1),
+		route("two-calls", sql.NullString{}, 2),
+	}
+	policy := freshness.Policy{MaxAge: 6 * time.Hour}
+
+	tests := []struct {
+		name         string
+		budget       int
+		wantFetch    []string
+		wantDeferred []string
+		wantCalls    int
+	}{
+		{"within budget", 4, []string{"one-call", "two-calls", "recent"}, []string{"three-calls", "five-calls"}, 4},
+		{"budget for all", 12, []string{"one-call", "two-calls", "three-calls", "recent", "five-calls"}, nil, 12},
+		{"no limit", 0, []string{"one-call", "two-calls", "three-calls", "recent", "five-calls"}, nil, 12},
+		{"room for one call", 1, []string{"one-call"}, []string{"two-calls", "three-calls", "recent", "five-calls"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Build(routes, Signals{}, policy, tt.budget, now)
+
+			if got := keys(plan.Fetch); !reflect.DeepEqual(got, tt.wantFetch) {
+				t.Errorf("fetch %v, want %v", got, tt.wantFetch)
+			}
+			if got := keys(plan.Deferred); !reflect.DeepEqual(got, tt.wantDeferred) {
+				t.Errorf("deferred %v, want %v", got, tt.wantDeferred)
+			}
+			if plan.Calls() != tt.wantCalls {
+				t.Errorf("plan costs %d calls, want %d", plan.Calls(), tt.wantCalls)
+			}
+			if len(plan.Fresh) != 1 || plan.Fresh[0].Key != "fresh" {
+				t.Errorf("fresh %v, want only fresh", plan.Fresh)
+			}
+		})
+	}
+}
+
+func TestScoreRoute(t *testing.T) {
+	price := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	signals := Signals{
+		Predictions: map[string]Prediction{
+			"BER|BCN": {Price: price(100), Low: price(80), High: price(120)},
+			"BER|OPO": {Price: price(100), High: price(120)},
+			"BER|LIS": {Low: price(80), High: price(120)},
+			"BER|JFK": {Price: price(400), Low: price(300), High: price(600), RouteFrequency: 6},
+		},
+		Appearances: map[string]int{"New York": 3},
+	}
+
+	tests := []struct {
+		name            string
+		route           Route
+		wantUncertainty float64
+		wantDemand      float64
+		wantStaleness   float64
+	}{
+		{"never priced, no prediction", Route{OriginIATA: "BER", DestinationIATA: "VIE"}, defaultUncertainty, 1, 1},
+		{"prediction interval", Route{OriginIATA: "BER", DestinationIATA: "BCN"}, 0.4, 1, 1},
+		{"interval without a low bound", Route{OriginIATA: "BER", DestinationIATA: "OPO"}, defaultUncertainty, 1, 1},
+		{"interval without a price", Route{OriginIATA: "BER", DestinationIATA: "LIS"}, defaultUncertainty, 1, 1},
+		{"popular route and destination", Route{OriginIATA: "BER", DestinationIATA: "JFK", DestinationCity: "New York"}, 0.75,
+			(1 + math.Log1p(6)) * (1 + math.Log1p(3)), 1},
+		{"half a lifetime old", route("half", fetchedAt(observationLifetime/2), 1), defaultUncertainty, 1, 0.5},
+		{"older than its lifetime", route("old", fetchedAt(30*24*time.Hour), 1), defaultUncertainty, 1, 1},
+		{"fetched after now", route("ahead", fetchedAt(-time.Hour), 1), defaultUncertainty, 1, 0},
+		{"unparsable fetch time", route("unparsable", sql.NullString{String: "yesterday", Valid: true}, 1), defaultUncertainty, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ScoreRoute(tt.route, signals, now)
+			if math.Abs(s.Uncertainty-tt.wantUncertainty) > 1e-9 {
+				t.Errorf("uncertainty %v, want %v", s.Uncertainty, tt.wantUncertainty)
+			}
+			if math.Abs(s.Demand-tt.wantDemand) > 1e-9 {
+				t.Errorf("demand %v, want %v", s.Demand, tt.wantDemand)
+			}
+			if math.Abs(s.Staleness-tt.wantStaleness) > 1e-9 {
+				t.Errorf("staleness %v, want %v", s.Staleness, tt.wantStaleness)
+			}
+			if want := s.Uncertainty * s.Demand * s.Staleness; s.Value != want {
+				t.Errorf("value %v, want %v", s.Value, want)
+			}
+		})
+	}
+}
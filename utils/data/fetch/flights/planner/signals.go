@@ -0,0 +1,117 @@
+package planner
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// appearanceWindowDays is how far back search appearances count
+const appearanceWindowDays = 30
+
+// Prediction is what generate/flight-prices knows about a route
+type Prediction struct {
+	Price          sql.NullFloat64
+	Low            sql.NullFloat64
+	High           sql.NullFloat64
+	RouteFrequency int
+}
+
+// Signals is what the planner knows about routes besides when they were last priced
+type Signals struct {
+	Predictions map[string]Prediction // By "origin IATA|destination IATA"
+	// Times shown in search results in the last 30 days, by city. Not by country too: the
+	// fetcher knows countries by name and the server by ISO code.
+	Appearances map[string]int
+}
+
+// LoadSignals reads the predictions in flight-prices.db and the search statistics the
+// server records. Either may be missing; the planner then treats every route alike on
+// that signal.
+func LoadSignals(flightPricesDBPath, searchesDBPath string) (Signals, error) {
+	signals := Signals{Predictions: make(map[string]Prediction), Appearances: make(map[string]int)}
+
+	if _, err := os.Stat(flightPricesDBPath); err == nil {
+		if signals.Predictions, err = loadPredictions(flightPricesDBPath); err != nil {
+			return signals, fmt.Errorf("failed to read predictions: %v", err)
+		}
+	} else {
+		log.Printf("No predictions at %s, planning without prediction intervals", flightPricesDBPath)
+	}
+
+	if _, err := os.Stat(searchesDBPath); err == nil {
+		if signals.Appearances, err = loadAppearances(searchesDBPath); err != nil {
+			return signals, fmt.Errorf("failed to read search statistics: %v", err)
+		}
+	} else {
+		log.Printf("No search statistics at %s, planning without search demand", searchesDBPath)
+	}
+	return signals, nil
+}
+
+func loadPredictions(path string) (map[string]Prediction, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Predictions made before intervals existed have no range
+	intervalColumns := "price_low, price_high"
+	if columns, err := dbschema.Columns(db, "prediction"); err != nil {
+		return nil, err
+	} else if !columns["price_low"] {
+		intervalColumns = "NULL, NULL"
+	}
+
+	rows, err := db.Query(`SELECT origin_iata, destination_iata, NULLIF(predicted_price, 0), ` + intervalColumns + `,
+		COALESCE(route_frequency, 0)
+		FROM prediction`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	predictions := make(map[string]Prediction)
+	for rows.Next() {
+		var originIATA, destinationIATA string
+		var p Prediction
+		if err := rows.Scan(&originIATA, &destinationIATA, &p.Price, &p.Low, &p.High, &p.RouteFrequency); err != nil {
+			return nil, err
+		}
+		predictions[originIATA+"|"+destinationIATA] = p
+	}
+	return predictions, rows.Err()
+}
+
+func loadAppearances(path string) (map[string]int, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT city, SUM(appearances)
+		FROM destination_appearance
+		WHERE date >= date('now', ?)
+		GROUP BY city`, fmt.Sprintf("-%d days", appearanceWindowDays))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appearances := make(map[string]int)
+	for rows.Next() {
+		var city string
+		var count int
+		if err := rows.Scan(&city, &count); err != nil {
+			return nil, err
+		}
+		appearances[city] = count
+	}
+	return appearances, rows.Err()
+}
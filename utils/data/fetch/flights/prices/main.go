@@ -3,17 +3,22 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/Tris20/FairFareFinder/config/handlers"
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
 	"github.com/Tris20/FairFareFinder/utils/common/model"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/flights/planner"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/pool"
 
 	"github.com/Tris20/FairFareFinder/utils/data/process/generate/urls"
 	"github.com/Tris20/FairFareFinder/utils/time-and-date"
-	"github.com/schollz/progressbar/v3"
 	"io/ioutil"
 	"log"
-	"math"
 	"net/http"
+	"time"
 )
 
 var apiKey string
@@ -38,96 +43,140 @@ type Itinerary struct {
 
 var origins []model.OriginInfo
 
+const skyscannerHost = "skyscanner80.p.rapidapi.com"
+
+// All workers share one limiter so concurrency never exceeds the RapidAPI quota
+var hostLimits = pool.NewHostLimits(map[string]int{skyscannerHost: 60})
+
 func main() {
+	policy := freshness.RegisterFlags(48 * time.Hour)
+	workers := pool.RegisterFlags(4)
+	fetchConfig, err := config_handlers.LoadFlightPricesFetchConfig("../../../../../config/config.yaml")
+	if err != nil {
+		log.Printf("Error loading flight_prices config, fetching without a call budget: %v", err)
+	}
+	budget := flag.Int("budget", fetchConfig.FlightPrices.CallBudget, "API calls this run may spend, best value routes first. 0 for no limit")
+	flag.Parse()
+
 	// Load IATA, skyscanenrID etc of origins(Berlin, Glasgow, Edi)
 	originsConfig, _ := config_handlers.LoadOrigins("../../../../../config/origins.yaml")
 	origins := config_handlers.ConvertConfigToModel(originsConfig)
 	origins = update_origin_dates(origins)
-	UpdateSkyscannerPrices(origins)
+	UpdateSkyscannerPrices(origins, *policy, *budget, *workers)
 }
 
-func GetBestPrice(origin model.OriginInfo, destination model.DestinationInfo) (float64, int, error) {
-	// Load API key from secrets.yaml
-	var err error
-	apiKey, err = config_handlers.LoadApiKey("../../../../../ignore/secrets.yaml", "skyscanner")
-	if err != nil {
-		return 0, 0, fmt.Errorf("error loading API key: %v", err)
-	}
+// DatePrice is the cheapest one-way fare found for a single day
+type DatePrice struct {
+	Leg   string // "outbound" from the origin, or "return" to it
+	Date  string
+	Price float64
+}
+
+// LegPrice is the cheapest day to fly one leg of a trip. A zero Date means no day had a price.
+type LegPrice struct {
+	Date         string
+	Price        float64
+	DurationMins int
+}
+
+// Found reports whether any day had a price
+func (l LegPrice) Found() bool {
+	return l.Date != ""
+}
+
+// NullDate is the leg's date, NULL when no day had a price
+func (l LegPrice) NullDate() sql.NullString {
+	return sql.NullString{String: l.Date, Valid: l.Found()}
+}
+
+// NullPrice is the leg's price, NULL when no day had a price
+func (l LegPrice) NullPrice() sql.NullFloat64 {
+	return sql.NullFloat64{Float64: l.Price, Valid: l.Found()}
+}
+
+// NullDurationMins is the leg's duration, NULL when no day had a price
+func (l LegPrice) NullDurationMins() sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(l.DurationMins), Valid: l.Found()}
+}
 
+// GetBestPrice finds the cheapest outbound and return flights of a route, along with the
+// price of every day searched
+func GetBestPrice(origin model.OriginInfo, destination model.DestinationInfo) (LegPrice, LegPrice, []DatePrice, error) {
 	departureDates, err := timeutils.ListDatesBetween(origin.NextDepartureStartDate, origin.NextDepartureEndDate)
 	if err != nil {
-		return 0, 0, fmt.Errorf("error generating departure dates: %v", err)
+		return LegPrice{}, LegPrice{}, nil, fmt.Errorf("error generating departure dates: %v", err)
 	}
 
 	returnDates, err := timeutils.ListDatesBetween(origin.NextArrivalStartDate, origin.NextArrivalEndDate)
 	if err != nil {
-		return 0, 0, fmt.Errorf("error generating return dates: %v", err)
+		return LegPrice{}, LegPrice{}, nil, fmt.Errorf("error generating return dates: %v", err)
 	}
 
-	// Get departure price and duration
-	depPrice, depDuration, err := GetBestPriceForGivenDates(origin.SkyScannerID, destination.SkyScannerID, departureDates)
+	outbound, depDatePrices, err := GetBestPriceForGivenDates(origin.SkyScannerID, destination.SkyScannerID, departureDates)
 	if err != nil {
-		return 0, 0, err
+		return LegPrice{}, LegPrice{}, nil, err
 	}
 
-	// Get return price and duration
-	returnPrice, returnDuration, err := GetBestPriceForGivenDates(destination.SkyScannerID, origin.SkyScannerID, returnDates)
+	ret, returnDatePrices, err := GetBestPriceForGivenDates(destination.SkyScannerID, origin.SkyScannerID, returnDates)
 	if err != nil {
-		return 0, 0, err
+		return LegPrice{}, LegPrice{}, nil, err
 	}
-	// Total price and duration
 
-	totalPrice := depPrice + returnPrice
-	totalDuration := depDuration + returnDuration // Total round-trip duration
+	datePrices := make([]DatePrice, 0, len(depDatePrices)+len(returnDatePrices))
+	for _, dp := range depDatePrices {
+		dp.Leg = "outbound"
+		datePrices = append(datePrices, dp)
+	}
+	for _, dp := range returnDatePrices {
+		dp.Leg = "return"
+		datePrices = append(datePrices, dp)
+	}
 
-	return totalPrice, totalDuration, nil
+	return outbound, ret, datePrices, nil
 
 	// price = (get_lowest_departure_price() + get_lowest_arrival_price())
 
 	//return SearchOneWay(origin.SkyScannerID, destination.SkyScannerID )
 }
 
-func GetBestPriceForGivenDates(departureSkyScannerID string, arrivalSkyScannerID string, dates []string) (float64, int, error) {
-	var lowestDayPrice float64 = math.MaxFloat64
-	var err error
-	var lowestDuration int = math.MaxInt
+// errNoItineraries is a search that worked but found no flights on its date
+var errNoItineraries = errors.New("no itineraries found")
+
+// GetBestPriceForGivenDates searches every date and returns the cheapest day with its
+// flight's duration, along with the price of each date that had one.
+// Without a price on any date it only returns the zero LegPrice, stored as NULL, if every
+// search found no flights; a failed search may have missed the only price.
+func GetBestPriceForGivenDates(departureSkyScannerID string, arrivalSkyScannerID string, dates []string) (LegPrice, []DatePrice, error) {
+	var best LegPrice
+	var datePrices []DatePrice
+	var searchErr error
 	for _, date := range dates {
-		fmt.Printf("\n\nsearching %s", date)
-		price, duration, err := SearchOneWay(departureSkyScannerID, arrivalSkyScannerID, date)
+		price, durationMins, err := SearchOneWay(departureSkyScannerID, arrivalSkyScannerID, date)
+		if errors.Is(err, errNoItineraries) {
+			continue
+		}
 		if err != nil {
-			// Handle the error according to your error policy.
-			// For example, you can return the error or continue to try other dates.
-			fmt.Println("\nError fetching price for date:", date, "Error:", err)
+			// A failed date is not fatal while another date in the window has a price
+			log.Printf("Error searching %s to %s on %s: %v", departureSkyScannerID, arrivalSkyScannerID, date, err)
+			searchErr = err
 			continue
 		}
+		datePrices = append(datePrices, DatePrice{Date: date, Price: price})
 
-		if price < lowestDayPrice {
-			lowestDayPrice = price
-		}
-		if duration < lowestDuration {
-			lowestDuration = duration
+		if best.Date == "" || price < best.Price {
+			best = LegPrice{Date: date, Price: price, DurationMins: durationMins}
 		}
-
-	}
-
-	// Check if lowestDayPrice was updated, return an error if not
-	if lowestDayPrice == math.MaxFloat64 {
-		lowestDayPrice = 0.0
-		//	return 0, fmt.Errorf("no valid prices found")
 	}
 
-	// Check if lowestDayPrice was updated, return an error if not
-	if lowestDuration == math.MaxInt {
-		lowestDuration = 0
-		//	return 0, fmt.Errorf("no valid prices found")
+	if !best.Found() && searchErr != nil {
+		return LegPrice{}, nil, fmt.Errorf("no price found and searches failed: %v", searchErr)
 	}
-
-	fmt.Printf("\nLowest Price, %.2f", lowestDayPrice)
-	return lowestDayPrice, lowestDuration, err
+	return best, datePrices, nil
 }
 
+// SearchOneWay returns the cheapest fare on a date and its flight's duration in minutes
 func SearchOneWay(Departure_SkyScannerID string, Arrival_SkyScannerID string, date string) (float64, int, error) {
-	url := fmt.Sprintf("https://skyscanner80.p.rapidapi.com/api/v1/flights/search-one-way?fromId=%s&toId=%s&departDate=%s&adults=1&currency=EUR&market=US&locale=en-US", Departure_SkyScannerID, Arrival_SkyScannerID, date)
+	url := fmt.Sprintf("https://%s/api/v1/flights/search-one-way?fromId=%s&toId=%s&departDate=%s&adults=1&currency=EUR&market=US&locale=en-US", skyscannerHost, Departure_SkyScannerID, Arrival_SkyScannerID, date)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -135,8 +184,9 @@ func SearchOneWay(Departure_SkyScannerID string, Arrival_SkyScannerID string, da
 	}
 
 	req.Header.Add("X-RapidAPI-Key", apiKey)
-	req.Header.Add("X-RapidAPI-Host", "skyscanner80.p.rapidapi.com")
+	req.Header.Add("X-RapidAPI-Host", skyscannerHost)
 
+	hostLimits.Wait(skyscannerHost)
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return 0, 0, err
@@ -147,6 +197,10 @@ func SearchOneWay(Departure_SkyScannerID string, Arrival_SkyScannerID string, da
 	if err != nil {
 		return 0, 0, err
 	}
+	// Rate limits, bad keys and server errors are failures, not a day without flights
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return 0, 0, fmt.Errorf("skyscanner returned %s", res.Status)
+	}
 
 	// Parse the JSON response and determine the best price and duration
 	return determineBestPriceFromResponse(body)
@@ -160,7 +214,7 @@ func determineBestPriceFromResponse(body []byte) (float64, int, error) {
 	}
 
 	if len(response.Data.Itineraries) == 0 {
-		return 0, 0, fmt.Errorf("no itineraries found")
+		return 0, 0, errNoItineraries
 	}
 
 	bestPrice := response.Data.Itineraries[0].Price.Raw
@@ -173,10 +227,7 @@ func determineBestPriceFromResponse(body []byte) (float64, int, error) {
 		}
 	}
 
-	// Convert duration to the nearest hour
-	durationInHours := int(math.Round(float64(bestDuration) / 60))
-
-	return bestPrice, durationInHours, nil
+	return bestPrice, bestDuration, nil
 }
 
 /*
@@ -191,7 +242,166 @@ func sumcosts()
 
 */
 
-func UpdateSkyscannerPrices(origins []model.OriginInfo) {
+// RouteJob is one origin-destination pair that UpdateSkyscannerPrices may price
+type RouteJob struct {
+	Origin      model.OriginInfo
+	Destination model.DestinationInfo
+}
+
+// routeKey identifies a route in skyscannerprices and in a freshness plan
+func routeKey(originSkyScannerID, destinationSkyScannerID string) string {
+	return originSkyScannerID + "|" + destinationSkyScannerID
+}
+
+// searchesPerRoute is the number of one-way searches GetBestPrice makes for an origin
+func searchesPerRoute(origin model.OriginInfo) int {
+	departureDates, _ := timeutils.ListDatesBetween(origin.NextDepartureStartDate, origin.NextDepartureEndDate)
+	returnDates, _ := timeutils.ListDatesBetween(origin.NextArrivalStartDate, origin.NextArrivalEndDate)
+	return len(departureDates) + len(returnDates)
+}
+
+// LastPriced is when a route was last priced, and the first departure date it was searched for
+type LastPriced struct {
+	FetchedAt   sql.NullString
+	WindowStart sql.NullString
+}
+
+// fetchLastPriced returns when every route already in skyscannerprices was last priced.
+// Databases created before staleness tracking have every route as never fetched, and
+// before windows were stored, as searched for an unknown window.
+func fetchLastPriced(db *sql.DB) (map[string]LastPriced, error) {
+	columns, err := dbschema.Columns(db, "skyscannerprices")
+	if err != nil {
+		return nil, err
+	}
+	fetchedAtColumn := "fetched_at"
+	if !columns["fetched_at"] {
+		fetchedAtColumn = "NULL"
+	}
+	windowColumn := "departure_window_start"
+	if !columns[windowColumn] {
+		windowColumn = "NULL"
+	}
+	// SQLite takes the bare window column from the row with the latest fetched_at
+	rows, err := db.Query(`SELECT origin_skyscanner_id, destination_skyscanner_id, MAX(` + fetchedAtColumn + `), ` + windowColumn + `
+		FROM skyscannerprices GROUP BY origin_skyscanner_id, destination_skyscanner_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lastPriced := make(map[string]LastPriced)
+	for rows.Next() {
+		var originID, destinationID string
+		var lp LastPriced
+		if err := rows.Scan(&originID, &destinationID, &lp.FetchedAt, &lp.WindowStart); err != nil {
+			return nil, err
+		}
+		lastPriced[routeKey(originID, destinationID)] = lp
+	}
+	return lastPriced, rows.Err()
+}
+
+// fetchedAtFor is when a route was last priced for the window from windowStart. Prices of
+// another window, such as last weekend's once the window has rolled over, count as never
+// fetched, however recent.
+func (lp LastPriced) fetchedAtFor(windowStart string) sql.NullString {
+	if !lp.WindowStart.Valid || lp.WindowStart.String != windowStart {
+		return sql.NullString{}
+	}
+	return lp.FetchedAt
+}
+
+// planRoutes lists every route flown from the origins and plans which of the stale ones
+// are worth the budget
+func planRoutes(db *sql.DB, origins []model.OriginInfo, policy freshness.Policy, budget int) ([]RouteJob, planner.Plan, error) {
+	lastPriced, err := fetchLastPriced(db)
+	if err != nil {
+		return nil, planner.Plan{}, err
+	}
+	signals, err := planner.LoadSignals("../../../../../data/generated/flight-prices.db", "../../../../../data/raw/searches/searches.db")
+	if err != nil {
+		return nil, planner.Plan{}, err
+	}
+
+	jobsByKey := make(map[string]RouteJob)
+	var routes []planner.Route
+	for _, origin := range origins {
+		// Assume DetermineFlightsFromConfig and GenerateFlightsAndHotelsURLs are functions that return valid results
+		airportDetailsList := DetermineFlightsFromConfig(origin)
+		destinationsWithUrls := urlgenerators.GenerateFlightsAndHotelsURLs(origin, airportDetailsList)
+		calls := searchesPerRoute(origin)
+
+		for _, destination := range destinationsWithUrls {
+			key := routeKey(origin.SkyScannerID, destination.SkyScannerID)
+			if _, seen := jobsByKey[key]; seen {
+				continue
+			}
+			jobsByKey[key] = RouteJob{Origin: origin, Destination: destination}
+			routes = append(routes, planner.Route{
+				Item: freshness.Item{
+					Key:       key,
+					FetchedAt: lastPriced[key].fetchedAtFor(origin.NextDepartureStartDate),
+					Calls:     calls,
+				},
+				OriginIATA:      origin.IATA,
+				DestinationIATA: destination.IATA,
+				DestinationCity: destination.City,
+			})
+		}
+	}
+
+	plan := planner.Build(routes, signals, policy, budget, time.Now())
+	var jobs []RouteJob
+	for _, route := range plan.Fetch {
+		jobs = append(jobs, jobsByKey[route.Key])
+	}
+	return jobs, plan, nil
+}
+
+// RoutePrice is the fetched price of a RouteJob
+type RoutePrice struct {
+	Job        RouteJob
+	Outbound   LegPrice
+	Return     LegPrice
+	DatePrices []DatePrice
+	FetchedAt  string
+}
+
+// WindowStart is the first departure date searched, which tells the windows' prices apart
+func (rp RoutePrice) WindowStart() string {
+	return rp.Job.Origin.NextDepartureStartDate
+}
+
+// Price is the round trip: the cheapest outbound plus the cheapest return.
+// NULL unless both legs have a price.
+func (rp RoutePrice) Price() sql.NullFloat64 {
+	if !rp.Outbound.Found() || !rp.Return.Found() {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: rp.Outbound.Price + rp.Return.Price, Valid: true}
+}
+
+// DurationMins is the time spent flying there and back, NULL unless both legs have a price
+func (rp RoutePrice) DurationMins() sql.NullInt64 {
+	if !rp.Outbound.Found() || !rp.Return.Found() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(rp.Outbound.DurationMins + rp.Return.DurationMins), Valid: true}
+}
+
+// legColumns are the skyscannerprices columns of each leg, added after the round trip price
+var legColumns = map[string]string{
+	"outbound_date":          "TEXT",
+	"outbound_price":         "REAL",
+	"outbound_duration_mins": "INTEGER",
+	"return_date":            "TEXT",
+	"return_price":           "REAL",
+	"return_duration_mins":   "INTEGER",
+}
+
+// UpdateSkyscannerPrices prices the routes the planner picks for the budget, 0 for no limit
+func UpdateSkyscannerPrices(origins []model.OriginInfo, policy freshness.Policy, budget int, workers int) {
 	// Open SQLite database
 	db, err := sql.Open("sqlite3", "../../../../../data/raw/flights/flights.db")
 	if err != nil {
@@ -199,6 +409,47 @@ func UpdateSkyscannerPrices(origins []model.OriginInfo) {
 	}
 	defer db.Close()
 
+	// A dry run writes nothing, so the migrations wait until the plan is carried out
+	jobs, plan, err := planRoutes(db, origins, policy, budget)
+	if err != nil {
+		log.Fatalf("Failed to plan routes: %v", err)
+	}
+
+	if policy.DryRun {
+		planner.PrintPlan("Skyscanner prices", plan)
+		return
+	}
+
+	// Databases created before staleness tracking don't have fetched_at yet
+	if err := freshness.EnsureFetchedAtColumn(db, "skyscannerprices"); err != nil {
+		log.Fatalf("Failed to migrate skyscannerprices: %v", err)
+	}
+	for column, columnType := range legColumns {
+		if err := freshness.EnsureColumn(db, "skyscannerprices", column, columnType); err != nil {
+			log.Fatalf("Failed to migrate skyscannerprices: %v", err)
+		}
+	}
+	if err := freshness.EnsureColumn(db, "skyscannerprices", "departure_window_start", "TEXT"); err != nil {
+		log.Fatalf("Failed to migrate skyscannerprices: %v", err)
+	}
+	if err := ensureRoutePriceByDateTable(db); err != nil {
+		log.Fatalf("Failed to create route_price_by_date: %v", err)
+	}
+	if err := ensurePriceObservationTable(db); err != nil {
+		log.Fatalf("Failed to create price_observation: %v", err)
+	}
+	if err := clearZeroPrices(db); err != nil {
+		log.Fatalf("Failed to clear zero prices: %v", err)
+	}
+
+	log.Printf("Pricing %d routes (%d API calls), %d deferred over budget, %d still fresh", len(plan.Fetch), plan.Calls(), len(plan.Deferred), len(plan.Fresh))
+
+	// Load API key from secrets.yaml once, before any worker starts
+	apiKey, err = config_handlers.LoadApiKey("../../../../../ignore/secrets.yaml", "skyscanner")
+	if err != nil {
+		log.Fatalf("Error loading API key: %v", err)
+	}
+
 	fmt.Printf("\n\n\n COPY WEEKEND")
 
 	// SQL statement to copy "next_weekend" to "this_weekend"
@@ -211,92 +462,198 @@ func UpdateSkyscannerPrices(origins []model.OriginInfo) {
 	}
 	log.Println("Table updated successfully.")
 
-	/*
-		// Update if entry exists
-		updateStmt, err := db.Prepare("UPDATE skyscannerprices SET next_weekend = ? WHERE origin_skyscanner_id = ? AND destination_skyscanner_id = ?")
-		if err != nil {
-			log.Fatalf("Failed to prepare update statement: %v", err)
-		}
-		defer updateStmt.Close()
-		// Create if entry for dest AND origin does not exist
-		insertStmt, err := db.Prepare("INSERT INTO skyscannerprices (origin_city, origin_country, origin_iata, origin_skyscanner_id, destination_city, destination_country, destination_iata, destination_skyscanner_id, next_weekend) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	jobsByKey := make(map[string]RouteJob)
+	var keys []string
+	for _, job := range jobs {
+		key := routeKey(job.Origin.SkyScannerID, job.Destination.SkyScannerID)
+		jobsByKey[key] = job
+		keys = append(keys, key)
+	}
+
+	fetch := func(key string) (RoutePrice, error) {
+		job := jobsByKey[key]
+		outbound, ret, datePrices, err := GetBestPrice(job.Origin, job.Destination)
 		if err != nil {
-			log.Fatalf("Failed to prepare insert statement: %v", err)
+			return RoutePrice{}, fmt.Errorf("%s to %s: %v", job.Origin.IATA, job.Destination.IATA, err)
 		}
-		defer insertStmt.Close()
-	*/
+		return RoutePrice{Job: job, Outbound: outbound, Return: ret, DatePrices: datePrices, FetchedAt: freshness.Now()}, nil
+	}
+
+	store := func(results []pool.Result[RoutePrice]) error {
+		return storeRoutePriceBatch(db, results)
+	}
+
+	summary := pool.Run(pool.Pool{Workers: workers, BatchSize: 25, Description: "Skyscanner prices"}, keys, fetch, store)
+	summary.Print()
+}
+
+// storeRoutePriceBatch updates or inserts a batch of route prices in a single transaction
+func storeRoutePriceBatch(db *sql.DB, results []pool.Result[RoutePrice]) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback() // Rollback on error
 
 	// HOTFIX setting both this weekend and nextweekend to price value because we don't use both prices in the output table yet
-	updateStmt, err := db.Prepare(`
+	// duration is the round trip in minutes
+	updateStmt, err := tx.Prepare(`
     UPDATE skyscannerprices 
-    SET next_weekend = ?, this_weekend = ?, duration = ? 
+    SET next_weekend = ?, this_weekend = ?, duration = ?, fetched_at = ?,
+        outbound_date = ?, outbound_price = ?, outbound_duration_mins = ?,
+        return_date = ?, return_price = ?, return_duration_mins = ?, departure_window_start = ?
     WHERE origin_skyscanner_id = ? 
     AND destination_skyscanner_id = ?`)
 	if err != nil {
-		log.Fatalf("Failed to prepare update statement: %v", err)
+		return fmt.Errorf("failed to prepare update statement: %v", err)
 	}
 	defer updateStmt.Close()
 
-	insertStmt, err := db.Prepare(`
+	insertStmt, err := tx.Prepare(`
     INSERT INTO skyscannerprices 
-    (origin_city, origin_country, origin_iata, origin_skyscanner_id, destination_city, destination_country, destination_iata, destination_skyscanner_id, next_weekend, this_weekend, duration) 
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+    (origin_city, origin_country, origin_iata, origin_skyscanner_id, destination_city, destination_country, destination_iata, destination_skyscanner_id, next_weekend, this_weekend, duration, fetched_at,
+     outbound_date, outbound_price, outbound_duration_mins, return_date, return_price, return_duration_mins, departure_window_start) 
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
-		log.Fatalf("Failed to prepare insert statement: %v", err)
+		return fmt.Errorf("failed to prepare insert statement: %v", err)
 	}
 	defer insertStmt.Close()
 
-	println("HERE4\n")
-	totalDestinations := calculateTotalDestinations(origins) // Function to sum up all destinations for all origins
+	datePriceStmt, err := tx.Prepare(`
+    INSERT OR REPLACE INTO route_price_by_date
+    (origin_city, origin_country, origin_iata, origin_skyscanner_id, destination_city, destination_country, destination_iata, destination_skyscanner_id, leg, date, price, fetched_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare date price statement: %v", err)
+	}
+	defer datePriceStmt.Close()
 
-	// Create a new progress bar
-	bar := progressbar.Default(int64(totalDestinations))
+	observationStmt, err := tx.Prepare(`
+    INSERT INTO price_observation
+    (origin_city, origin_country, origin_iata, origin_skyscanner_id, destination_city, destination_country, destination_iata, destination_skyscanner_id, leg, travel_date, observed_at, price, source)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare price observation statement: %v", err)
+	}
+	defer observationStmt.Close()
 
-	for _, origin := range origins {
-		// Assume DetermineFlightsFromConfig and GenerateFlightsAndHotelsURLs are functions that return valid results
-		airportDetailsList := DetermineFlightsFromConfig(origin)
-		destinationsWithUrls := urlgenerators.GenerateFlightsAndHotelsURLs(origin, airportDetailsList)
+	for _, r := range results {
+		rp := r.Value
+		origin, destination := rp.Job.Origin, rp.Job.Destination
 
-		println("HERE5\n")
-		for _, destination := range destinationsWithUrls {
-			price, duration, err := GetBestPrice(origin, destination)
+		// Execute the update statement for each origin-destination pair with the new price
+		outbound, ret := rp.Outbound, rp.Return
+		result, err := updateStmt.Exec(rp.Price(), rp.Price(), rp.DurationMins(), rp.FetchedAt,
+			outbound.NullDate(), outbound.NullPrice(), outbound.NullDurationMins(), ret.NullDate(), ret.NullPrice(), ret.NullDurationMins(), rp.WindowStart(),
+			origin.SkyScannerID, destination.SkyScannerID)
+		if err != nil {
+			return fmt.Errorf("failed to update price for %s to %s: %v", origin.IATA, destination.IATA, err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error checking rows affected for %s to %s: %v", origin.IATA, destination.IATA, err)
+		}
+
+		// If no rows were updated, insert a new row
+		if rowsAffected == 0 {
+			_, err = insertStmt.Exec(origin.City, origin.Country,
+				origin.IATA, origin.SkyScannerID, destination.City, destination.Country, destination.IATA, destination.SkyScannerID, rp.Price(), rp.Price(), rp.DurationMins(), rp.FetchedAt,
+				outbound.NullDate(), outbound.NullPrice(), outbound.NullDurationMins(), ret.NullDate(), ret.NullPrice(), ret.NullDurationMins(), rp.WindowStart())
 			if err != nil {
-				log.Printf("Error getting best price for %s to %s: %v", origin.SkyScannerID, destination.SkyScannerID, err)
-				bar.Add(1) // Increment progress bar even on error
-				continue   // Continue with the next destination if there's an error
+				return fmt.Errorf("failed to insert price for %s to %s: %v", origin.IATA, destination.IATA, err)
 			}
+		}
 
-			// Execute the update statement for each origin-destination pair with the new price
-			result, err := updateStmt.Exec(price, price, duration, origin.SkyScannerID, destination.SkyScannerID)
+		for _, dp := range rp.DatePrices {
+			_, err = datePriceStmt.Exec(origin.City, origin.Country, origin.IATA, origin.SkyScannerID,
+				destination.City, destination.Country, destination.IATA, destination.SkyScannerID, dp.Leg, dp.Date, dp.Price, rp.FetchedAt)
 			if err != nil {
-				log.Printf("Failed to update price for %s to %s: %v", origin.SkyScannerID, destination.SkyScannerID, err)
-				bar.Add(1) // Increment progress bar even on error
-				continue
+				return fmt.Errorf("failed to store %s price on %s for %s to %s: %v", dp.Leg, dp.Date, origin.IATA, destination.IATA, err)
 			}
-
-			rowsAffected, err := result.RowsAffected()
+			_, err = observationStmt.Exec(origin.City, origin.Country, origin.IATA, origin.SkyScannerID,
+				destination.City, destination.Country, destination.IATA, destination.SkyScannerID, dp.Leg, dp.Date, rp.FetchedAt, dp.Price, priceSource)
 			if err != nil {
-				log.Printf("Error checking rows affected for %s to %s: %v", origin.SkyScannerID, destination.SkyScannerID, err)
-				bar.Add(1) // Increment progress bar even on error
-				continue
+				return fmt.Errorf("failed to record %s price on %s for %s to %s: %v", dp.Leg, dp.Date, origin.IATA, destination.IATA, err)
 			}
+		}
+	}
 
-			// If no rows were updated, insert a new row
-			if rowsAffected == 0 {
-				_, err = insertStmt.Exec(origin.City, origin.Country,
-					origin.IATA, origin.SkyScannerID, destination.City, destination.Country, destination.IATA, destination.SkyScannerID, price, price, duration)
-				if err != nil {
-					log.Printf("Failed to insert price for %s to %s: %v", origin.SkyScannerID, destination.SkyScannerID, err)
-				} else {
-					log.Printf("Successfully inserted price for %s(%s) to %s(%s): €%.2f", origin.IATA, origin.SkyScannerID, destination.IATA, destination.SkyScannerID, price)
-				}
-			} else {
-				log.Printf("Successfully updated price for %s(%s) to %s(%s): €%.2f", origin.IATA, origin.SkyScannerID, destination.IATA, destination.SkyScannerID, price)
+	return tx.Commit()
+}
 
-			}
-			bar.Add(1) // Increment progress bar even on error
+// ensureRoutePriceByDateTable creates route_price_by_date, the price of every day searched.
+// Both legs are stored under the route's origin and destination; return fares fly the other way.
+func ensureRoutePriceByDateTable(db *sql.DB) error {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS route_price_by_date (
+        origin_city TEXT,
+        origin_country TEXT,
+        origin_iata TEXT,
+        origin_skyscanner_id TEXT NOT NULL,
+        destination_city TEXT,
+        destination_country TEXT,
+        destination_iata TEXT,
+        destination_skyscanner_id TEXT NOT NULL,
+        leg TEXT NOT NULL,
+        date TEXT NOT NULL,
+        price REAL,
+        fetched_at TEXT,
+        PRIMARY KEY (origin_skyscanner_id, destination_skyscanner_id, leg, date)
+    )`)
+	return err
+}
+
+// clearZeroPrices migrates rows stored before missing prices were NULL. A leg without an
+// itinerary was stored as 0, and the round trip as the other leg alone; both become NULL.
+// Nothing costs nothing, so no real price is lost.
+func clearZeroPrices(db *sql.DB) error {
+	for _, query := range []string{
+		`UPDATE skyscannerprices SET this_weekend = NULL, next_weekend = NULL, duration = NULL
+		WHERE outbound_price = 0 OR return_price = 0 OR this_weekend = 0 OR next_weekend = 0`,
+		`UPDATE skyscannerprices SET outbound_date = NULL, outbound_price = NULL, outbound_duration_mins = NULL
+		WHERE outbound_price = 0`,
+		`UPDATE skyscannerprices SET return_date = NULL, return_price = NULL, return_duration_mins = NULL
+		WHERE return_price = 0`,
+		`DELETE FROM route_price_by_date WHERE price = 0`,
+		`DELETE FROM price_observation WHERE price = 0`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// priceSource names where the prices in price_observation come from
+const priceSource = "skyscanner"
+
+// ensurePriceObservationTable creates price_observation, every price ever fetched. Rows are
+// only ever appended, so compile can see how a route's price moves over time.
+func ensurePriceObservationTable(db *sql.DB) error {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS price_observation (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        origin_city TEXT,
+        origin_country TEXT,
+        origin_iata TEXT,
+        origin_skyscanner_id TEXT NOT NULL,
+        destination_city TEXT,
+        destination_country TEXT,
+        destination_iata TEXT,
+        destination_skyscanner_id TEXT NOT NULL,
+        leg TEXT NOT NULL,
+        travel_date TEXT NOT NULL,
+        observed_at TEXT NOT NULL,
+        price REAL NOT NULL,
+        source TEXT NOT NULL
+    )`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_price_observation_route
+    ON price_observation (origin_iata, destination_iata, observed_at)`)
+	return err
 }
 
 // Function to get price for a given pair of skyscanner IDs
@@ -312,17 +669,6 @@ func GetPriceForRoute(db *sql.DB, weekend string, origin string, destination str
 	return price, nil // Return the found price and no error
 }
 
-// this determines the length of the progress bar
-func calculateTotalDestinations(origins []model.OriginInfo) int {
-	total := 0
-	for _, origin := range origins {
-		airportDetailsList := DetermineFlightsFromConfig(origin)
-		destinationsWithUrls := urlgenerators.GenerateFlightsAndHotelsURLs(origin, airportDetailsList)
-		total += len(destinationsWithUrls)
-	}
-	return total
-}
-
 func update_origin_dates(origins []model.OriginInfo) []model.OriginInfo {
 
 	for i := range origins {
@@ -0,0 +1,116 @@
+package freshness
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
+)
+
+// TimeLayout is the format used for every fetched_at column in the raw databases.
+// Timestamps are always written in UTC so that comparisons in SQL and Go agree.
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Policy decides which keys of a raw table need to be fetched again
+type Policy struct {
+	MaxAge time.Duration
+	DryRun bool
+}
+
+// RegisterFlags adds -max-age and -dry-run to the default flag set.
+// defaultMaxAge is the freshness window used when the flag is not passed.
+func RegisterFlags(defaultMaxAge time.Duration) *Policy {
+	p := &Policy{}
+	flag.DurationVar(&p.MaxAge, "max-age", defaultMaxAge, "Refetch keys whose fetched_at is older than this (e.g. 6h, 48h). 0 refetches everything")
+	flag.BoolVar(&p.DryRun, "dry-run", false, "List what would be fetched and how many API calls it would cost, without fetching")
+	return p
+}
+
+// Now returns the current time formatted for a fetched_at column
+func Now() string {
+	return time.Now().UTC().Format(TimeLayout)
+}
+
+// IsStale reports whether a key last fetched at fetchedAt should be fetched again.
+// Keys that were never fetched (NULL or unparsable) are always stale.
+func (p Policy) IsStale(fetchedAt sql.NullString, now time.Time) bool {
+	if p.MaxAge <= 0 || !fetchedAt.Valid {
+		return true
+	}
+	t, err := time.Parse(TimeLayout, fetchedAt.String)
+	if err != nil {
+		return true
+	}
+	return now.UTC().Sub(t) >= p.MaxAge
+}
+
+// EnsureFetchedAtColumn adds a fetched_at column to tables created before it existed
+func EnsureFetchedAtColumn(db *sql.DB, table string) error {
+	return EnsureColumn(db, table, "fetched_at", "TEXT")
+}
+
+// EnsureColumn adds column to table if it is not there yet
+func EnsureColumn(db *sql.DB, table, column, columnType string) error {
+	return dbschema.EnsureColumns(db, table, []dbschema.Column{{Name: column, Definition: columnType}})
+}
+
+// Item is a single key that a fetcher may request, with the number of API calls it costs
+type Item struct {
+	Key       string
+	FetchedAt sql.NullString
+	Calls     int
+}
+
+// Plan is the result of applying a Policy to every candidate key
+type Plan struct {
+	Fetch   []Item
+	Skipped []Item
+}
+
+// Calls returns the total number of API calls needed to execute the plan
+func (p Plan) Calls() int {
+	total := 0
+	for _, item := range p.Fetch {
+		total += item.Calls
+	}
+	return total
+}
+
+// BuildPlan splits items into those to fetch and those still fresh.
+// Items to fetch are ordered never-fetched first, then oldest first, so an
+// interrupted run still refreshes the most out of date data.
+func (p Policy) BuildPlan(items []Item) Plan {
+	now := time.Now()
+	var plan Plan
+	for _, item := range items {
+		if p.IsStale(item.FetchedAt, now) {
+			plan.Fetch = append(plan.Fetch, item)
+		} else {
+			plan.Skipped = append(plan.Skipped, item)
+		}
+	}
+
+	sort.SliceStable(plan.Fetch, func(i, j int) bool {
+		a, b := plan.Fetch[i].FetchedAt, plan.Fetch[j].FetchedAt
+		if a.Valid != b.Valid {
+			return !a.Valid
+		}
+		return a.String < b.String
+	})
+	return plan
+}
+
+// PrintPlan writes a dry-run summary of the plan to stdout
+func PrintPlan(name string, plan Plan) {
+	fmt.Printf("\n%s dry run: %d to fetch, %d still fresh, %d API calls\n", name, len(plan.Fetch), len(plan.Skipped), plan.Calls())
+	for _, item := range plan.Fetch {
+		lastFetched := "never"
+		if item.FetchedAt.Valid {
+			lastFetched = item.FetchedAt.String
+		}
+		fmt.Printf("  %-40s last fetched: %-19s calls: %d\n", item.Key, lastFetched, item.Calls)
+	}
+}
@@ -0,0 +1,59 @@
+package freshness
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fetchedAt(ago time.Duration) sql.NullString {
+	return sql.NullString{String: time.Now().UTC().Add(-ago).Format(TimeLayout), Valid: true}
+}
+
+// TestBuildPlanOrder fetches never-fetched keys first, in input order, then the oldest first
+func TestBuildPlanOrder(t *testing.T) {
+	items := []Item{
+		{Key: "fresh", FetchedAt: fetchedAt(time.Hour), Calls: 1},
+		{Key: "day-old", FetchedAt: fetchedAt(24 * time.Hour), Calls: 2},
+		{Key: "never-1", Calls: 1},
+		{Key: "week-old", FetchedAt: fetchedAt(7 * 24 * time.Hour), Calls: 1},
+		{Key: "unparsable", FetchedAt: sql.NullString{String: "yesterday", Valid: true}, Calls: 1},
+		{Key: "never-2", Calls: 3},
+	}
+
+	plan := Policy{MaxAge: 6 * time.Hour}.BuildPlan(items)
+
+	var fetch []string
+	for _, item := range plan.Fetch {
+		fetch = append(fetch, item.Key)
+	}
+	// Unparsable timestamps count as stale, and sort by their text after the valid ones
+	want := []string{"never-1", "never-2", "week-old", "day-old", "unparsable"}
+	if !reflect.DeepEqual(fetch, want) {
+		t.Errorf("fetch order %v, want %v", fetch, want)
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].Key != "fresh" {
+		t.Errorf("skipped %v, want only fresh", plan.Skipped)
+	}
+	if plan.Calls() != 8 {
+		t.Errorf("plan costs %d calls, want 8", plan.Calls())
+	}
+}
+
+// TestBuildPlanZeroMaxAge refetches everything when -max-age is 0
+func TestBuildPlanZeroMaxAge(t *testing.T) {
+	items := []Item{
+		{Key: "fresh", FetchedAt: fetchedAt(time.Minute), Calls: 1},
+		{Key: "never", Calls: 1},
+	}
+
+	plan := Policy{}.BuildPlan(items)
+
+	if len(plan.Fetch) != 2 || len(plan.Skipped) != 0 {
+		t.Fatalf("got %d to fetch and %d skipped, want 2 and 0", len(plan.Fetch), len(plan.Skipped))
+	}
+	if plan.Fetch[0].Key != "never" {
+		t.Errorf("first fetch is %s, want never", plan.Fetch[0].Key)
+	}
+}
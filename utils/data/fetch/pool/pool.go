@@ -0,0 +1,151 @@
+package pool
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Pool runs fetch jobs on a bounded number of workers and hands the results
+// back to the calling goroutine in input order, so database writes stay on a
+// single connection and progress output is the same on every run.
+type Pool struct {
+	Workers     int
+	BatchSize   int
+	Description string
+}
+
+// Result is the outcome of fetching a single key
+type Result[T any] struct {
+	Key   string
+	Value T
+	Err   error
+}
+
+// RegisterFlags adds -workers to the default flag set
+func RegisterFlags(defaultWorkers int) *int {
+	return flag.Int("workers", defaultWorkers, "Number of concurrent fetch workers")
+}
+
+// Run fetches every key with at most p.Workers requests in flight.
+// Successful results are passed to flush in batches of p.BatchSize, in the
+// same order as keys. Failed fetches and failed flushes end up in the summary.
+func Run[T any](p Pool, keys []string, fetch func(key string) (T, error), flush func(batch []Result[T]) error) *Summary {
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	batchSize := p.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	type indexed struct {
+		index  int
+		result Result[T]
+	}
+
+	jobs := make(chan int)
+	done := make(chan indexed)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				value, err := fetch(keys[i])
+				done <- indexed{index: i, result: Result[T]{Key: keys[i], Value: value, Err: err}}
+			}
+		}()
+	}
+
+	go func() {
+		for i := range keys {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	summary := &Summary{Name: p.Description, Total: len(keys)}
+	bar := progressbar.Default(int64(len(keys)), p.Description)
+
+	var batch []Result[T]
+	flushBatch := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := flush(batch); err != nil {
+			for _, r := range batch {
+				summary.fail(r.Key, fmt.Errorf("store: %v", err))
+			}
+		} else {
+			summary.Succeeded += len(batch)
+		}
+		batch = batch[:0]
+	}
+
+	// Results arrive in completion order; hold them back until every
+	// earlier key has been delivered so output never depends on timing.
+	pending := make(map[int]Result[T])
+	next := 0
+	for r := range done {
+		pending[r.index] = r.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			bar.Add(1)
+
+			if result.Err != nil {
+				summary.fail(result.Key, result.Err)
+				continue
+			}
+			batch = append(batch, result)
+			if len(batch) >= batchSize {
+				flushBatch()
+			}
+		}
+	}
+	flushBatch()
+	bar.Finish()
+
+	return summary
+}
+
+// Failure records why a single key could not be fetched or stored
+type Failure struct {
+	Key string
+	Err error
+}
+
+// Summary collects the outcome of a Run
+type Summary struct {
+	Name      string
+	Total     int
+	Succeeded int
+	Failures  []Failure
+}
+
+func (s *Summary) fail(key string, err error) {
+	s.Failures = append(s.Failures, Failure{Key: key, Err: err})
+}
+
+// Print writes the totals followed by every failure, sorted by key
+func (s *Summary) Print() {
+	fmt.Printf("\n%s: %d/%d succeeded, %d failed\n", s.Name, s.Succeeded, s.Total, len(s.Failures))
+	sort.Slice(s.Failures, func(i, j int) bool {
+		return s.Failures[i].Key < s.Failures[j].Key
+	})
+	for _, f := range s.Failures {
+		fmt.Printf("  %s: %v\n", f.Key, f.Err)
+	}
+}
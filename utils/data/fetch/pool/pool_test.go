@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRunKeepsInputOrder makes later keys finish first, so results only come out in
+// order if Run holds them back until every earlier key has been delivered
+func TestRunKeepsInputOrder(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	fetch := func(key string) (string, error) {
+		time.Sleep(time.Duration('h'-key[0]) * time.Millisecond)
+		if key == "c" || key == "f" {
+			return "", fmt.Errorf("no itineraries")
+		}
+		return key + "!", nil
+	}
+
+	var flushed [][]string
+	flush := func(batch []Result[string]) error {
+		var values []string
+		for _, r := range batch {
+			values = append(values, r.Value)
+		}
+		flushed = append(flushed, values)
+		return nil
+	}
+
+	summary := Run(Pool{Workers: 4, BatchSize: 2, Description: "test"}, keys, fetch, flush)
+
+	// Failed keys are left out of the batches, the rest keep their order
+	want := [][]string{{"a!", "b!"}, {"d!", "e!"}, {"g!", "h!"}}
+	if !reflect.DeepEqual(flushed, want) {
+		t.Errorf("flushed %v, want %v", flushed, want)
+	}
+	if summary.Total != len(keys) || summary.Succeeded != 6 {
+		t.Errorf("got %d/%d succeeded, want 6/%d", summary.Succeeded, summary.Total, len(keys))
+	}
+	var failed []string
+	for _, f := range summary.Failures {
+		failed = append(failed, f.Key)
+	}
+	if !reflect.DeepEqual(failed, []string{"c", "f"}) {
+		t.Errorf("failed keys %v, want [c f]", failed)
+	}
+}
+
+// TestRunRecordsFailedFlushes counts every key of a batch that could not be stored as failed
+func TestRunRecordsFailedFlushes(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	fetch := func(key string) (string, error) { return key, nil }
+	flush := func(batch []Result[string]) error {
+		if batch[0].Key == "a" {
+			return fmt.Errorf("database is locked")
+		}
+		return nil
+	}
+
+	summary := Run(Pool{Workers: 2, BatchSize: 2, Description: "test"}, keys, fetch, flush)
+
+	if summary.Succeeded != 1 || len(summary.Failures) != 2 {
+		t.Fatalf("got %d succeeded and %d failed, want 1 and 2", summary.Succeeded, len(summary.Failures))
+	}
+	for i, key := range []string{"a", "b"} {
+		if summary.Failures[i].Key != key {
+			t.Errorf("failure %d is %s, want %s", i, summary.Failures[i].Key, key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces requests to one host evenly, shared by all workers
+type RateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// NewRateLimiter allows at most requestsPerMinute requests per minute.
+// A value of 0 or less disables limiting.
+func NewRateLimiter(requestsPerMinute int) *RateLimiter {
+	if requestsPerMinute <= 0 {
+		return &RateLimiter{}
+	}
+	return &RateLimiter{interval: time.Minute / time.Duration(requestsPerMinute)}
+}
+
+// Wait blocks until the caller may send its next request
+func (r *RateLimiter) Wait() {
+	if r.interval == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// HostLimits holds one RateLimiter per API host
+type HostLimits struct {
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+// NewHostLimits creates limiters from a map of host to requests per minute
+func NewHostLimits(requestsPerMinute map[string]int) *HostLimits {
+	h := &HostLimits{limiters: make(map[string]*RateLimiter)}
+	for host, rpm := range requestsPerMinute {
+		h.limiters[host] = NewRateLimiter(rpm)
+	}
+	return h
+}
+
+// Wait blocks until a request to host is allowed. Hosts without a configured limit are not limited.
+func (h *HostLimits) Wait(host string) {
+	h.mu.Lock()
+	limiter, ok := h.limiters[host]
+	h.mu.Unlock()
+	if ok {
+		limiter.Wait()
+	}
+}
@@ -4,7 +4,9 @@ package main
 import (
 	"database/sql"
 	"log"
+	"strings"
 
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -13,12 +15,16 @@ type AirportInfo struct {
 	City    string
 	Country string
   IATA    string
+	Lat     sql.NullFloat64
+	Lon     sql.NullFloat64
+	// IANA zone, e.g. "Europe/Lisbon"; forecasts are stored in UTC and converted with it when compiled
+	Timezone sql.NullString
 }
 
 // fetchAirports retrieves all airports with non-empty IATA codes from flights.db
 func fetchAirports(db *sql.DB) ([]AirportInfo, error) {
 
-query := `SELECT a.city, a.country, a.iata
+query := `SELECT a.city, a.country, a.iata, a.lat, a.lon, a.tz
 FROM airport a
 JOIN city c ON LOWER(TRIM(a.city)) = LOWER(TRIM(c.city_ascii)) 
             AND LOWER(TRIM(a.country)) = LOWER(TRIM(c.iso2))  -- Using iso2 for country code
@@ -38,7 +44,7 @@ AND c.include_tf = 1;
 	var airports []AirportInfo
 	for rows.Next() {
 		var ai AirportInfo
-		if err := rows.Scan(&ai.City, &ai.Country, &ai.IATA); err != nil {
+		if err := rows.Scan(&ai.City, &ai.Country, &ai.IATA, &ai.Lat, &ai.Lon, &ai.Timezone); err != nil {
 			return nil, err
 		}
 		airports = append(airports, ai)
@@ -51,6 +57,53 @@ AND c.include_tf = 1;
 	return airports, nil
 }
 
+// fetchOriginAirports retrieves the airports flights depart from, so the website can compare
+// a destination's weather with home. Origins aren't necessarily included destinations.
+func fetchOriginAirports(locationsDB, flightsDB *sql.DB) ([]AirportInfo, error) {
+	rows, err := flightsDB.Query(`SELECT DISTINCT departureAirport FROM schedule WHERE departureAirport IS NOT NULL AND departureAirport != ''`)
+	if err != nil {
+		return nil, err
+	}
+	var iatas []interface{}
+	for rows.Next() {
+		var iata string
+		if err := rows.Scan(&iata); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		iatas = append(iatas, iata)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(iatas) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(iatas)), ", ")
+	rows, err = locationsDB.Query(`SELECT city, country, iata, lat, lon, tz
+FROM airport
+WHERE city IS NOT NULL
+AND country IS NOT NULL
+AND iata IN (`+placeholders+`)`, iatas...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var airports []AirportInfo
+	for rows.Next() {
+		var ai AirportInfo
+		if err := rows.Scan(&ai.City, &ai.Country, &ai.IATA, &ai.Lat, &ai.Lon, &ai.Timezone); err != nil {
+			return nil, err
+		}
+		airports = append(airports, ai)
+	}
+
+	return airports, rows.Err()
+}
+
 // initWeatherDB creates the weather database and table if it doesn't exist
 func initWeatherDB(dbPath string) {
 	db, err := sql.Open("sqlite3", dbPath)
@@ -70,10 +123,54 @@ func initWeatherDB(dbPath string) {
 		weather_icon_url TEXT NOT NULL,
 		google_weather_link TEXT NOT NULL,
     wind_speed REAL NOT NULL,
-    wpi FLOAT(10,1)
+    wpi FLOAT(10,1),
+    fetched_at TEXT,
+    provider TEXT,
+    precipitation_probability REAL,
+    cloud_cover REAL,
+    humidity REAL,
+    timezone TEXT
 	);`
 
 	if _, err := db.Exec(createTableSQL); err != nil {
 		log.Fatalf("Error creating Weather table: %v", err)
 	}
+
+	// Databases created before staleness tracking don't have fetched_at yet
+	if err := freshness.EnsureFetchedAtColumn(db, "all_weather"); err != nil {
+		log.Fatalf("Error migrating Weather table: %v", err)
+	}
+	for column, columnType := range map[string]string{
+		"provider":                  "TEXT",
+		"precipitation_probability": "REAL",
+		"cloud_cover":               "REAL",
+		"humidity":                  "REAL",
+		// Rows without a timezone predate UTC dates and are in the fetching server's local time
+		"timezone":                  "TEXT",
+	} {
+		if err := freshness.EnsureColumn(db, "all_weather", column, columnType); err != nil {
+			log.Fatalf("Error migrating Weather table: %v", err)
+		}
+	}
+}
+
+// fetchLastFetched returns the most recent fetched_at of every airport in all_weather
+func fetchLastFetched(db *sql.DB) (map[string]sql.NullString, error) {
+	rows, err := db.Query(`SELECT iata, MAX(fetched_at) FROM all_weather GROUP BY iata`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lastFetched := make(map[string]sql.NullString)
+	for rows.Next() {
+		var iata string
+		var fetchedAt sql.NullString
+		if err := rows.Scan(&iata, &fetchedAt); err != nil {
+			return nil, err
+		}
+		lastFetched[iata] = fetchedAt
+	}
+
+	return lastFetched, rows.Err()
 }
@@ -2,12 +2,15 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/Tris20/FairFareFinder/config/handlers"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/freshness"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/pool"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/schollz/progressbar/v3"
 )
 
 
@@ -21,9 +24,11 @@ type WeatherDataBatch struct {
 
 
 func main() {
+	// The daemon refreshes weather every 6 hours; 5h keeps every airport inside that cycle
+	policy := freshness.RegisterFlags(5 * time.Hour)
+	workers := pool.RegisterFlags(4)
+	flag.Parse()
 
-	 var batch []WeatherDataBatch
-    batchSize := 50
 	flightsDB, err := sql.Open("sqlite3", "../../../../data/raw/locations/locations.db")
   
 if err != nil {
@@ -45,54 +50,80 @@ if err != nil {
 
     // Fetch airport info with non-empty IATA codes
 
-	airports, err := fetchAirports(flightsDB)
+	allAirports, err := fetchAirports(flightsDB)
 	if err != nil {
 		log.Fatalf("Error fetching airports: %v", err)
 	}
 
-	// Start of the rate limiting period
-	//startTime := time.Now()
-
-	// The maximum number of requests we can make per minute
-	const maxRequestsPerMinute = 50
-	// Calculate the interval at which we can make requests to not exceed the limit
-	requestInterval := time.Minute / maxRequestsPerMinute
-
-	// Create a new progress bar
-	bar := progressbar.Default(int64(len(airports)))
-
-   for _, airport := range airports {
-        bar.Add(1)
-        fmt.Printf("\ncity: %s  country: %s\n", airport.City, airport.Country)
-        weatherInfo, err := fetchWeatherForCity(airport.City, airport.Country)
-        if err != nil {
-            log.Printf("Error fetching weather for %s: %v", airport.City, err)
-            continue
-        }
-
-        // Add to batch
-        batch = append(batch, WeatherDataBatch{
-            Airport:     airport,
-            WeatherInfo: weatherInfo,
-        })
-
-        if len(batch) >= batchSize {
-            fmt.Println("Storing results...")
-            if err := storeWeatherDataBatch(db, batch); err != nil {
-                log.Printf("Error storing weather data for batch: %v", err)
-            }
-            batch = batch[:0] // Reset the batch
-            fmt.Println("Batch stored")
-        }
-
-        // Rate-limiting logic (adjust as needed)
-        time.Sleep(requestInterval)
-    }
+	// Origin cities need weather too, to compare destinations with home
+	scheduleDB, err := sql.Open("sqlite3", "../../../../data/raw/flights/flights.db")
+	if err != nil {
+		log.Fatalf("Error opening flights.db: %v", err)
+	}
+	defer scheduleDB.Close()
+	originAirports, err := fetchOriginAirports(flightsDB, scheduleDB)
+	if err != nil {
+		log.Printf("Error fetching origin airports, fetching destinations only: %v", err)
+	}
+	allAirports = append(allAirports, originAirports...)
 
-    // Insert any remaining batch
-    if len(batch) > 0 {
-        if err := storeWeatherDataBatch(db, batch); err != nil {
-            log.Printf("Error storing weather data for final batch: %v", err)
-        }
-    }
+	// Only refetch airports whose forecast is older than the freshness policy
+	lastFetched, err := fetchLastFetched(db)
+	if err != nil {
+		log.Fatalf("Error reading fetched_at from weather.db: %v", err)
+	}
+	airportsByIATA := make(map[string]AirportInfo)
+	var items []freshness.Item
+	for _, airport := range allAirports {
+		if _, seen := airportsByIATA[airport.IATA]; seen {
+			continue
+		}
+		airportsByIATA[airport.IATA] = airport
+		items = append(items, freshness.Item{Key: airport.IATA, FetchedAt: lastFetched[airport.IATA], Calls: 1})
+	}
+	plan := policy.BuildPlan(items)
+
+	if policy.DryRun {
+		freshness.PrintPlan("Weather", plan)
+		return
+	}
+	fmt.Printf("Fetching weather for %d airports, %d still fresh\n", len(plan.Fetch), len(plan.Skipped))
+
+	var iatas []string
+	for _, item := range plan.Fetch {
+		iatas = append(iatas, item.Key)
+	}
+
+	providersConfig, err := config_handlers.LoadWeatherProvidersConfig("../../../../config/config.yaml")
+	if err != nil {
+		log.Fatalf("Error loading weather providers from config.yaml: %v", err)
+	}
+
+	// OpenWeatherMap's free tier allows 60 calls per minute; stay below it.
+	// Open-Meteo allows 600 per minute for non-commercial use.
+	limits := pool.NewHostLimits(map[string]int{openWeatherMapHost: 50, openMeteoHost: 500})
+	chain, err := newProviderChain(providersConfig, limits)
+	if err != nil {
+		log.Fatalf("Error configuring weather providers: %v", err)
+	}
+
+	fetch := func(iata string) (WeatherDataBatch, error) {
+		airport := airportsByIATA[iata]
+		weatherInfo, err := chain.Forecast(airport)
+		if err != nil {
+			return WeatherDataBatch{}, fmt.Errorf("%s, %s: %v", airport.City, airport.Country, err)
+		}
+		return WeatherDataBatch{Airport: airport, WeatherInfo: weatherInfo}, nil
+	}
+
+	store := func(results []pool.Result[WeatherDataBatch]) error {
+		batch := make([]WeatherDataBatch, 0, len(results))
+		for _, r := range results {
+			batch = append(batch, r.Value)
+		}
+		return storeWeatherDataBatch(db, batch)
+	}
+
+	summary := pool.Run(pool.Pool{Workers: *workers, BatchSize: 50, Description: "Weather"}, iatas, fetch, store)
+	summary.Print()
 }
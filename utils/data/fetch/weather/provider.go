@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+	"github.com/Tris20/FairFareFinder/utils/data/fetch/pool"
+)
+
+// WeatherProvider returns normalised forecast records for an airport.
+// Every provider maps its own condition codes onto the OpenWeatherMap "main"
+// names (Clear, Clouds, Rain, ...) so weatherPleasantness.yaml applies to all of them.
+type WeatherProvider interface {
+	Name() string
+	Host() string
+	Forecast(airport AirportInfo) ([]WeatherData, error)
+}
+
+// newWeatherProvider creates a provider from its name in config.yaml
+func newWeatherProvider(name string, config config_handlers.WeatherProvidersConfig) (WeatherProvider, error) {
+	switch strings.ToLower(name) {
+	case "openweathermap":
+		return &openWeatherMap{}, nil
+	case "open-meteo":
+		return &openMeteo{}, nil
+	case "fixture":
+		return &fixtureProvider{dir: config.Weather.FixturesDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider %q", name)
+	}
+}
+
+// ProviderChain tries each provider in order and returns the first forecast that succeeds
+type ProviderChain struct {
+	providers []WeatherProvider
+	limits    *pool.HostLimits
+}
+
+// newProviderChain builds the fallback chain configured in config.yaml
+func newProviderChain(config config_handlers.WeatherProvidersConfig, limits *pool.HostLimits) (*ProviderChain, error) {
+	names := config.Weather.Providers
+	if len(names) == 0 {
+		names = []string{"openweathermap"}
+	}
+
+	chain := &ProviderChain{limits: limits}
+	for _, name := range names {
+		provider, err := newWeatherProvider(name, config)
+		if err != nil {
+			return nil, err
+		}
+		chain.providers = append(chain.providers, provider)
+	}
+	return chain, nil
+}
+
+// Forecast returns the forecast from the first provider that answers, tagged with that provider's name
+func (c *ProviderChain) Forecast(airport AirportInfo) ([]WeatherData, error) {
+	var errs []string
+	for _, provider := range c.providers {
+		c.limits.Wait(provider.Host())
+		records, err := provider.Forecast(airport)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+		for i := range records {
+			records[i].Provider = provider.Name()
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("all weather providers failed: %s", strings.Join(errs, "; "))
+}
+
+// googleWeatherLink links a forecast card to Google's weather panel for the city
+func googleWeatherLink(cityName, countryCode string) string {
+	return fmt.Sprintf("https://www.google.com/search?q=weather+%s", locationString(cityName, countryCode))
+}
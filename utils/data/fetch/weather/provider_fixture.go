@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// fixtureProvider serves forecasts from JSON files named <IATA>.json, so the
+// weather pipeline can be run offline or without spending API calls.
+// Each file holds a JSON array of WeatherData records, dated in UTC.
+type fixtureProvider struct {
+	dir string
+}
+
+func (f *fixtureProvider) Name() string { return "fixture" }
+
+// Host is empty because fixtures are never rate limited
+func (f *fixtureProvider) Host() string { return "" }
+
+func (f *fixtureProvider) Forecast(airport AirportInfo) ([]WeatherData, error) {
+	dir := f.dir
+	if dir == "" {
+		dir = "fixtures"
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, airport.IATA+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("no fixture for %s: %v", airport.IATA, err)
+	}
+
+	var records []WeatherData
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid fixture for %s: %v", airport.IATA, err)
+	}
+	return records, nil
+}
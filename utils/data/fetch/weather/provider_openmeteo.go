@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const openMeteoHost = "api.open-meteo.com"
+
+// OpenMeteoResponse is the part of Open-Meteo's hourly forecast we use
+type OpenMeteoResponse struct {
+	Hourly struct {
+		Time                     []string  `json:"time"`
+		Temperature              []float64 `json:"temperature_2m"`
+		WindSpeed                []float64 `json:"wind_speed_10m"`
+		WeatherCode              []int     `json:"weather_code"`
+		PrecipitationProbability []float64 `json:"precipitation_probability"`
+		CloudCover               []float64 `json:"cloud_cover"`
+		Humidity                 []float64 `json:"relative_humidity_2m"`
+		IsDay                    []int     `json:"is_day"`
+	} `json:"hourly"`
+}
+
+// openMeteo reads Open-Meteo's free hourly forecast, which needs no API key but does need coordinates
+type openMeteo struct{}
+
+func (o *openMeteo) Name() string { return "open-meteo" }
+
+func (o *openMeteo) Host() string { return openMeteoHost }
+
+func (o *openMeteo) Forecast(airport AirportInfo) ([]WeatherData, error) {
+	if !airport.Lat.Valid || !airport.Lon.Valid {
+		return nil, fmt.Errorf("no coordinates for %s", airport.IATA)
+	}
+
+	apiURL := fmt.Sprintf("https://%s/v1/forecast?latitude=%.4f&longitude=%.4f&hourly=temperature_2m,wind_speed_10m,weather_code,precipitation_probability,cloud_cover,relative_humidity_2m,is_day&wind_speed_unit=ms&timezone=GMT&forecast_days=5",
+		openMeteoHost, airport.Lat.Float64, airport.Lon.Float64)
+
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("received non-200 status code from Open-Meteo: %d, response: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResp OpenMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, err
+	}
+
+	hourly := apiResp.Hourly
+	var result []WeatherData
+	for i, ts := range hourly.Time {
+		t, err := time.ParseInLocation("2006-01-02T15:04", ts, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected time %q: %v", ts, err)
+		}
+		if i >= len(hourly.Temperature) || i >= len(hourly.WindSpeed) || i >= len(hourly.WeatherCode) {
+			break
+		}
+
+		isDay := i < len(hourly.IsDay) && hourly.IsDay[i] == 1
+		condition, icon := wmoCondition(hourly.WeatherCode[i], isDay)
+
+		record := WeatherData{
+			Date:              t.Format("2006-01-02 15:04:05"),
+			WeatherType:       condition,
+			Temperature:       hourly.Temperature[i],
+			WeatherIconURL:    fmt.Sprintf("https://openweathermap.org/img/wn/%s.png", icon),
+			GoogleWeatherLink: googleWeatherLink(airport.City, airport.Country),
+			WindSpeed:         hourly.WindSpeed[i],
+		}
+		if i < len(hourly.PrecipitationProbability) {
+			record.PrecipitationProbability = hourly.PrecipitationProbability[i]
+		}
+		if i < len(hourly.CloudCover) {
+			record.CloudCover = hourly.CloudCover[i]
+		}
+		if i < len(hourly.Humidity) {
+			record.Humidity = hourly.Humidity[i]
+		}
+		result = append(result, record)
+	}
+
+	return result, nil
+}
+
+// wmoCondition maps a WMO weather code to an OpenWeatherMap condition name and icon code
+func wmoCondition(code int, isDay bool) (string, string) {
+	suffix := "n"
+	if isDay {
+		suffix = "d"
+	}
+
+	switch {
+	case code == 0:
+		return "Clear", "01" + suffix
+	case code == 1 || code == 2:
+		return "Clouds", "02" + suffix
+	case code == 3:
+		return "Clouds", "04" + suffix
+	case code == 45 || code == 48:
+		return "Fog", "50" + suffix
+	case code >= 51 && code <= 57:
+		return "Drizzle", "09" + suffix
+	case code >= 61 && code <= 67:
+		return "Rain", "10" + suffix
+	case code >= 71 && code <= 77, code == 85, code == 86:
+		return "Snow", "13" + suffix
+	case code >= 80 && code <= 82:
+		return "Rain", "09" + suffix
+	case code >= 95:
+		return "Thunderstorm", "11" + suffix
+	default:
+		return "Clouds", "03" + suffix
+	}
+}
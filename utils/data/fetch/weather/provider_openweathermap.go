@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+)
+
+const openWeatherMapHost = "api.openweathermap.org"
+
+// Assuming a part of the JSON response structure from OpenWeatherMap API for simplification
+type ApiResponse struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			Temp     float64 `json:"temp"`
+			Humidity float64 `json:"humidity"`
+		} `json:"main"`
+		Weather []struct {
+			Main string `json:"main"`
+			Icon string `json:"icon"`
+		} `json:"weather"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Clouds struct {
+			All float64 `json:"all"`
+		} `json:"clouds"`
+		Pop float64 `json:"pop"` // Probability of precipitation, 0 to 1
+	} `json:"list"`
+}
+
+// openWeatherMap reads OpenWeatherMap's 5 day / 3 hour forecast endpoint
+type openWeatherMap struct{}
+
+func (o *openWeatherMap) Name() string { return "openweathermap" }
+
+func (o *openWeatherMap) Host() string { return openWeatherMapHost }
+
+func (o *openWeatherMap) Forecast(airport AirportInfo) ([]WeatherData, error) {
+	return fetchWeatherForCity(airport.City, airport.Country)
+}
+
+func locationString(cityName string, countryCode string) string {
+	return url.QueryEscape(fmt.Sprintf("%s, %s", cityName, countryCode))
+}
+
+// fetchWeatherForCity fetches weather data for the specified city from OpenWeatherAPI
+func fetchWeatherForCity(cityName string, countryCode string) ([]WeatherData, error) {
+	location_string := locationString(cityName, countryCode)
+	apiKey, err := config_handlers.LoadApiKey("../../../../ignore/secrets.yaml", "openweathermap.org")
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("https://%s/data/2.5/forecast?q=%s&appid=%s&units=metric", openWeatherMapHost, location_string, apiKey)
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		bodyString := string(bodyBytes)
+		return nil, fmt.Errorf("received non-200 status code from weather API: %d, response: %s", resp.StatusCode, bodyString)
+	}
+
+	var apiResp ApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, err
+	}
+	var result []WeatherData
+
+	for _, item := range apiResp.List {
+		// This example extracts weather data for each time entry in the list.
+		// You might want to adjust this to extract daily averages or specific times of day.
+		date := time.Unix(item.Dt, 0).UTC().Format("2006-01-02 15:04:05")
+		weatherType := "Clear" // Default to clear, adjust based on actual data
+		iconURL := ""
+		if len(item.Weather) > 0 {
+			weatherType = item.Weather[0].Main
+			iconURL = fmt.Sprintf("https://openweathermap.org/img/wn/%s.png", item.Weather[0].Icon)
+		}
+
+		result = append(result, WeatherData{
+			Date:                     date,
+			WeatherType:              weatherType,
+			Temperature:              item.Main.Temp,
+			WeatherIconURL:           iconURL,
+			GoogleWeatherLink:        googleWeatherLink(cityName, countryCode),
+			WindSpeed:                item.Wind.Speed,
+			PrecipitationProbability: item.Pop * 100,
+			Humidity:                 item.Main.Humidity,
+			CloudCover:               item.Clouds.All,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/schollz/progressbar/v3"
+	"log"
+	"math"
+	"time"
+	// Destination zones must resolve even on servers without system zoneinfo
+	_ "time/tzdata"
+)
+
+// Location is where a city's sun is calculated, the average of its airports
+type Location struct {
+	Lat      float64
+	Lon      float64
+	Timezone string
+}
+
+// Daylight of one day. Sunrise and Sunset are local "15:04" times, NULL during
+// polar day or night when the sun doesn't rise or set.
+type Daylight struct {
+	Sunrise sql.NullString
+	Sunset  sql.NullString
+	Hours   float64
+}
+
+// The sun's centre is 0.833° below the horizon at sunrise and sunset,
+// allowing for refraction and the radius of the sun's disc
+const sunriseAltitude = -0.833
+
+// J2000 epoch as a Julian day, and the Julian day of the Unix epoch
+const (
+	j2000     = 2451545.0
+	unixEpoch = 2440587.5
+)
+
+func degreesToRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radiansToDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func julianToTime(jd float64) time.Time {
+	return time.Unix(0, int64((jd-unixEpoch)*86400*float64(time.Second))).UTC()
+}
+
+// calculateDaylight works out sunrise, sunset and day length with the sunrise equation,
+// accurate to a minute or two outside the polar regions.
+// date is the local calendar day; lon is east of Greenwich.
+func calculateDaylight(date time.Time, lat, lon float64, loc *time.Location) Daylight {
+	// Days since J2000 at noon UTC of the date, then shifted to the local mean solar noon
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+	n := math.Round(float64(noon.Unix())/86400+unixEpoch-j2000+0.0008) - 0.0008
+	meanNoon := n - lon/360
+
+	// Sun's position along the ecliptic
+	meanAnomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	m := degreesToRadians(meanAnomaly)
+	centre := 1.9148*math.Sin(m) + 0.02*math.Sin(2*m) + 0.0003*math.Sin(3*m)
+	eclipticLongitude := degreesToRadians(math.Mod(meanAnomaly+centre+180+102.9372, 360))
+
+	transit := j2000 + meanNoon + 0.0053*math.Sin(m) - 0.0069*math.Sin(2*eclipticLongitude)
+	declination := math.Asin(math.Sin(eclipticLongitude) * math.Sin(degreesToRadians(23.4397)))
+
+	phi := degreesToRadians(lat)
+	cosHourAngle := (math.Sin(degreesToRadians(sunriseAltitude)) - math.Sin(phi)*math.Sin(declination)) /
+		(math.Cos(phi) * math.Cos(declination))
+	switch {
+	case cosHourAngle >= 1:
+		return Daylight{Hours: 0} // Polar night
+	case cosHourAngle <= -1:
+		return Daylight{Hours: 24} // Midnight sun
+	}
+
+	hourAngle := radiansToDegrees(math.Acos(cosHourAngle))
+	sunrise := julianToTime(transit - hourAngle/360)
+	sunset := julianToTime(transit + hourAngle/360)
+	return Daylight{
+		Sunrise: sql.NullString{String: sunrise.In(loc).Format("15:04"), Valid: true},
+		Sunset:  sql.NullString{String: sunset.In(loc).Format("15:04"), Valid: true},
+		Hours:   math.Round(2*hourAngle/15*10) / 10, // The sun moves 15° an hour, for hourAngle either side of noon
+	}
+}
+
+// fetchLocations reads the coordinates and time zone of every city with airports.
+// Cities are named as in the airport table, the same names the weather table uses.
+func fetchLocations(db *sql.DB) (map[[2]string]Location, error) {
+	rows, err := db.Query(`
+	SELECT city, country, AVG(lat), AVG(lon), COALESCE(MAX(tz), 'UTC')
+	FROM airport
+	WHERE city IS NOT NULL AND country IS NOT NULL
+	AND lat IS NOT NULL AND lon IS NOT NULL
+	GROUP BY city, country`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := make(map[[2]string]Location)
+	for rows.Next() {
+		var city, country string
+		var l Location
+		if err := rows.Scan(&city, &country, &l.Lat, &l.Lon, &l.Timezone); err != nil {
+			return nil, err
+		}
+		locations[[2]string{city, country}] = l
+	}
+	return locations, rows.Err()
+}
+
+func main() {
+	locationsDB, err := sql.Open("sqlite3", "../../../../../data/raw/locations/locations.db")
+	if err != nil {
+		log.Fatalf("Failed to open locations database: %v", err)
+	}
+	defer locationsDB.Close()
+
+	locations, err := fetchLocations(locationsDB)
+	if err != nil {
+		log.Fatalf("Failed to read airport coordinates: %v", err)
+	}
+
+	mainDB, err := sql.Open("sqlite3", "../../../../../data/compiled/new_main.db")
+	if err != nil {
+		log.Fatalf("Failed to open main database: %v", err)
+	}
+	defer mainDB.Close()
+
+	rows, err := mainDB.Query(`SELECT DISTINCT city, country, date FROM weather`)
+	if err != nil {
+		log.Fatalf("Failed to query weather table: %v", err)
+	}
+	type day struct{ city, country, date string }
+	var days []day
+	for rows.Next() {
+		var d day
+		if err := rows.Scan(&d.city, &d.country, &d.date); err != nil {
+			log.Fatalf("Failed to scan row: %v", err)
+		}
+		days = append(days, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Error iterating through rows: %v", err)
+	}
+
+	tx, err := mainDB.Begin()
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE weather SET sunrise = ?, sunset = ?, daylight_hours = ? WHERE city = ? AND country = ? AND date = ?`)
+	if err != nil {
+		log.Fatalf("Failed to prepare update: %v", err)
+	}
+	defer stmt.Close()
+
+	zones := make(map[string]*time.Location)
+	bar := progressbar.Default(int64(len(days)), "Calculating daylight")
+	var skipped int
+	for _, d := range days {
+		bar.Add(1)
+		l, ok := locations[[2]string{d.city, d.country}]
+		if !ok {
+			skipped++
+			continue
+		}
+		date, err := time.Parse("2006-01-02", d.date)
+		if err != nil {
+			log.Printf("Skipping %s, %s: unexpected date %q", d.city, d.country, d.date)
+			skipped++
+			continue
+		}
+		zone, ok := zones[l.Timezone]
+		if !ok {
+			zone, err = time.LoadLocation(l.Timezone)
+			if err != nil {
+				log.Printf("Unknown time zone %q, using UTC: %v", l.Timezone, err)
+				zone = time.UTC
+			}
+			zones[l.Timezone] = zone
+		}
+
+		daylight := calculateDaylight(date, l.Lat, l.Lon, zone)
+		if _, err := stmt.Exec(daylight.Sunrise, daylight.Sunset, daylight.Hours, d.city, d.country, d.date); err != nil {
+			log.Fatalf("Failed to update daylight for %s, %s on %s: %v", d.city, d.country, d.date, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Failed to commit transaction: %v", err)
+	}
+	fmt.Printf("Calculated daylight for %d days, skipped %d without coordinates\n", len(days)-skipped, skipped)
+}
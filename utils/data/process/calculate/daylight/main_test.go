@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCalculateDaylight(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tromso, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sunrise and sunset as published for Berlin, which the equation should match to a couple of minutes
+	tests := []struct {
+		name     string
+		date     string
+		lat, lon float64
+		loc      *time.Location
+		hours    float64
+		sunrise  string
+		sunset   string
+	}{
+		{"mid latitude, summer solstice", "2026-06-21", 52.52, 13.405, berlin, 16.8, "04:43", "21:33"},
+		{"mid latitude, winter solstice", "2026-12-21", 52.52, 13.405, berlin, 7.6, "08:15", "15:54"},
+		{"mid latitude, equinox", "2026-03-20", 52.52, 13.405, berlin, 12.1, "06:09", "18:19"},
+		{"equator, equinox", "2026-03-20", 0, 0, time.UTC, 12.1, "", ""},
+		{"southern winter solstice", "2026-06-21", -33.87, 151.21, time.UTC, 9.9, "", ""},
+		{"midnight sun", "2026-06-21", 69.65, 18.96, tromso, 24, "", ""},
+		{"polar night", "2026-12-21", 69.65, 18.96, tromso, 0, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := time.Parse("2006-01-02", tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := calculateDaylight(date, tt.lat, tt.lon, tt.loc)
+
+			if math.Abs(got.Hours-tt.hours) > 0.1 {
+				t.Errorf("got %.1f hours of daylight, want %.1f", got.Hours, tt.hours)
+			}
+			if tt.hours == 0 || tt.hours == 24 {
+				if got.Sunrise.Valid || got.Sunset.Valid {
+					t.Errorf("got sunrise %q and sunset %q, want none when the sun doesn't rise or set", got.Sunrise.String, got.Sunset.String)
+				}
+				return
+			}
+			if tt.sunrise == "" {
+				return
+			}
+			for _, c := range []struct{ name, got, want string }{
+				{"sunrise", got.Sunrise.String, tt.sunrise},
+				{"sunset", got.Sunset.String, tt.sunset},
+			} {
+				gotTime, err := time.Parse("15:04", c.got)
+				if err != nil {
+					t.Fatalf("%s %q: %v", c.name, c.got, err)
+				}
+				wantTime, _ := time.Parse("15:04", c.want)
+				if diff := gotTime.Sub(wantTime); diff < -2*time.Minute || diff > 2*time.Minute {
+					t.Errorf("got %s %s, want %s", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"sort"
 
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/schollz/progressbar/v3"
 )
@@ -23,6 +24,16 @@ func median(values []float64) float64 {
 	return values[n/2]
 }
 
+// tiers are the accommodation prices a trip is priced with, each to its own price_fnaf column
+var tiers = []struct {
+	pppnColumn, fnafColumn string
+}{
+	{"booking_pppn", "price_fnaf"},
+	{"booking_pppn_budget", "price_fnaf_budget"},
+	{"booking_pppn_mid_range", "price_fnaf_mid_range"},
+	{"booking_pppn_premium", "price_fnaf_premium"},
+}
+
 func main() {
 	// Step 1: Open the database
 	db, err := sql.Open("sqlite3", "../../../../../../data/compiled/new_main.db")
@@ -38,12 +49,23 @@ func main() {
 		origin_country TEXT,
 		destination_city TEXT,
 		destination_country TEXT,
-		price_fnaf REAL
+		price_fnaf REAL,
+		price_fnaf_budget REAL,
+		price_fnaf_mid_range REAL,
+		price_fnaf_premium REAL
 	);`
 	_, err = db.Exec(createTableQuery)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// Tables created before the tiers have only price_fnaf
+	var tierColumns []dbschema.Column
+	for _, tier := range tiers[1:] {
+		tierColumns = append(tierColumns, dbschema.Column{Name: tier.fnafColumn, Definition: "REAL"})
+	}
+	if err := dbschema.EnsureColumns(db, "five_nights_and_flights", tierColumns); err != nil {
+		log.Fatal(err)
+	}
 	fmt.Println("Table 'five_nights_and_flights' ensured to exist or created successfully.")
 
 	// Step 3: Count the total rows to know the progress length
@@ -73,6 +95,13 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// The table is derived entirely from flight and accommodation, so rebuild it rather than
+	// adding to rows that may still hold totals of flights once stored with a price of 0
+	_, err = tx.Exec(`DELETE FROM five_nights_and_flights`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Step 6: Prepare the progress bar
 	bar := progressbar.NewOptions(rowCount,
 		progressbar.OptionSetDescription("Processing rows..."),
@@ -84,8 +113,8 @@ func main() {
 
 	// Step 7: Prepare the insert statement for five_nights_and_flights table
 	insertQuery := `
-	INSERT INTO five_nights_and_flights (origin_city, origin_country, destination_city, destination_country, price_fnaf)
-	VALUES (?, ?, ?, ?, ?)
+	INSERT INTO five_nights_and_flights (origin_city, origin_country, destination_city, destination_country, price_fnaf, price_fnaf_budget, price_fnaf_mid_range, price_fnaf_premium)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	stmt, err := tx.Prepare(insertQuery)
 	if err != nil {
@@ -94,9 +123,10 @@ func main() {
 	defer stmt.Close()
 
 	// Step 8: Iterate over the rows from the "flight" table
+	destinationPPPNs := make(map[string][]sql.NullFloat64)
 	for rows.Next() {
 		var originCity, originCountry, destCity, destCountry string
-		var flightPrice float64
+		var flightPrice sql.NullFloat64
 
 		// Scan the flight data
 		err := rows.Scan(&originCity, &originCountry, &destCity, &destCountry, &flightPrice)
@@ -104,32 +134,32 @@ func main() {
 			log.Fatal(err)
 		}
 
-		// Step 9: Get booking_pppn from the "accommodation" table for the same destination
-		var bookingPPPN float64
-		err = db.QueryRow(`
-			SELECT booking_pppn
-			FROM accommodation
-			WHERE city = ? AND country = ?
-		`, destCity, destCountry).Scan(&bookingPPPN)
-
-		if err != nil {
-			// If there's no matching entry in the accommodation table, calculate the median for the country
-			if err == sql.ErrNoRows {
-				bookingPPPN, err = getMedianBookingPPPNForCountry(db, destCountry)
-				if err != nil {
-					log.Fatal(err)
-				}
-				fmt.Printf("Using median booking_pppn for country: %s\n", destCountry)
-			} else {
+		// Step 9: Get the accommodation prices of each tier for the same destination.
+		// Destinations recur for every origin, so they are looked up once.
+		destKey := destCity + "," + destCountry
+		pppns, ok := destinationPPPNs[destKey]
+		if !ok {
+			pppns, err = getTierPPPNs(db, destCity, destCountry)
+			if err != nil {
 				log.Fatal(err)
 			}
+			destinationPPPNs[destKey] = pppns
 		}
 
-		// Step 10: Calculate the final price (flight + 5 nights of accommodation)
-		totalPriceFNAF := flightPrice + (bookingPPPN * 5)
+		// Step 10: Calculate the final price (flight + 5 nights of accommodation) for each tier.
+		// Without a flight price, or a price for the tier, there is no trip price either, so it stays NULL.
+		totalPricesFNAF := make([]interface{}, len(tiers))
+		for i, pppn := range pppns {
+			var totalPriceFNAF sql.NullFloat64
+			if flightPrice.Valid && pppn.Valid {
+				totalPriceFNAF = sql.NullFloat64{Float64: flightPrice.Float64 + (pppn.Float64 * 5), Valid: true}
+			}
+			totalPricesFNAF[i] = totalPriceFNAF
+		}
 
 		// Step 11: Insert the result into "five_nights_and_flights" table
-		_, err = stmt.Exec(originCity, originCountry, destCity, destCountry, totalPriceFNAF)
+		args := append([]interface{}{originCity, originCountry, destCity, destCountry}, totalPricesFNAF...)
+		_, err = stmt.Exec(args...)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -147,15 +177,51 @@ func main() {
 	fmt.Println("\nData inserted into 'five_nights_and_flights' table successfully.")
 }
 
-// Step 9 helper function to calculate the median booking_pppn for a given country
-func getMedianBookingPPPNForCountry(db *sql.DB, country string) (float64, error) {
-	rows, err := db.Query(`
-		SELECT booking_pppn
+// Step 9 helper function to get a destination's booking_pppn of each tier. A destination
+// without a price for a tier takes the median of its country's. Failing that, booking_pppn
+// defaults to 40 and the other tiers are NULL, rather than passing booking_pppn off as them.
+func getTierPPPNs(db *sql.DB, city, country string) ([]sql.NullFloat64, error) {
+	pppns := make([]sql.NullFloat64, len(tiers))
+	for i, tier := range tiers {
+		var pppn sql.NullFloat64
+		err := db.QueryRow(fmt.Sprintf(`
+			SELECT %s
+			FROM accommodation
+			WHERE city = ? AND country = ?
+		`, tier.pppnColumn), city, country).Scan(&pppn)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if pppn.Valid {
+			pppns[i] = pppn
+			continue
+		}
+
+		countryPPPN, found, err := getMedianPPPNForCountry(db, tier.pppnColumn, country)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case found:
+			pppns[i] = sql.NullFloat64{Float64: countryPPPN, Valid: true}
+			fmt.Printf("Using median %s for country: %s\n", tier.pppnColumn, country)
+		case i == 0:
+			fmt.Printf("No booking_pppn values found for country %s, using default value of 40\n", country)
+			pppns[i] = sql.NullFloat64{Float64: 40, Valid: true}
+		}
+	}
+	return pppns, nil
+}
+
+// getMedianPPPNForCountry calculates the median of an accommodation price column for a given country
+func getMedianPPPNForCountry(db *sql.DB, column, country string) (float64, bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT %s
 		FROM accommodation
-		WHERE country = ?
-	`, country)
+		WHERE country = ? AND %s IS NOT NULL
+	`, column, column), country)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	defer rows.Close()
 
@@ -163,17 +229,18 @@ func getMedianBookingPPPNForCountry(db *sql.DB, country string) (float64, error)
 	for rows.Next() {
 		var ppnn float64
 		if err := rows.Scan(&ppnn); err != nil {
-			return 0, err
+			return 0, false, err
 		}
 		ppnns = append(ppnns, ppnn)
 	}
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
 
-	// If no values are found, use the default value of 40
 	if len(ppnns) == 0 {
-		fmt.Printf("No booking_pppn values found for country %s, using default value of 40\n", country)
-		return 40, nil
+		return 0, false, nil
 	}
 
 	// Calculate the median
-	return median(ppnns), nil
+	return median(ppnns), true, nil
 }
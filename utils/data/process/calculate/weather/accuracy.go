@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// all_weather keeps every forecast we fetched. Once a timestamp has passed, the
+// forecast issued closest before it stands in for the observed weather, and the
+// forecasts issued earlier are scored against it.
+const (
+	// A forecast issued at most this long before its timestamp counts as observed
+	observedMaxLeadHours = 12
+	// Fewer comparisons than this are too noisy to call a city reliable or not
+	minAccuracySamples = 20
+	// A temperature error this large, in °C, scores no confidence at all
+	maxTemperatureError = 5.0
+)
+
+type ForecastAccuracy struct {
+	City              string
+	Country           string
+	LeadDays          int
+	Samples           int
+	TemperatureMAE    float64
+	ConditionAccuracy float64
+}
+
+// Confidence combines the temperature and condition accuracy into 0 to 1.
+// It is NULL when there are too few samples to say.
+func (a ForecastAccuracy) Confidence() sql.NullFloat64 {
+	if a.Samples < minAccuracySamples {
+		return sql.NullFloat64{}
+	}
+	temperature := clamp(1-a.TemperatureMAE/maxTemperatureError, 0, 1)
+	return sql.NullFloat64{Float64: 0.5*temperature + 0.5*a.ConditionAccuracy, Valid: true}
+}
+
+// forecastComparisonsSQL pairs every past forecast with the observed value for the same airport and time.
+// Dates without a timezone are in the server's local time, as in processDatabaseEntries.
+const forecastComparisonsSQL = `
+	WITH readings AS (
+		SELECT iata, city_name, country_code, temperature, weather_type, date_utc,
+		       (julianday(date_utc) - julianday(fetched_at)) * 24 AS lead_hours
+		FROM (
+			SELECT *, CASE WHEN timezone IS NULL THEN datetime(date, 'utc') ELSE datetime(date) END AS date_utc
+			FROM all_weather
+			WHERE fetched_at IS NOT NULL AND city_name != ''
+		)
+		WHERE date_utc <= datetime('now') AND date_utc >= fetched_at
+	),
+	observed AS (
+		SELECT iata, date_utc, temperature, weather_type
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY iata, date_utc ORDER BY lead_hours) AS latest
+			FROM readings
+		)
+		WHERE latest = 1 AND lead_hours <= ?
+	)
+	SELECT r.city_name, r.country_code, CAST(r.lead_hours / 24 AS INTEGER) AS lead_days,
+	       ABS(r.temperature - o.temperature) AS temperature_error, r.weather_type = o.weather_type AS condition_hit
+	FROM readings r
+	JOIN observed o ON o.iata = r.iata AND o.date_utc = r.date_utc
+	WHERE r.lead_hours > ?
+`
+
+// processForecastAccuracy rebuilds forecast_accuracy from the forecast history in all_weather.
+// Rows with an empty city_name and country_code hold the accuracy over all cities, for
+// cities that don't have enough history of their own yet.
+func processForecastAccuracy(db *sql.DB) error {
+	rows, err := db.Query(forecastComparisonsSQL, observedMaxLeadHours, observedMaxLeadHours)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		city, country string
+		leadDays      int
+	}
+	stats := make(map[key]*ForecastAccuracy)
+	var keys []key
+	add := func(k key, temperatureError float64, conditionHit bool) {
+		a, ok := stats[k]
+		if !ok {
+			a = &ForecastAccuracy{City: k.city, Country: k.country, LeadDays: k.leadDays}
+			stats[k] = a
+			keys = append(keys, k)
+		}
+		// Running sums for now, turned into averages below
+		a.Samples++
+		a.TemperatureMAE += temperatureError
+		if conditionHit {
+			a.ConditionAccuracy++
+		}
+	}
+
+	for rows.Next() {
+		var city, country string
+		var leadDays int
+		var temperatureError float64
+		var conditionHit bool
+		if err := rows.Scan(&city, &country, &leadDays, &temperatureError, &conditionHit); err != nil {
+			rows.Close()
+			return err
+		}
+		add(key{city, country, leadDays}, temperatureError, conditionHit)
+		add(key{"", "", leadDays}, temperatureError, conditionHit)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DROP TABLE IF EXISTS forecast_accuracy"); err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+        CREATE TABLE forecast_accuracy (
+            city_name TEXT,
+            country_code TEXT,
+            lead_days INTEGER,
+            samples INTEGER,
+            temperature_mae REAL,
+            condition_accuracy REAL,
+            confidence REAL,
+            PRIMARY KEY (city_name, country_code, lead_days)
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO forecast_accuracy (city_name, country_code, lead_days, samples, temperature_mae, condition_accuracy, confidence) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, k := range keys {
+		a := stats[k]
+		a.TemperatureMAE /= float64(a.Samples)
+		a.ConditionAccuracy /= float64(a.Samples)
+		if _, err := stmt.Exec(a.City, a.Country, a.LeadDays, a.Samples, a.TemperatureMAE, a.ConditionAccuracy, a.Confidence()); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Calculated forecast accuracy for %d city lead times\n", len(keys))
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
+)
+
+// Used when the climate normals have no wind speed for a city, a light breeze
+const defaultClimateWindSpeed = 3.0
+
+type ClimateNormal struct {
+	City          string
+	Country       string
+	Month         int
+	MeanTemp      float64
+	RainDays      float64
+	SunshineHours float64
+	MeanWindSpeed sql.NullFloat64
+}
+
+// processClimateNormals scores every month in climate_normals with the same model as
+// the forecast, so dates beyond the forecast horizon still get a WPI
+func processClimateNormals(db *sql.DB, profile config_handlers.WPIProfile) error {
+	var tableCount int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'climate_normals'`).Scan(&tableCount)
+	if err != nil {
+		return err
+	}
+	if tableCount == 0 {
+		fmt.Println("No climate_normals table, skipping climatological WPI")
+		return nil
+	}
+
+	// Component score columns were added after the first climate_normals imports
+	var scoreColumns []dbschema.Column
+	for _, column := range []string{"temperature_score", "wind_score", "condition_score", "precipitation_score", "cloud_cover_score"} {
+		scoreColumns = append(scoreColumns, dbschema.Column{Name: column, Definition: "REAL"})
+	}
+	if err := dbschema.EnsureColumns(db, "climate_normals", scoreColumns); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT city_name, country_code, month, mean_temp, rain_days, sunshine_hours, mean_wind_speed FROM climate_normals`)
+	if err != nil {
+		return err
+	}
+
+	var normals []ClimateNormal
+	for rows.Next() {
+		var n ClimateNormal
+		if err := rows.Scan(&n.City, &n.Country, &n.Month, &n.MeanTemp, &n.RainDays, &n.SunshineHours, &n.MeanWindSpeed); err != nil {
+			rows.Close()
+			return err
+		}
+		normals = append(normals, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE climate_normals
+		SET wpi = ?, temperature_score = ?, wind_score = ?, condition_score = ?, precipitation_score = ?, cloud_cover_score = ?
+		WHERE city_name = ? AND country_code = ? AND month = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, n := range normals {
+		scores := climateScores(n, profile)
+		wpi := combinePleasantness(scores, profile.Weights)
+		if _, err := stmt.Exec(wpi, scores.Temperature, scores.Wind, scores.Condition, scores.Precipitation, scores.CloudCover, n.City, n.Country, n.Month); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Calculated climatological WPI for %d city-months\n", len(normals))
+	return nil
+}
+
+// climateScores are the component scores of a month of climate normals, the same way
+// weatherPleasantness scores a forecast.
+// The share of rainy days stands in for precipitation probability and the
+// share of daylight without sun for cloud cover; humidity is not known.
+func climateScores(n ClimateNormal, profile config_handlers.WPIProfile) ComponentScores {
+	wind := defaultClimateWindSpeed
+	if n.MeanWindSpeed.Valid {
+		wind = n.MeanWindSpeed.Float64
+	}
+	// Day 0 of the next month is the last day of this one
+	daysInMonth := time.Date(2001, time.Month(n.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	rainShare, sunShare := climateShares(n.RainDays, n.SunshineHours, daysInMonth)
+
+	scores := componentScores(WeatherInputs{
+		Temperature:              n.MeanTemp,
+		WindSpeed:                wind,
+		PrecipitationProbability: sql.NullFloat64{Float64: rainShare * 100, Valid: true},
+		CloudCover:               sql.NullFloat64{Float64: (1 - sunShare) * 100, Valid: true},
+	}, profile)
+	scores.Condition = climateCondPleasantness(n.RainDays, n.SunshineHours, daysInMonth, profile.Conditions)
+	return scores
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -12,54 +13,77 @@ import (
 	"github.com/schollz/progressbar/v3"
 )
 
-type WeatherPleasantnessConfig struct {
-	Conditions map[string]float64 `yaml:"conditions"`
-}
+const weatherPleasantnessPath = "../../../../../config/weatherPleasantness.yaml"
 
 type WeatherEntry struct {
-	City              string
-	Country           string
-	IATA              string
-	Date              string
-	WeatherType       string
-	Temperature       float64
-	WindSpeed         float64
-	WPI               float64
-	WeatherIconURL    string
-	GoogleWeatherLink string
+	City                     string
+	Country                  string
+	IATA                     string
+	Date                     string // UTC
+	Timezone                 string
+	WeatherType              string
+	Temperature              float64
+	WindSpeed                float64
+	PrecipitationProbability sql.NullFloat64
+	Humidity                 sql.NullFloat64
+	CloudCover               sql.NullFloat64
+	WPI                      float64
+	Scores                   ComponentScores
+	WeatherIconURL           string
+	GoogleWeatherLink        string
 }
 
 func main() {
-	if len(os.Args) == 4 {
-		processCommandLineArguments()
+	profileName := flag.String("profile", "", "WPI profile from weatherPleasantness.yaml (default: default_profile)")
+	validate := flag.Bool("validate", false, "Print the WPI of sample weather for every profile and exit")
+	flag.Parse()
+
+	config, err := config_handlers.LoadWeatherPleasantnessConfig(weatherPleasantnessPath)
+	if err != nil {
+		log.Fatal("Error loading weather pleasantness config:", err)
+	}
+
+	if *validate {
+		names := config.ProfileNames()
+		if *profileName != "" {
+			names = []string{*profileName}
+		}
+		if err := printSampleWPIs(os.Stdout, config, names); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	profile, err := config.Profile(*profileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flag.NArg() == 3 {
+		processCommandLineArguments(flag.Args(), profile)
 	} else {
-		processDatabaseEntries()
+		processDatabaseEntries(profile)
 	}
 }
 
-func processCommandLineArguments() {
-	temp, err := strconv.ParseFloat(os.Args[1], 64)
+func processCommandLineArguments(args []string, profile config_handlers.WPIProfile) {
+	temp, err := strconv.ParseFloat(args[0], 64)
 	if err != nil {
 		log.Fatal("Invalid temperature input:", err)
 	}
 
-	windSpeed, err := strconv.ParseFloat(os.Args[2], 64)
+	windSpeed, err := strconv.ParseFloat(args[1], 64)
 	if err != nil {
 		log.Fatal("Invalid wind speed input:", err)
 	}
 
-	condition := os.Args[3]
+	condition := args[2]
 
-	config, err := config_handlers.LoadWeatherPleasantnessConfig("../../../../../config/weatherPleasantness.yaml")
-	if err != nil {
-		log.Fatal("Error loading weather pleasantness config:", err)
-	}
-
-	wpi := weatherPleasantness(temp, windSpeed, condition, config)
+	wpi := weatherPleasantness(WeatherInputs{Temperature: temp, WindSpeed: windSpeed, Condition: condition}, profile)
 	fmt.Printf("Weather Pleasantness Index: %.2f\n", wpi)
 }
 
-func processDatabaseEntries() {
+func processDatabaseEntries(profile config_handlers.WPIProfile) {
 	db, err := sql.Open("sqlite3", "../../../../../data/raw/weather/weather.db")
 	if err != nil {
 		log.Fatal("Error opening database:", err)
@@ -79,12 +103,22 @@ func processDatabaseEntries() {
             country_code TEXT,
             iata TEXT,
             date TEXT,
+            timezone TEXT,
             weather_type TEXT,
             temperature REAL,
             weather_icon_url TEXT,
             google_weather_link TEXT,
             wind_speed REAL,
-            wpi REAL
+            precipitation_probability REAL,
+            humidity REAL,
+            cloud_cover REAL,
+            wpi REAL,
+            temperature_score REAL,
+            wind_score REAL,
+            condition_score REAL,
+            precipitation_score REAL,
+            humidity_score REAL,
+            cloud_cover_score REAL
         )
     `)
 	if err != nil {
@@ -92,9 +126,17 @@ func processDatabaseEntries() {
 	}
 
 	rows, err := db.Query(`
-        SELECT city_name, country_code, iata, date, weather_type, temperature, wind_speed, weather_icon_url, google_weather_link
-        FROM all_weather
-        WHERE datetime(date) > datetime('now', 'localtime') AND city_name != ''
+        SELECT city_name, country_code, iata, date_utc, COALESCE(timezone, 'UTC'), weather_type, temperature, wind_speed, precipitation_probability, humidity, cloud_cover, weather_icon_url, google_weather_link
+        FROM (
+            -- all_weather keeps every fetch as forecast history; only the latest one is current
+            SELECT *, ROW_NUMBER() OVER (PARTITION BY iata, date_utc ORDER BY fetched_at DESC) AS latest
+            FROM (
+                -- Rows fetched before dates were stored in UTC are in this server's local time
+                SELECT *, CASE WHEN timezone IS NULL THEN datetime(date, 'utc') ELSE datetime(date) END AS date_utc
+                FROM all_weather
+            )
+        )
+        WHERE latest = 1 AND date_utc > datetime('now') AND city_name != ''
     `)
 	if err != nil {
 		log.Fatal("Error querying database:", err)
@@ -104,17 +146,12 @@ func processDatabaseEntries() {
 	var entries []WeatherEntry
 	for rows.Next() {
 		var entry WeatherEntry
-		if err := rows.Scan(&entry.City, &entry.Country, &entry.IATA, &entry.Date, &entry.WeatherType, &entry.Temperature, &entry.WindSpeed, &entry.WeatherIconURL, &entry.GoogleWeatherLink); err != nil {
+		if err := rows.Scan(&entry.City, &entry.Country, &entry.IATA, &entry.Date, &entry.Timezone, &entry.WeatherType, &entry.Temperature, &entry.WindSpeed, &entry.PrecipitationProbability, &entry.Humidity, &entry.CloudCover, &entry.WeatherIconURL, &entry.GoogleWeatherLink); err != nil {
 			log.Fatal("Error scanning database row:", err)
 		}
 		entries = append(entries, entry)
 	}
 
-	config, err := config_handlers.LoadWeatherPleasantnessConfig("../../../../../config/weatherPleasantness.yaml")
-	if err != nil {
-		log.Fatal("Error loading weather pleasantness config:", err)
-	}
-
 	bar := progressbar.Default(int64(len(entries)))
 
 	tx, err := db.Begin()
@@ -122,7 +159,7 @@ func processDatabaseEntries() {
 		log.Fatal("Error starting transaction:", err)
 	}
 
-	stmt, err := tx.Prepare(`INSERT INTO current_weather (city_name, country_code, iata, date, weather_type, temperature, wind_speed, wpi, weather_icon_url, google_weather_link) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO current_weather (city_name, country_code, iata, date, timezone, weather_type, temperature, wind_speed, precipitation_probability, humidity, cloud_cover, wpi, temperature_score, wind_score, condition_score, precipitation_score, humidity_score, cloud_cover_score, weather_icon_url, google_weather_link) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		tx.Rollback()
 		log.Fatal("Failed to prepare SQL statement:", err)
@@ -130,8 +167,19 @@ func processDatabaseEntries() {
 	defer stmt.Close()
 
 	for _, entry := range entries {
-		entry.WPI = weatherPleasantness(entry.Temperature, entry.WindSpeed, entry.WeatherType, config)
-		_, err = stmt.Exec(entry.City, entry.Country, entry.IATA, entry.Date, entry.WeatherType, entry.Temperature, entry.WindSpeed, entry.WPI, entry.WeatherIconURL, entry.GoogleWeatherLink)
+		// Keep the component scores so the website can explain the WPI
+		entry.Scores = componentScores(WeatherInputs{
+			Temperature:              entry.Temperature,
+			WindSpeed:                entry.WindSpeed,
+			Condition:                entry.WeatherType,
+			PrecipitationProbability: entry.PrecipitationProbability,
+			Humidity:                 entry.Humidity,
+			CloudCover:               entry.CloudCover,
+		}, profile)
+		entry.WPI = combinePleasantness(entry.Scores, profile.Weights)
+		scores := entry.Scores
+		_, err = stmt.Exec(entry.City, entry.Country, entry.IATA, entry.Date, entry.Timezone, entry.WeatherType, entry.Temperature, entry.WindSpeed, entry.PrecipitationProbability, entry.Humidity, entry.CloudCover, entry.WPI,
+			scores.Temperature, scores.Wind, scores.Condition, scores.Precipitation, scores.Humidity, scores.CloudCover, entry.WeatherIconURL, entry.GoogleWeatherLink)
 		if err != nil {
 			tx.Rollback()
 			log.Fatal("Failed to insert data into current_weather:", err)
@@ -143,16 +191,16 @@ func processDatabaseEntries() {
 	if err := tx.Commit(); err != nil {
 		log.Fatal("Error committing transaction:", err)
 	}
-}
 
-func weatherPleasantness(temp float64, wind float64, cond string, config config_handlers.WeatherPleasantnessConfig) float64 {
-	weightTemp := 5.0
-	weightWind := 1.0
-	weightCond := 2.0
+	if err := processClimateNormals(db, profile); err != nil {
+		log.Fatal("Error calculating climatological WPI:", err)
+	}
 
-	tempIndex := tempPleasantness(temp) * weightTemp
-	windIndex := windPleasantness(wind) * weightWind
-	weatherIndex := weatherCondPleasantness(cond, config) * weightCond
+	if err := processForecastAccuracy(db); err != nil {
+		log.Fatal("Error calculating forecast accuracy:", err)
+	}
+}
 
-	return (tempIndex + windIndex + weatherIndex) / (weightTemp + weightWind + weightCond)
+func weatherPleasantness(in WeatherInputs, profile config_handlers.WPIProfile) float64 {
+	return combinePleasantness(componentScores(in, profile), profile.Weights)
 }
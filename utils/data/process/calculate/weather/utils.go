@@ -1,6 +1,8 @@
 package main
 
 import (
+	"database/sql"
+
 	"github.com/Tris20/FairFareFinder/config/handlers"
 )
 
@@ -10,52 +12,122 @@ func interpolate(temp, temp1, temp2, score1, score2 float64) float64 {
 	return ((temp-temp1)/(temp2-temp1))*(score2-score1) + score1
 }
 
-// tempPleasantness, windPleasantness, weatherCondPleasantness defined here.
-
-func tempPleasantness(temperature float64) float64 {
-	// Optimal range
-	if temperature >= 22 && temperature <= 26 {
-		return 10
+// curveScore reads a score off a piecewise linear curve, holding the end scores flat beyond the first and last breakpoints
+func curveScore(points []config_handlers.CurvePoint, value float64) float64 {
+	if len(points) == 0 {
+		return 0
 	}
-
-	//     Interpolation between key temperatures below the optimal range
-	if temperature > 18 && temperature < 22 {
-		return interpolate(temperature, 18, 22, 7, 10)
+	if value <= points[0].Value {
+		return points[0].Score
 	}
-
-	// Interpolation between key temperatures above the optimal range
-	if temperature > 26 && temperature < 40 {
-		return interpolate(temperature, 26, 40, 10, 0)
+	for i := 1; i < len(points); i++ {
+		if value <= points[i].Value {
+			return interpolate(value, points[i-1].Value, points[i].Value, points[i-1].Score, points[i].Score)
+		}
 	}
+	return points[len(points)-1].Score
+}
 
-	// Below 18 down to 0
-	if temperature >= 5 && temperature <= 18 {
-		return interpolate(temperature, 5, 18, 0, 7)
-	}
+// WeatherInputs are the measurements a WPI is calculated from.
+// Rows fetched before a measurement existed leave it NULL, and its component is skipped.
+type WeatherInputs struct {
+	Temperature              float64
+	WindSpeed                float64
+	Condition                string
+	PrecipitationProbability sql.NullFloat64
+	Humidity                 sql.NullFloat64
+	CloudCover               sql.NullFloat64
+}
 
-	// Anything below 0 or above 40
-	if temperature <= 5 || temperature >= 40 {
-		return 0
+// ComponentScores are the 0 to 10 scores that a profile's weights combine into the WPI
+type ComponentScores struct {
+	Temperature   float64
+	Wind          float64
+	Condition     float64
+	Precipitation sql.NullFloat64
+	Humidity      sql.NullFloat64
+	CloudCover    sql.NullFloat64
+}
+
+// componentScores scores each measurement against the profile's curves
+func componentScores(in WeatherInputs, profile config_handlers.WPIProfile) ComponentScores {
+	scores := ComponentScores{
+		Temperature: curveScore(profile.Temperature, in.Temperature),
+		Wind:        curveScore(profile.Wind, in.WindSpeed),
+		Condition:   weatherCondPleasantness(in.Condition, profile.Conditions),
 	}
+	scores.Precipitation = optionalScore(profile.Precipitation, in.PrecipitationProbability)
+	scores.Humidity = optionalScore(profile.Humidity, in.Humidity)
+	scores.CloudCover = optionalScore(profile.CloudCover, in.CloudCover)
+	return scores
+}
 
-	return 0 // Default case if needed
+func optionalScore(points []config_handlers.CurvePoint, value sql.NullFloat64) sql.NullFloat64 {
+	if !value.Valid || len(points) == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: curveScore(points, value.Float64), Valid: true}
 }
 
-// windPleasantness returns a value between 0 and 10 for wind condition pleasantness
-func windPleasantness(windSpeed float64) float64 {
-	worstWind := 13.8
-	if windSpeed >= worstWind {
+// combinePleasantness weights the component scores into one WPI.
+// Missing optional components are left out of both the sum and the total weight.
+func combinePleasantness(scores ComponentScores, weights config_handlers.WPIWeights) float64 {
+	sum := scores.Temperature*weights.Temperature + scores.Wind*weights.Wind + scores.Condition*weights.Condition
+	totalWeight := weights.Temperature + weights.Wind + weights.Condition
+
+	optional := []struct {
+		score  sql.NullFloat64
+		weight float64
+	}{
+		{scores.Precipitation, weights.Precipitation},
+		{scores.Humidity, weights.Humidity},
+		{scores.CloudCover, weights.CloudCover},
+	}
+	for _, component := range optional {
+		if component.score.Valid && component.weight > 0 {
+			sum += component.score.Float64 * component.weight
+			totalWeight += component.weight
+		}
+	}
+
+	if totalWeight == 0 {
 		return 0
-	} else {
-		return 10 - windSpeed*10/worstWind
 	}
+	return sum / totalWeight
 }
 
 // weatherCondPleasantness returns a value between 0 and 10 for weather condition pleasantness
-func weatherCondPleasantness(cond string, config config_handlers.WeatherPleasantnessConfig) float64 {
-	pleasantness, ok := config.Conditions[cond]
+func weatherCondPleasantness(cond string, conditions map[string]float64) float64 {
+	pleasantness, ok := conditions[cond]
 	if !ok {
 		return 0
 	}
 	return pleasantness
 }
+
+// climateCondPleasantness turns a month's rain days and sunshine into a condition score.
+// Rainy days score as Rain; dry days score between Clouds and Clear depending on how sunny the month is.
+func climateCondPleasantness(rainDays, sunshineHours float64, daysInMonth int, conditions map[string]float64) float64 {
+	rainShare, sunShare := climateShares(rainDays, sunshineHours, daysInMonth)
+
+	dryScore := sunShare*weatherCondPleasantness("Clear", conditions) + (1-sunShare)*weatherCondPleasantness("Clouds", conditions)
+	return rainShare*weatherCondPleasantness("Rain", conditions) + (1-rainShare)*dryScore
+}
+
+// climateShares returns the share of rainy days and the share of daylight that is sunny
+func climateShares(rainDays, sunshineHours float64, daysInMonth int) (float64, float64) {
+	rainShare := clamp(rainDays/float64(daysInMonth), 0, 1)
+	// Assume about 12 hours of daylight; a month with that much sunshine every day counts as clear
+	sunShare := clamp(sunshineHours/float64(daysInMonth)/12, 0, 1)
+	return rainShare, sunShare
+}
+
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
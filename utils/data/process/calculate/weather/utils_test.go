@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+)
+
+// legacyWPI is the WPI as it was hard-coded before weatherPleasantness.yaml had profiles
+func legacyWPI(temperature, windSpeed float64, cond string, conditions map[string]float64) float64 {
+	var tempScore float64
+	switch {
+	case temperature >= 22 && temperature <= 26:
+		tempScore = 10
+	case temperature > 18 && temperature < 22:
+		tempScore = interpolate(temperature, 18, 22, 7, 10)
+	case temperature > 26 && temperature < 40:
+		tempScore = interpolate(temperature, 26, 40, 10, 0)
+	case temperature >= 5 && temperature <= 18:
+		tempScore = interpolate(temperature, 5, 18, 0, 7)
+	}
+
+	worstWind := 13.8
+	windScore := 0.0
+	if windSpeed < worstWind {
+		windScore = 10 - windSpeed*10/worstWind
+	}
+
+	condScore := conditions[cond]
+	return (tempScore*5 + windScore*1 + condScore*2) / (5 + 1 + 2)
+}
+
+// TestDefaultProfileMatchesLegacyWPI keeps the stored WPI unchanged for the default profile,
+// both as configured in weatherPleasantness.yaml and as built in for when it defines no profiles
+func TestDefaultProfileMatchesLegacyWPI(t *testing.T) {
+	config, err := config_handlers.LoadWeatherPleasantnessConfig(weatherPleasantnessPath)
+	if err != nil {
+		t.Fatalf("Error loading %s: %v", weatherPleasantnessPath, err)
+	}
+	if config.DefaultProfile != "balanced" {
+		t.Fatalf("default_profile is %q, want balanced", config.DefaultProfile)
+	}
+	configured, err := config.Profile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtIn, err := config_handlers.WeatherPleasantnessConfig{Conditions: config.Conditions}.Profile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	humid := sql.NullFloat64{Float64: 90, Valid: true}
+	tests := []struct {
+		name string
+		in   WeatherInputs
+	}{
+		{"freezing and calm", WeatherInputs{Temperature: -3, WindSpeed: 0, Condition: "Snow"}},
+		{"at the bottom of the curve", WeatherInputs{Temperature: 5, WindSpeed: 2, Condition: "Fog"}},
+		{"cool", WeatherInputs{Temperature: 12.5, WindSpeed: 4.2, Condition: "Clouds"}},
+		{"mild", WeatherInputs{Temperature: 18, WindSpeed: 3, Condition: "Drizzle"}},
+		{"warming up", WeatherInputs{Temperature: 20.4, WindSpeed: 1.1, Condition: "Clear"}},
+		{"ideal", WeatherInputs{Temperature: 24, WindSpeed: 0, Condition: "Clear"}},
+		{"hot", WeatherInputs{Temperature: 33, WindSpeed: 6.9, Condition: "Haze"}},
+		{"scorching", WeatherInputs{Temperature: 41, WindSpeed: 9, Condition: "Dust"}},
+		{"gale", WeatherInputs{Temperature: 15, WindSpeed: 20, Condition: "Squall"}},
+		{"unknown condition", WeatherInputs{Temperature: 22, WindSpeed: 5, Condition: "Volcano"}},
+		// balanced scores none of the optional components, so they must not move the WPI
+		{"muggy", WeatherInputs{Temperature: 27, WindSpeed: 2, Condition: "Rain", Humidity: humid,
+			PrecipitationProbability: sql.NullFloat64{Float64: 80, Valid: true}, CloudCover: sql.NullFloat64{Float64: 100, Valid: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := legacyWPI(tt.in.Temperature, tt.in.WindSpeed, tt.in.Condition, config.Conditions)
+			for name, profile := range map[string]config_handlers.WPIProfile{"configured": configured, "built-in": builtIn} {
+				if got := weatherPleasantness(tt.in, profile); math.Abs(got-want) > 1e-9 {
+					t.Errorf("%s profile: WPI %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
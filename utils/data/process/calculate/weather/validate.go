@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Tris20/FairFareFinder/config/handlers"
+)
+
+// sampleWeather covers the kinds of days the WPI has to rank sensibly.
+// Review the -validate output for these after any change to weatherPleasantness.yaml.
+var sampleWeather = []struct {
+	Name   string
+	Inputs WeatherInputs
+}{
+	{"Beach day", WeatherInputs{Temperature: 28, WindSpeed: 3, Condition: "Clear", PrecipitationProbability: percent(0), Humidity: percent(55), CloudCover: percent(5)}},
+	{"Mild and sunny", WeatherInputs{Temperature: 21, WindSpeed: 2, Condition: "Clear", PrecipitationProbability: percent(0), Humidity: percent(50), CloudCover: percent(10)}},
+	{"Heatwave", WeatherInputs{Temperature: 38, WindSpeed: 1, Condition: "Clear", PrecipitationProbability: percent(0), Humidity: percent(30), CloudCover: percent(0)}},
+	{"Muggy and cloudy", WeatherInputs{Temperature: 27, WindSpeed: 1, Condition: "Clouds", PrecipitationProbability: percent(30), Humidity: percent(90), CloudCover: percent(80)}},
+	{"Overcast autumn", WeatherInputs{Temperature: 14, WindSpeed: 4, Condition: "Clouds", PrecipitationProbability: percent(20), Humidity: percent(75), CloudCover: percent(95)}},
+	{"Light drizzle", WeatherInputs{Temperature: 16, WindSpeed: 3, Condition: "Drizzle", PrecipitationProbability: percent(70), Humidity: percent(85), CloudCover: percent(100)}},
+	{"Windy rain", WeatherInputs{Temperature: 10, WindSpeed: 11, Condition: "Rain", PrecipitationProbability: percent(95), Humidity: percent(90), CloudCover: percent(100)}},
+	{"Thunderstorm", WeatherInputs{Temperature: 24, WindSpeed: 8, Condition: "Thunderstorm", PrecipitationProbability: percent(90), Humidity: percent(85), CloudCover: percent(100)}},
+	{"Crisp winter sun", WeatherInputs{Temperature: 2, WindSpeed: 2, Condition: "Clear", PrecipitationProbability: percent(0), Humidity: percent(60), CloudCover: percent(0)}},
+	{"Snow", WeatherInputs{Temperature: -3, WindSpeed: 5, Condition: "Snow", PrecipitationProbability: percent(80), Humidity: percent(90), CloudCover: percent(100)}},
+}
+
+func percent(value float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: value, Valid: true}
+}
+
+// printSampleWPIs prints one row per sample day with its WPI under each profile
+func printSampleWPIs(out io.Writer, config config_handlers.WeatherPleasantnessConfig, names []string) error {
+	profiles := make([]config_handlers.WPIProfile, len(names))
+	for i, name := range names {
+		profile, err := config.Profile(name)
+		if err != nil {
+			return err
+		}
+		profiles[i] = profile
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "Sample\tTemp\tWind\tCondition\tRain %\tHumidity\tClouds\t")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t", name)
+	}
+	fmt.Fprintln(w)
+
+	for _, sample := range sampleWeather {
+		in := sample.Inputs
+		fmt.Fprintf(w, "%s\t%.0f°C\t%.0f m/s\t%s\t%.0f\t%.0f\t%.0f\t",
+			sample.Name, in.Temperature, in.WindSpeed, in.Condition,
+			in.PrecipitationProbability.Float64, in.Humidity.Float64, in.CloudCover.Float64)
+		for _, profile := range profiles {
+			fmt.Fprintf(w, "%.2f\t", weatherPleasantness(in, profile))
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
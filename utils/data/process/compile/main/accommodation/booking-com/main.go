@@ -5,26 +5,60 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"os"
 	"sort"
+	"time"
 
+	"compile-main-db/dbschema"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/schollz/progressbar/v3"
 )
 
 // Accommodation represents the filtered data we will extract
 type Accommodation struct {
-	City       string
-	Country    string
-	GrossPrice float64
-	Checkin    string
-	Checkout   string
+	City        string
+	Country     string
+	GrossPrice  float64
+	ReviewScore sql.NullFloat64
+	Checkin     string
+	Checkout    string
 }
 
 // LocationPrices holds prices for a specific location (city + country)
 type LocationPrices struct {
-	City    string
-	Country string
-	Prices  []float64
+	City          string
+	Country       string
+	Prices        []float64 // Of places reviewed above 7, for booking_pppn
+	Properties    []Property
+	LatestCheckin string
+}
+
+// Property is one place's price for a stay, and its review score (0 if unreviewed)
+type Property struct {
+	Price       float64
+	ReviewScore float64
+}
+
+// A city's booking.com prices are stale once its latest stay checked in this long ago.
+// Fetching is paused, so estimates from generate/accommodation-prices take their place.
+const observationStaleAfterDays = 28
+
+// buildBookingURL builds the booking.com search for a city's stay
+func buildBookingURL(city, checkin, checkout string) string {
+	return fmt.Sprintf("https://www.booking.com/searchresults.en-gb.html?ss=%s&group_adults=1&no_rooms=1&group_children=0&nflt=price%%3DEUR-min-110-1%%3Breview_score%%3D80&flex_window=2&checkin=%s&checkout=%s", city, checkin, checkout)
+}
+
+// accommodationColumns are the columns added to the accommodation table since it was
+// first created
+var accommodationColumns = []dbschema.Column{
+	{Name: "booking_pppn_source", Definition: "TEXT DEFAULT 'observed'"},
+	{Name: "booking_pppn_p10", Definition: "REAL"},
+	{Name: "booking_pppn_p50", Definition: "REAL"},
+	{Name: "booking_pppn_p90", Definition: "REAL"},
+	{Name: "booking_pppn_budget", Definition: "REAL"},
+	{Name: "booking_pppn_mid_range", Definition: "REAL"},
+	{Name: "booking_pppn_premium", Definition: "REAL"},
+	{Name: "booking_properties", Definition: "INTEGER"},
 }
 
 func main() {
@@ -41,12 +75,27 @@ func main() {
 		city TEXT NOT NULL,
 		country TEXT NOT NULL,
 		booking_url TEXT,
-		booking_pppn REAL NOT NULL
+		booking_pppn REAL NOT NULL,
+		booking_pppn_source TEXT DEFAULT 'observed',
+		booking_pppn_p10 REAL,
+		booking_pppn_p50 REAL,
+		booking_pppn_p90 REAL,
+		booking_pppn_budget REAL,
+		booking_pppn_mid_range REAL,
+		booking_pppn_premium REAL,
+		booking_properties INTEGER
 	);`
 	_, err = newDb.Exec(createTableQuery)
 	if err != nil {
 		log.Fatalf("Failed to create accommodation table: %v", err)
 	}
+	if err := dbschema.EnsureColumns(newDb, "accommodation", accommodationColumns); err != nil {
+		log.Fatalf("Failed to migrate accommodation table: %v", err)
+	}
+	// The table is derived entirely from booking.db and the estimates, so rebuild it
+	if _, err := newDb.Exec(`DELETE FROM accommodation`); err != nil {
+		log.Fatalf("Failed to clear accommodation table: %v", err)
+	}
 
 	// Step 3: Open the "raw/booking.db"
 	rawDb, err := sql.Open("sqlite3", "../../../../../../../data/raw/accommocation/booking-com/booking.db")
@@ -55,8 +104,9 @@ func main() {
 	}
 	defer rawDb.Close()
 
-	// Step 4: Query the 'property' table for records where review_score > 7
-	query := `SELECT city, country, gross_price, checkin_date, checkout_date FROM property WHERE review_score > 7`
+	// Step 4: Query the 'property' table. booking_pppn is of the places reviewed above 7,
+	// the percentiles and tiers of all of them.
+	query := `SELECT city, country, gross_price, review_score, checkin_date, checkout_date FROM property WHERE gross_price > 0`
 	rows, err := rawDb.Query(query)
 	if err != nil {
 		log.Fatalf("Failed to query property table: %v", err)
@@ -70,7 +120,7 @@ func main() {
 
 	for rows.Next() {
 		var acc Accommodation
-		err := rows.Scan(&acc.City, &acc.Country, &acc.GrossPrice, &acc.Checkin, &acc.Checkout)
+		err := rows.Scan(&acc.City, &acc.Country, &acc.GrossPrice, &acc.ReviewScore, &acc.Checkin, &acc.Checkout)
 		if err != nil {
 			log.Printf("Failed to scan row: %v", err)
 			continue
@@ -92,17 +142,33 @@ func main() {
 		}
 
 		location := locationData[locationKey]
-		location.Prices = append(location.Prices, acc.GrossPrice)
+		if acc.ReviewScore.Float64 > 7 {
+			location.Prices = append(location.Prices, acc.GrossPrice)
+		}
+		location.Properties = append(location.Properties, Property{Price: acc.GrossPrice / pppnDivisor, ReviewScore: acc.ReviewScore.Float64})
+		if acc.Checkin > location.LatestCheckin {
+			location.LatestCheckin = acc.Checkin
+		}
 		locationData[locationKey] = location
 	}
 
 	// Step 6: Set up the progress bar for processing the locations
 	bar := progressbar.Default(int64(len(locationData)))
 
+	// Cities whose prices are older than this take an estimate instead, if there is one
+	estimates := readEstimates("../../../../../../../data/generated/accommodation-prices.db")
+	staleBefore := time.Now().UTC().AddDate(0, 0, -observationStaleAfterDays).Format("2006-01-02")
+	observed := make(map[string]bool)
+	tierPrices := make(map[string]PriceStats)
+
 	// Step 7: Process each location's prices and insert into new_main.db
-	for _, loc := range locationData {
+	for key, loc := range locationData {
 		bar.Add(1)
 
+		if _, ok := estimates[key]; ok && loc.LatestCheckin < staleBefore {
+			continue
+		}
+
 		// Sort the prices (lowest to highest)
 		sort.Float64s(loc.Prices)
 
@@ -125,18 +191,51 @@ func main() {
 		medianPrice := calculateMedian(remainingPrices)
 
 		// Step 9: Calculate avg_pppn by dividing the median by 14 and rounding to 2 decimal places
-		avgPppn := roundToTwoDecimalPlaces(medianPrice / 14)
+		avgPppn := roundToTwoDecimalPlaces(medianPrice / pppnDivisor)
 
 		// Step 10: Create the booking URL for this location
-		bookingURL := fmt.Sprintf("https://www.booking.com/searchresults.en-gb.html?ss=%s&group_adults=1&no_rooms=1&group_children=0&nflt=price%%3DEUR-min-110-1%%3Breview_score%%3D80&flex_window=2&checkin=%s&checkout=%s", loc.City, checkinDate, checkoutDate)
+		bookingURL := buildBookingURL(loc.City, checkinDate, checkoutDate)
 
 		// Step 11: Insert the data into the accommodation table
-		insertQuery := `INSERT INTO accommodation (city, country, booking_url, booking_pppn) VALUES (?, ?, ?, ?)`
-		_, err := newDb.Exec(insertQuery, loc.City, loc.Country, bookingURL, avgPppn)
+		stats := summarise(loc.Properties)
+		stats.Standard = avgPppn
+		tierPrices[key] = stats
+		insertQuery := `INSERT INTO accommodation (
+			city, country, booking_url, booking_pppn, booking_pppn_source,
+			booking_pppn_p10, booking_pppn_p50, booking_pppn_p90,
+			booking_pppn_budget, booking_pppn_mid_range, booking_pppn_premium, booking_properties
+		) VALUES (?, ?, ?, ?, 'observed', ?, ?, ?, ?, ?, ?, ?)`
+		_, err := newDb.Exec(insertQuery, loc.City, loc.Country, bookingURL, avgPppn,
+			stats.P10, stats.P50, stats.P90, stats.Budget, stats.MidRange, stats.Premium, len(loc.Properties))
 		if err != nil {
 			log.Printf("Failed to insert accommodation for %s, %s: %v", loc.City, loc.Country, err)
+			continue
+		}
+		observed[key] = true
+	}
+
+	// Step 12: Fill the cities without a fresh booking.com price with their estimate. Their
+	// tiers are the estimate scaled as the tiers of the observed cities typically are.
+	ratios := typicalTierRatios(tierPrices)
+	estimated := 0
+	for key, e := range estimates {
+		if observed[key] {
+			continue
+		}
+		bookingURL := buildBookingURL(e.City, e.StayDate, e.CheckoutDate())
+		insertQuery := `INSERT INTO accommodation (
+			city, country, booking_url, booking_pppn, booking_pppn_source,
+			booking_pppn_budget, booking_pppn_mid_range, booking_pppn_premium
+		) VALUES (?, ?, ?, ?, 'estimated', ?, ?, ?)`
+		_, err := newDb.Exec(insertQuery, e.City, e.Country, bookingURL, roundToTwoDecimalPlaces(e.Pppn),
+			ratios.scale(ratios.Budget, e.Pppn), ratios.scale(ratios.MidRange, e.Pppn), ratios.scale(ratios.Premium, e.Pppn))
+		if err != nil {
+			log.Printf("Failed to insert estimated accommodation for %s, %s: %v", e.City, e.Country, err)
+			continue
 		}
+		estimated++
 	}
+	fmt.Printf("Estimated accommodation prices for %d cities without a fresh booking.com price\n", estimated)
 
 	fmt.Println("Data inserted into new_main.db successfully!")
 }
@@ -154,3 +253,52 @@ func calculateMedian(prices []float64) float64 {
 func roundToTwoDecimalPlaces(value float64) float64 {
 	return math.Round(value*100) / 100
 }
+
+// Estimate is generate/accommodation-prices' per-night price for a city
+type Estimate struct {
+	City     string
+	Country  string
+	Pppn     float64
+	StayDate string
+}
+
+// CheckoutDate is a week after StayDate, like the stays booking.com is searched for
+func (e Estimate) CheckoutDate() string {
+	stayDate, err := time.Parse("2006-01-02", e.StayDate)
+	if err != nil {
+		return ""
+	}
+	return stayDate.AddDate(0, 0, 7).Format("2006-01-02")
+}
+
+// readEstimates reads the estimates by "city,country". Without accommodation-prices.db
+// there are none, and every city keeps its booking.com price however old.
+func readEstimates(path string) map[string]Estimate {
+	estimates := make(map[string]Estimate)
+	if _, err := os.Stat(path); err != nil {
+		fmt.Printf("No accommodation price estimates at %s\n", path)
+		return estimates
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Printf("Failed to open accommodation-prices.db: %v", err)
+		return estimates
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT city, country, estimated_pppn, stay_date FROM estimate WHERE estimated_pppn > 0`)
+	if err != nil {
+		log.Printf("Failed to read accommodation price estimates: %v", err)
+		return estimates
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e Estimate
+		if err := rows.Scan(&e.City, &e.Country, &e.Pppn, &e.StayDate); err != nil {
+			log.Printf("Failed to scan accommodation price estimate: %v", err)
+			continue
+		}
+		estimates[fmt.Sprintf("%s,%s", e.City, e.Country)] = e
+	}
+	return estimates
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// Gross prices are divided by this for booking_pppn, the percentiles and the tiers
+const pppnDivisor = 14
+
+// A city's percentiles need as many places as booking_pppn does, and each tier this many
+const minTierProperties = 3
+
+// Places reviewed this well or better count as mid-range or premium
+const minTierReviewScore = 8
+
+/*
+The tiers split a city's places into thirds by price:
+
+	budget     the cheapest third, however well reviewed
+	mid-range  the middle third, reviewed 8 or more
+	premium    the dearest third, reviewed 8 or more
+
+Each tier's price is the median of its places. A city with too few places in a tier has
+no price for it, rather than one from a handful of places.
+*/
+
+// PriceStats are a city's per-night prices. The percentiles are of all its places.
+type PriceStats struct {
+	Standard                  float64 // booking_pppn
+	P10, P50, P90             sql.NullFloat64
+	Budget, MidRange, Premium sql.NullFloat64
+}
+
+// summarise computes the percentiles and tiers of a city's places
+func summarise(properties []Property) PriceStats {
+	var stats PriceStats
+	sorted := make([]Property, len(properties))
+	copy(sorted, properties)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+
+	prices := make([]float64, len(sorted))
+	for i, p := range sorted {
+		prices[i] = p.Price
+	}
+	if len(prices) >= 10 {
+		stats.P10 = roundedPrice(percentile(prices, 0.10))
+		stats.P50 = roundedPrice(percentile(prices, 0.50))
+		stats.P90 = roundedPrice(percentile(prices, 0.90))
+	}
+
+	third := len(sorted) / 3
+	stats.Budget = tierPrice(sorted[:third], 0)
+	stats.MidRange = tierPrice(sorted[third:len(sorted)-third], minTierReviewScore)
+	stats.Premium = tierPrice(sorted[len(sorted)-third:], minTierReviewScore)
+	return stats
+}
+
+// tierPrice is the median price of the places reviewed minReviewScore or more
+func tierPrice(properties []Property, minReviewScore float64) sql.NullFloat64 {
+	var prices []float64
+	for _, p := range properties {
+		if p.ReviewScore >= minReviewScore {
+			prices = append(prices, p.Price)
+		}
+	}
+	if len(prices) < minTierProperties {
+		return sql.NullFloat64{}
+	}
+	return roundedPrice(calculateMedian(prices))
+}
+
+// percentile of sorted prices, interpolating between neighbours
+func percentile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(position)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*(position-float64(lower))
+}
+
+func roundedPrice(price float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: roundToTwoDecimalPlaces(price), Valid: true}
+}
+
+// TierRatios are the typical price of each tier over booking_pppn, across observed cities
+type TierRatios struct {
+	Budget, MidRange, Premium sql.NullFloat64
+}
+
+// typicalTierRatios takes the median ratio of each tier over the cities that have it
+func typicalTierRatios(cities map[string]PriceStats) TierRatios {
+	var budget, midRange, premium []float64
+	for _, stats := range cities {
+		if stats.Standard <= 0 {
+			continue
+		}
+		if stats.Budget.Valid {
+			budget = append(budget, stats.Budget.Float64/stats.Standard)
+		}
+		if stats.MidRange.Valid {
+			midRange = append(midRange, stats.MidRange.Float64/stats.Standard)
+		}
+		if stats.Premium.Valid {
+			premium = append(premium, stats.Premium.Float64/stats.Standard)
+		}
+	}
+	return TierRatios{Budget: medianRatio(budget), MidRange: medianRatio(midRange), Premium: medianRatio(premium)}
+}
+
+func medianRatio(ratios []float64) sql.NullFloat64 {
+	if len(ratios) == 0 {
+		return sql.NullFloat64{}
+	}
+	sort.Float64s(ratios)
+	return sql.NullFloat64{Float64: calculateMedian(ratios), Valid: true}
+}
+
+// scale applies a tier's ratio to an estimated booking_pppn
+func (TierRatios) scale(ratio sql.NullFloat64, pppn float64) sql.NullFloat64 {
+	if !ratio.Valid {
+		return sql.NullFloat64{}
+	}
+	return roundedPrice(pppn * ratio.Float64)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// propertiesPriced returns a property for each price, all reviewed reviewScore
+func propertiesPriced(reviewScore float64, prices ...float64) []Property {
+	properties := make([]Property, len(prices))
+	for i, price := range prices {
+		properties[i] = Property{Price: price, ReviewScore: reviewScore}
+	}
+	return properties
+}
+
+func price(p float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: p, Valid: true}
+}
+
+func TestSummarise(t *testing.T) {
+	// Twelve places from 10 to 120, given out of order; the middle third has two poorly reviewed
+	twelve := propertiesPriced(9, 120, 10, 110, 20, 100, 30, 90, 40, 80, 50, 70, 60)
+	poorlyReviewedMiddle := append([]Property(nil), twelve...)
+	poorlyReviewedMiddle[9].ReviewScore = 7  // 50
+	poorlyReviewedMiddle[11].ReviewScore = 6 // 60
+	// A cheap place with poor reviews is still budget
+	poorlyReviewedBudget := propertiesPriced(9, 10, 20, 30, 40, 50, 60, 70, 80, 90)
+	poorlyReviewedBudget[0].ReviewScore = 5
+
+	tests := []struct {
+		name       string
+		properties []Property
+		want       PriceStats
+	}{
+		{"no places", nil, PriceStats{}},
+		{"too few for any tier", propertiesPriced(9, 50, 60), PriceStats{}},
+		{"a third each", propertiesPriced(9, 10, 20, 30, 40, 50, 60, 70, 80, 90),
+			PriceStats{Budget: price(20), MidRange: price(50), Premium: price(80)}},
+		{"poorly reviewed budget place", poorlyReviewedBudget,
+			PriceStats{Budget: price(20), MidRange: price(50), Premium: price(80)}},
+		{"percentiles", twelve,
+			PriceStats{P10: price(21), P50: price(65), P90: price(109), Budget: price(25), MidRange: price(65), Premium: price(105)}},
+		{"too few well reviewed mid-range places", poorlyReviewedMiddle,
+			PriceStats{P10: price(21), P50: price(65), P90: price(109), Budget: price(25), Premium: price(105)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarise(tt.properties); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if twelve[0].Price != 120 {
+		t.Errorf("summarise sorted the places it was given")
+	}
+}
+
+// TestTypicalTierRatios gives cities without their own tier prices the median ratio of
+// the cities that have them, leaving out cities without a booking_pppn
+func TestTypicalTierRatios(t *testing.T) {
+	cities := map[string]PriceStats{
+		"Berlin|Germany":    {Standard: 100, Budget: price(60), MidRange: price(100), Premium: price(200)},
+		"Hamburg|Germany":   {Standard: 100, Budget: price(70), MidRange: price(120)},
+		"Munich|Germany":    {Standard: 200, Budget: price(100), MidRange: price(220)},
+		"Nowhere|Germany":   {Budget: price(10), Premium: price(900)},
+		"Unpriced|Germany":  {Standard: 80},
+		"Cologne|Germany":   {Standard: 100},
+		"Dortmund|Germany":  {Standard: 50, Budget: price(40)},
+		"Leipzig|Germany":   {Standard: 100, Budget: price(65)},
+		"Dresden|Germany":   {Standard: 100, MidRange: price(110)},
+		"Frankfurt|Germany": {Standard: 100, Premium: price(300)},
+	}
+
+	ratios := typicalTierRatios(cities)
+
+	// Budget 0.5, 0.6, 0.65, 0.7, 0.8; mid-range 1, 1.1, 1.1, 1.2; premium 2, 3
+	want := TierRatios{Budget: price(0.65), MidRange: price(1.1), Premium: price(2.5)}
+	if !reflect.DeepEqual(ratios, want) {
+		t.Errorf("got %+v, want %+v", ratios, want)
+	}
+
+	if got := ratios.scale(ratios.Premium, 40); got != price(100) {
+		t.Errorf("premium of an estimated 40 is %+v, want 100", got)
+	}
+	if got := (TierRatios{}).scale(sql.NullFloat64{}, 40); got.Valid {
+		t.Errorf("a tier no city has priced scaled to %v, want NULL", got.Float64)
+	}
+	if got := typicalTierRatios(nil); got != (TierRatios{}) {
+		t.Errorf("ratios of no cities %+v, want none", got)
+	}
+}
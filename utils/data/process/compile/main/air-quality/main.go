@@ -0,0 +1,210 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+	// Destination zones must resolve even on servers without system zoneinfo
+	_ "time/tzdata"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// reading is one hourly air quality forecast from all_air_quality
+type reading struct {
+	City     string
+	Country  string
+	Timezone string
+	Time     time.Time // UTC
+	AQI      int
+	PM25     sql.NullFloat64
+	PM10     sql.NullFloat64
+}
+
+// CompiledAirQuality is a city's air quality on one local day
+type CompiledAirQuality struct {
+	City    string
+	Country string
+	Date    string
+	AQI     int // worst hour of the day, 1 Good to 5 Very Poor
+	PM25    sql.NullFloat64
+	PM10    sql.NullFloat64
+}
+
+// loadReadings reads the latest forecast of every airport and hour
+func loadReadings(db *sql.DB) ([]reading, error) {
+	rows, err := db.Query(`
+	SELECT city_name, country_code, COALESCE(timezone, 'UTC'), date, aqi, pm2_5, pm10
+	FROM (
+		SELECT *, ROW_NUMBER() OVER (PARTITION BY iata, date ORDER BY fetched_at DESC) AS fetch_rank
+		FROM all_air_quality
+	)
+	WHERE fetch_rank = 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []reading
+	for rows.Next() {
+		var r reading
+		var date string
+		if err := rows.Scan(&r.City, &r.Country, &r.Timezone, &date, &r.AQI, &r.PM25, &r.PM10); err != nil {
+			return nil, err
+		}
+		r.Time, err = time.Parse("2006-01-02 15:04:05", date)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected date %q for %s: %v", date, r.City, err)
+		}
+		readings = append(readings, r)
+	}
+	return readings, rows.Err()
+}
+
+// mean averages the valid values added to it; NULL if there are none
+type mean struct {
+	sum   float64
+	count int
+}
+
+func (m *mean) add(value sql.NullFloat64) {
+	if value.Valid {
+		m.sum += value.Float64
+		m.count++
+	}
+}
+
+func (m mean) value() sql.NullFloat64 {
+	if m.count == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: math.Round(m.sum/float64(m.count)*10) / 10, Valid: true}
+}
+
+// dailyAirQuality groups the readings by city and local day. A day is as bad as its
+// worst hour at any of the city's airports, since that is what a sensitive visitor notices.
+func dailyAirQuality(readings []reading) []CompiledAirQuality {
+	type dayKey struct{ city, country, date string }
+	type day struct {
+		aqi        int
+		pm25, pm10 mean
+	}
+
+	zones := make(map[string]*time.Location)
+	days := make(map[dayKey]*day)
+	var keys []dayKey
+	for _, r := range readings {
+		loc, ok := zones[r.Timezone]
+		if !ok {
+			var err error
+			loc, err = time.LoadLocation(r.Timezone)
+			if err != nil {
+				log.Printf("Unknown time zone %q, using UTC: %v", r.Timezone, err)
+				loc = time.UTC
+			}
+			zones[r.Timezone] = loc
+		}
+
+		key := dayKey{r.City, r.Country, r.Time.In(loc).Format("2006-01-02")}
+		d, ok := days[key]
+		if !ok {
+			d = &day{}
+			days[key] = d
+			keys = append(keys, key)
+		}
+		if r.AQI > d.aqi {
+			d.aqi = r.AQI
+		}
+		d.pm25.add(r.PM25)
+		d.pm10.add(r.PM10)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].city != keys[j].city {
+			return keys[i].city < keys[j].city
+		}
+		if keys[i].country != keys[j].country {
+			return keys[i].country < keys[j].country
+		}
+		return keys[i].date < keys[j].date
+	})
+
+	compiled := make([]CompiledAirQuality, 0, len(keys))
+	for _, key := range keys {
+		d := days[key]
+		compiled = append(compiled, CompiledAirQuality{
+			City:    key.city,
+			Country: key.country,
+			Date:    key.date,
+			AQI:     d.aqi,
+			PM25:    d.pm25.value(),
+			PM10:    d.pm10.value(),
+		})
+	}
+	return compiled
+}
+
+func main() {
+	db, err := sql.Open("sqlite3", "../../../../../../data/raw/air-quality/air-quality.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	readings, err := loadReadings(db)
+	if err != nil {
+		log.Fatal("Failed to read air quality forecasts:", err)
+	}
+	days := dailyAirQuality(readings)
+
+	compiledDB, err := sql.Open("sqlite3", "../../../../../../data/compiled/new_main.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer compiledDB.Close()
+
+	// Older databases were created before air quality was compiled
+	_, err = compiledDB.Exec(`CREATE TABLE IF NOT EXISTS air_quality (
+		city VARCHAR(255),
+		country VARCHAR(255),
+		date DATE,
+		aqi INTEGER,
+		pm2_5 FLOAT(10,2),
+		pm10 FLOAT(10,2),
+		PRIMARY KEY (city, country, date)
+	)`)
+	if err != nil {
+		log.Fatal("Failed to create air_quality table:", err)
+	}
+
+	tx, err := compiledDB.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM air_quality"); err != nil {
+		log.Fatal("Failed to clear existing air quality data:", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO air_quality (city, country, date, aqi, pm2_5, pm10) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stmt.Close()
+
+	for _, d := range days {
+		if _, err := stmt.Exec(d.City, d.Country, d.Date, d.AQI, d.PM25, d.PM10); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Compiled %d days of air quality into new_main.db\n", len(days))
+}
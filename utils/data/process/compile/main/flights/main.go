@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
+	"compile-main-db/dbschema"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/schollz/progressbar/v3"
 )
@@ -25,7 +27,9 @@ type Prediction struct {
 	MostCommonAircraft    string
 	SeatingCapacity       int
 	DurationHourDotMins   string
-	PredictedPrice        float64
+	PredictedPrice        sql.NullFloat64
+	PriceLow              sql.NullFloat64 // The prediction interval, NULL for models without one
+	PriceHigh             sql.NullFloat64
 }
 
 // SkyScannerPrice represents one row from the skyscannerprices table.
@@ -42,6 +46,60 @@ type SkyScannerPrice struct {
 	NextWeekend             sql.NullFloat64
 	SkyScannerURL           string
 	SkyscannerDuration      sql.NullInt64
+	Outbound                Leg
+	Return                  Leg
+	FetchedAt               sql.NullString
+}
+
+// Where a flight's price comes from. Observed prices older than staleObservationAge, or of
+// unknown age, are stale: the dates searched have likely moved on since.
+const (
+	priceSourcePredicted     = "predicted"
+	priceSourceObserved      = "observed"
+	priceSourceStaleObserved = "stale-observed"
+)
+
+const staleObservationAge = 7 * 24 * time.Hour
+
+// fetchedAtLayout is how the fetch stage writes fetched_at, always in UTC
+const fetchedAtLayout = "2006-01-02 15:04:05"
+
+// observedPriceSource tells fresh observations from stale ones
+func observedPriceSource(fetchedAt sql.NullString, now time.Time) string {
+	if !fetchedAt.Valid {
+		return priceSourceStaleObserved
+	}
+	observed, err := time.Parse(fetchedAtLayout, fetchedAt.String)
+	if err != nil || now.Sub(observed) > staleObservationAge {
+		return priceSourceStaleObserved
+	}
+	return priceSourceObserved
+}
+
+// Leg is the cheapest day found to fly one way, its price and its duration in minutes
+type Leg struct {
+	Date         sql.NullString
+	Price        sql.NullFloat64
+	DurationMins sql.NullInt64
+}
+
+// Columns added to flight after new_main.db was first created; it is only rebuilt from scratch weekly
+var flightMigrations = []dbschema.Column{
+	{Name: "outbound_date", Definition: "DATE"},
+	{Name: "outbound_price", Definition: "DECIMAL"},
+	{Name: "outbound_duration_mins", Definition: "INTEGER"},
+	{Name: "return_date", Definition: "DATE"},
+	{Name: "return_price", Definition: "DECIMAL"},
+	{Name: "return_duration_mins", Definition: "INTEGER"},
+	{Name: "price_source", Definition: "TEXT DEFAULT 'predicted'"},
+	{Name: "price_observed_at", Definition: "TEXT"},
+	{Name: "price_low", Definition: "DECIMAL"},
+	{Name: "price_high", Definition: "DECIMAL"},
+}
+
+// ensureFlightColumns adds any missing flightMigrations columns to new_main.db
+func ensureFlightColumns(db *sql.DB) error {
+	return dbschema.EnsureColumns(db, "flight", flightMigrations)
 }
 
 // buildSkyScannerURL builds a URL based on origin and destination IATA codes.
@@ -61,10 +119,21 @@ func main() {
 	}
 	defer predDB.Close()
 
+	// flight-prices.db has no intervals until the predictions have been generated since they were added
+	predictionColumns, err := dbschema.Columns(predDB, "prediction")
+	if err != nil {
+		log.Fatal("Error reading prediction columns: ", err)
+	}
+	intervalColumns := "price_low, price_high"
+	if !predictionColumns["price_low"] {
+		intervalColumns = "NULL, NULL"
+	}
+
+	// Predictions rejected by the boundary rules were stored as 0 before they were NULL
 	predRows, err := predDB.Query(`SELECT origin_city_name, origin_country, origin_iata,
 		origin_population, destination_city_name, destination_country, destination_iata, destination_population,
 		route_frequency, route_classification, most_common_airline, most_common_aircraft,
-		most_common_aircraft_seating_capacity, duration_hour_dot_mins, predicted_price
+		most_common_aircraft_seating_capacity, duration_hour_dot_mins, NULLIF(predicted_price, 0), ` + intervalColumns + `
 		FROM prediction;`)
 	if err != nil {
 		log.Fatal("Error querying prediction table: ", err)
@@ -77,7 +146,7 @@ func main() {
 		err = predRows.Scan(&p.OriginCity, &p.OriginCountry, &p.OriginIATA, &p.OriginPopulation,
 			&p.DestinationCity, &p.DestinationCountry, &p.DestinationIATA, &p.DestinationPopulation,
 			&p.RouteFrequency, &p.RouteClassification, &p.MostCommonAirline, &p.MostCommonAircraft,
-			&p.SeatingCapacity, &p.DurationHourDotMins, &p.PredictedPrice)
+			&p.SeatingCapacity, &p.DurationHourDotMins, &p.PredictedPrice, &p.PriceLow, &p.PriceHigh)
 		if err != nil {
 			log.Fatal("Error scanning prediction row: ", err)
 		}
@@ -97,6 +166,10 @@ func main() {
 	}
 	defer mainDB.Close()
 
+	if err := ensureFlightColumns(mainDB); err != nil {
+		log.Fatal("Failed to migrate flight table: ", err)
+	}
+
 	// Delete existing entries from the flight table.
 	_, err = mainDB.Exec("DELETE FROM flight")
 	if err != nil {
@@ -112,8 +185,9 @@ func main() {
 		origin_city_name, origin_country, origin_iata, origin_skyscanner_id,
 		destination_city_name, destination_country, destination_iata, destination_skyscanner_id,
 		price_this_week, skyscanner_url_this_week, price_next_week, skyscanner_url_next_week,
-		duration_in_minutes, duration_in_hours, duration_in_hours_rounded, duration_hour_dot_mins
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		duration_in_minutes, duration_in_hours, duration_in_hours_rounded, duration_hour_dot_mins, price_source,
+		price_low, price_high
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	for _, p := range predictions {
 		// For each prediction, look up the extra duration fields from the routes table in flight-prices.db.
 		var durationMinutes int
@@ -148,6 +222,8 @@ func main() {
 				}
 				return ""
 			}(),
+			priceSourcePredicted,
+			p.PriceLow, p.PriceHigh,
 		)
 		if err != nil {
 			log.Fatal("Error inserting prediction row: ", err)
@@ -166,9 +242,25 @@ func main() {
 	}
 	defer skyscannerDB.Close()
 
+	// flights.db has no legs until the price fetcher has run since they were added
+	skyscannerColumns, err := dbschema.Columns(skyscannerDB, "skyscannerprices")
+	if err != nil {
+		log.Fatal("Error reading skyscannerprices columns: ", err)
+	}
+	legColumns := "outbound_date, NULLIF(outbound_price, 0), outbound_duration_mins, return_date, NULLIF(return_price, 0), return_duration_mins"
+	if !skyscannerColumns["outbound_price"] {
+		legColumns = "NULL, NULL, NULL, NULL, NULL, NULL"
+	}
+	fetchedAtColumn := "fetched_at"
+	if !skyscannerColumns["fetched_at"] {
+		fetchedAtColumn = "NULL"
+	}
+
+	// Routes without an itinerary were stored as 0 before the fetcher wrote NULL; NULLIF keeps
+	// an old flights.db from showing them as free
 	skyscannerRows, err := skyscannerDB.Query(`SELECT origin_city, origin_country, origin_iata, origin_skyscanner_id,
 		destination_city, destination_country, destination_iata, destination_skyscanner_id,
-		this_weekend, next_weekend
+		NULLIF(this_weekend, 0), NULLIF(next_weekend, 0), ` + legColumns + `, ` + fetchedAtColumn + `
 		FROM skyscannerprices`)
 	if err != nil {
 		log.Fatal("Error querying skyscannerprices: ", err)
@@ -180,7 +272,9 @@ func main() {
 		var sp SkyScannerPrice
 		err = skyscannerRows.Scan(&sp.OriginCity, &sp.OriginCountry, &sp.OriginIATA, &sp.OriginSkyScannerID,
 			&sp.DestinationCity, &sp.DestinationCountry, &sp.DestinationIATA, &sp.DestinationSkyScannerID,
-			&sp.ThisWeekend, &sp.NextWeekend)
+			&sp.ThisWeekend, &sp.NextWeekend,
+			&sp.Outbound.Date, &sp.Outbound.Price, &sp.Outbound.DurationMins,
+			&sp.Return.Date, &sp.Return.Price, &sp.Return.DurationMins, &sp.FetchedAt)
 		if err != nil {
 			log.Fatal("Error scanning skyscannerprices row: ", err)
 		}
@@ -193,15 +287,22 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Keep the prediction made before each price was seen next to it, for backtesting,
+	// before the price replaces the prediction
+	now := time.Now().UTC()
+	if err := logPredictionOutcomes(predDB, scannerPrices, now); err != nil {
+		log.Fatal("Error logging prediction outcomes: ", err)
+	}
+
 	// For each skyscanner entry, update the corresponding row in flight table.
-	// We match on origin_iata and destination_iata.
+	// We match on origin_iata and destination_iata. A recent search that found no itinerary
+	// leaves the price NULL, shown as unavailable, rather than keeping the prediction. A stale
+	// one says nothing about flights now, so the prediction stays. Failed searches are not
+	// stored at all. Observed prices are not estimates, so they have no interval.
 	for _, sp := range scannerPrices {
-		var priceThisWeek, priceNextWeek float64
-		if sp.ThisWeekend.Valid {
-			priceThisWeek = sp.ThisWeekend.Float64
-		}
-		if sp.NextWeekend.Valid {
-			priceNextWeek = sp.NextWeekend.Float64
+		source := observedPriceSource(sp.FetchedAt, now)
+		if !sp.NextWeekend.Valid && source != priceSourceObserved {
+			continue
 		}
 		_, err := mainDB.Exec(`UPDATE flight 
 			SET origin_skyscanner_id = ?,
@@ -209,11 +310,24 @@ func main() {
 			    price_this_week = ?,
 			    skyscanner_url_this_week = ?,
 			    price_next_week = ?,
-			    skyscanner_url_next_week = ?
+			    skyscanner_url_next_week = ?,
+			    outbound_date = ?,
+			    outbound_price = ?,
+			    outbound_duration_mins = ?,
+			    return_date = ?,
+			    return_price = ?,
+			    return_duration_mins = ?,
+			    price_source = ?,
+			    price_observed_at = ?,
+			    price_low = NULL,
+			    price_high = NULL
 			WHERE origin_iata = ? AND destination_iata = ?`,
 			sp.OriginSkyScannerID, sp.DestinationSkyScannerID,
-			priceThisWeek, sp.SkyScannerURL,
-			priceNextWeek, sp.SkyScannerURL,
+			sp.ThisWeekend, sp.SkyScannerURL,
+			sp.NextWeekend, sp.SkyScannerURL,
+			sp.Outbound.Date, sp.Outbound.Price, sp.Outbound.DurationMins,
+			sp.Return.Date, sp.Return.Price, sp.Return.DurationMins,
+			source, sp.FetchedAt,
 			sp.OriginIATA, sp.DestinationIATA)
 		if err != nil {
 			log.Printf("Error updating flight for route %s -> %s: %v", sp.OriginIATA, sp.DestinationIATA, err)
@@ -221,4 +335,100 @@ func main() {
 	}
 
 	fmt.Println("Skyscanner prices have been used to update the flight table in new_main.db.")
+
+	// -----------------------------------
+	// STEP 4: Copy the price of every upcoming date searched, for the price calendar.
+	// -----------------------------------
+	if err := compileRoutePriceByDate(skyscannerDB, mainDB); err != nil {
+		log.Fatal("Error compiling route_price_by_date: ", err)
+	}
+
+	// -----------------------------------
+	// STEP 5: Summarise how each route's price has moved, for the "cheaper than usual" badge.
+	// -----------------------------------
+	if err := compilePriceTrends(skyscannerDB, mainDB); err != nil {
+		log.Fatal("Error compiling price trends: ", err)
+	}
+}
+
+// compileRoutePriceByDate replaces route_price_by_date in new_main.db with the dates still to come.
+// Older flights.db files have no per-date prices yet; the table is then left empty.
+func compileRoutePriceByDate(skyscannerDB, mainDB *sql.DB) error {
+	_, err := mainDB.Exec(`CREATE TABLE IF NOT EXISTS route_price_by_date (
+		origin_city_name TEXT,
+		origin_country TEXT,
+		origin_iata TEXT,
+		destination_city_name TEXT,
+		destination_country TEXT,
+		destination_iata TEXT,
+		leg TEXT,
+		date DATE,
+		price DECIMAL,
+		fetched_at TEXT
+	)`)
+	if err != nil {
+		return err
+	}
+
+	exists, err := tableExists(skyscannerDB, "route_price_by_date")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		fmt.Println("No route_price_by_date in flights.db, skipping the price calendar.")
+		return nil
+	}
+
+	rows, err := skyscannerDB.Query(`SELECT origin_city, origin_country, origin_iata,
+		destination_city, destination_country, destination_iata, leg, date, price, fetched_at
+		FROM route_price_by_date
+		WHERE date >= date('now') AND price IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tx, err := mainDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM route_price_by_date"); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO route_price_by_date (
+		origin_city_name, origin_country, origin_iata, destination_city_name, destination_country, destination_iata,
+		leg, date, price, fetched_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var count int
+	for rows.Next() {
+		var originCity, originCountry, originIATA, destinationCity, destinationCountry, destinationIATA, leg, date string
+		var price float64
+		var fetchedAt sql.NullString
+		if err := rows.Scan(&originCity, &originCountry, &originIATA, &destinationCity, &destinationCountry, &destinationIATA,
+			&leg, &date, &price, &fetchedAt); err != nil {
+			return err
+		}
+		_, err := stmt.Exec(originCity, GetISOCode(originCountry), originIATA, destinationCity, GetISOCode(destinationCountry), destinationIATA,
+			leg, date, price, fetchedAt)
+		if err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Copied %d date prices into route_price_by_date of new_main.db.\n", count)
+	return nil
 }
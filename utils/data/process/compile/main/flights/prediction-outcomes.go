@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"compile-main-db/dbschema"
+)
+
+// logPredictionOutcomes records, in flight-prices.db's prediction_outcome, each fresh price
+// seen next to the last prediction for its route made before it was seen.
+// generate/flight-prices/backtest reports on them and learns correction factors from them.
+// Predictions made since are left out: the model trains on observed prices, so they have
+// already seen the answer. Each price is logged once, however often flights are compiled.
+func logPredictionOutcomes(predDB *sql.DB, scannerPrices []SkyScannerPrice, now time.Time) error {
+	exists, err := tableExists(predDB, "prediction_history")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		fmt.Println("No prediction_history in flight-prices.db, skipping prediction outcomes.")
+		return nil
+	}
+
+	_, err = predDB.Exec(`CREATE TABLE IF NOT EXISTS prediction_outcome (
+		origin_iata TEXT NOT NULL,
+		destination_iata TEXT NOT NULL,
+		origin_country TEXT,
+		destination_country TEXT,
+		route_classification TEXT,
+		most_common_airline TEXT,
+		model_version INTEGER,
+		model_price REAL,
+		correction REAL,
+		predicted_price REAL,
+		price_low REAL,
+		price_high REAL,
+		predicted_at TEXT,
+		observed_price REAL,
+		observed_at TEXT NOT NULL,
+		modifier_factor REAL,
+		PRIMARY KEY (origin_iata, destination_iata, observed_at)
+	)`)
+	if err != nil {
+		return err
+	}
+	if err := dbschema.EnsureColumns(predDB, "prediction_outcome", []dbschema.Column{{Name: "modifier_factor", Definition: "REAL"}}); err != nil {
+		return err
+	}
+	// Predictions recorded before modifiers had none applied
+	historyColumns, err := dbschema.Columns(predDB, "prediction_history")
+	if err != nil {
+		return err
+	}
+	modifierFactorColumn := "1"
+	if historyColumns["modifier_factor"] {
+		modifierFactorColumn = "COALESCE(modifier_factor, 1)"
+	}
+
+	tx, err := predDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// fetched_at and predicted_at are both UTC in the same layout, so they compare as strings
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO prediction_outcome (
+		origin_iata, destination_iata, origin_country, destination_country, route_classification,
+		most_common_airline, model_version, model_price, correction, predicted_price, price_low, price_high,
+		predicted_at, modifier_factor, observed_price, observed_at
+	)
+	SELECT origin_iata, destination_iata, origin_country, destination_country, route_classification,
+		most_common_airline, model_version, model_price, correction, predicted_price, price_low, price_high,
+		predicted_at, ` + modifierFactorColumn + `, ?, ?
+	FROM prediction_history
+	WHERE origin_iata = ? AND destination_iata = ? AND predicted_at <= ? AND predicted_price > 0
+	ORDER BY predicted_at DESC
+	LIMIT 1`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	logged := 0
+	for _, sp := range scannerPrices {
+		if !sp.NextWeekend.Valid || observedPriceSource(sp.FetchedAt, now) != priceSourceObserved {
+			continue
+		}
+		result, err := stmt.Exec(sp.NextWeekend, sp.FetchedAt, sp.OriginIATA, sp.DestinationIATA, sp.FetchedAt)
+		if err != nil {
+			return fmt.Errorf("failed to log outcome for %s -> %s: %v", sp.OriginIATA, sp.DestinationIATA, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			logged += int(n)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Logged %d new prediction outcomes for backtesting.\n", logged)
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// How far back route_price_history goes, and the windows route_price_trend summarises
+const (
+	priceHistoryDays = 90
+	shortTrendDays   = 7
+	longTrendDays    = 30
+)
+
+// A latest price this far from the 30 day median counts as a move up or down
+const trendThreshold = 0.05
+
+// RoutePricePoint is the cheapest round trip seen on one day: the cheapest outbound plus
+// the cheapest return observed that day, whichever dates they were for
+type RoutePricePoint struct {
+	Date  time.Time
+	Price float64
+}
+
+// RouteSeries is the daily price history of one route
+type RouteSeries struct {
+	OriginCity         string
+	OriginCountry      string
+	OriginIATA         string
+	DestinationCity    string
+	DestinationCountry string
+	DestinationIATA    string
+	Points             []RoutePricePoint
+}
+
+// PriceTrend summarises a RouteSeries
+type PriceTrend struct {
+	LatestDate      time.Time
+	LatestPrice     float64
+	Min7d           float64
+	Median7d        float64
+	Min30d          float64
+	Median30d       float64
+	Observations30d int
+	Direction       string // "up", "down" or "flat"
+}
+
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+// readRouteSeries reads the daily round trip price of every route from price_observation
+func readRouteSeries(skyscannerDB *sql.DB) ([]RouteSeries, error) {
+	rows, err := skyscannerDB.Query(fmt.Sprintf(`
+	SELECT origin_city, origin_country, origin_iata, destination_city, destination_country, destination_iata,
+		day, SUM(leg_price)
+	FROM (
+		SELECT origin_city, origin_country, origin_iata, destination_city, destination_country, destination_iata,
+			date(observed_at) AS day, leg, MIN(price) AS leg_price
+		FROM price_observation
+		WHERE observed_at >= date('now', '-%d days')
+		GROUP BY origin_iata, destination_iata, day, leg
+	)
+	GROUP BY origin_iata, destination_iata, day
+	HAVING COUNT(*) = 2
+	ORDER BY origin_iata, destination_iata, day`, priceHistoryDays))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var series []RouteSeries
+	for rows.Next() {
+		var s RouteSeries
+		var day string
+		var price float64
+		if err := rows.Scan(&s.OriginCity, &s.OriginCountry, &s.OriginIATA,
+			&s.DestinationCity, &s.DestinationCountry, &s.DestinationIATA, &day, &price); err != nil {
+			return nil, err
+		}
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected observation date %q: %v", day, err)
+		}
+
+		last := len(series) - 1
+		if last < 0 || series[last].OriginIATA != s.OriginIATA || series[last].DestinationIATA != s.DestinationIATA {
+			s.OriginCountry = GetISOCode(s.OriginCountry)
+			s.DestinationCountry = GetISOCode(s.DestinationCountry)
+			series = append(series, s)
+			last++
+		}
+		series[last].Points = append(series[last].Points, RoutePricePoint{Date: date, Price: price})
+	}
+	return series, rows.Err()
+}
+
+// calculatePriceTrend compares a route's latest price with the previous 7 and 30 days.
+// points must be in date order.
+func calculatePriceTrend(points []RoutePricePoint) PriceTrend {
+	latest := points[len(points)-1]
+	trend := PriceTrend{LatestDate: latest.Date, LatestPrice: latest.Price, Direction: "flat"}
+
+	var week, month []float64
+	for _, p := range points {
+		age := latest.Date.Sub(p.Date)
+		if age < shortTrendDays*24*time.Hour {
+			week = append(week, p.Price)
+		}
+		if age < longTrendDays*24*time.Hour {
+			month = append(month, p.Price)
+		}
+	}
+	trend.Min7d, trend.Median7d = minAndMedian(week)
+	trend.Min30d, trend.Median30d = minAndMedian(month)
+	trend.Observations30d = len(month)
+
+	switch {
+	case len(month) < 2:
+	case trend.LatestPrice < trend.Median30d*(1-trendThreshold):
+		trend.Direction = "down"
+	case trend.LatestPrice > trend.Median30d*(1+trendThreshold):
+		trend.Direction = "up"
+	}
+	return trend
+}
+
+func minAndMedian(prices []float64) (float64, float64) {
+	sorted := append([]float64(nil), prices...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[0], sorted[n/2]
+	}
+	return sorted[0], (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// compilePriceTrends replaces route_price_history and route_price_trend in new_main.db
+// with what price_observation shows of each route's price over time
+func compilePriceTrends(skyscannerDB, mainDB *sql.DB) error {
+	for _, schema := range []string{`CREATE TABLE IF NOT EXISTS route_price_history (
+		origin_city_name TEXT,
+		origin_country TEXT,
+		origin_iata TEXT,
+		destination_city_name TEXT,
+		destination_country TEXT,
+		destination_iata TEXT,
+		date DATE,
+		price DECIMAL
+	)`, `CREATE TABLE IF NOT EXISTS route_price_trend (
+		origin_city_name TEXT,
+		origin_country TEXT,
+		origin_iata TEXT,
+		destination_city_name TEXT,
+		destination_country TEXT,
+		destination_iata TEXT,
+		latest_date DATE,
+		latest_price DECIMAL,
+		min_7d DECIMAL,
+		median_7d DECIMAL,
+		min_30d DECIMAL,
+		median_30d DECIMAL,
+		observations_30d INTEGER,
+		direction TEXT
+	)`} {
+		if _, err := mainDB.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	exists, err := tableExists(skyscannerDB, "price_observation")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		fmt.Println("No price_observation in flights.db, skipping price trends.")
+		return nil
+	}
+
+	series, err := readRouteSeries(skyscannerDB)
+	if err != nil {
+		return err
+	}
+
+	tx, err := mainDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"route_price_history", "route_price_trend"} {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	historyStmt, err := tx.Prepare(`INSERT INTO route_price_history (
+		origin_city_name, origin_country, origin_iata, destination_city_name, destination_country, destination_iata, date, price
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer historyStmt.Close()
+	trendStmt, err := tx.Prepare(`INSERT INTO route_price_trend (
+		origin_city_name, origin_country, origin_iata, destination_city_name, destination_country, destination_iata,
+		latest_date, latest_price, min_7d, median_7d, min_30d, median_30d, observations_30d, direction
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer trendStmt.Close()
+
+	for _, s := range series {
+		for _, p := range s.Points {
+			_, err := historyStmt.Exec(s.OriginCity, s.OriginCountry, s.OriginIATA, s.DestinationCity, s.DestinationCountry, s.DestinationIATA,
+				p.Date.Format("2006-01-02"), p.Price)
+			if err != nil {
+				return err
+			}
+		}
+
+		t := calculatePriceTrend(s.Points)
+		_, err := trendStmt.Exec(s.OriginCity, s.OriginCountry, s.OriginIATA, s.DestinationCity, s.DestinationCountry, s.DestinationIATA,
+			t.LatestDate.Format("2006-01-02"), t.LatestPrice, t.Min7d, t.Median7d, t.Min30d, t.Median30d, t.Observations30d, t.Direction)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Compiled price trends of %d routes into new_main.db.\n", len(series))
+	return nil
+}
@@ -40,7 +40,9 @@ func UpdateAvgWPI(db *sql.DB) {
 	// Loop over each city-country pair to calculate and update avg_wpi
 	for _, pair := range cityCountryPairs {
 		var avgResult sql.NullFloat64 // Use sql.NullFloat64 to handle NULL values
-		err = db.QueryRow("SELECT AVG(avg_daytime_wpi) FROM weather WHERE LOWER(city) = LOWER(?) AND LOWER(country) = LOWER(?)", pair.city, pair.country).Scan(&avgResult)
+		// Days forecast with little confidence count for as little as half as much
+		err = db.QueryRow(`SELECT SUM(avg_daytime_wpi * (0.5 + 0.5 * COALESCE(forecast_confidence, 1))) / NULLIF(SUM(0.5 + 0.5 * COALESCE(forecast_confidence, 1)), 0)
+			FROM weather WHERE LOWER(city) = LOWER(?) AND LOWER(country) = LOWER(?) AND source = 'forecast' AND avg_daytime_wpi IS NOT NULL`, pair.city, pair.country).Scan(&avgResult)
 		if err != nil {
 			fmt.Printf("Failed to calculate average WPI for %s, %s: %v\n", pair.city, pair.country, err)
 			bar.Add(1) // Increment the progress bar even in case of an error
@@ -319,6 +319,8 @@ func main() {
 					log.Println("%sCOMPLETED: prices (flight prices)%s\n", green, reset)
 					runExecutableInDir(filepath.Join(absoluteBase, "fetch/weather"), "update-weather-db")
 					log.Println("%sCOMPLETED: update-weather-db (weather update)%s\n", green, reset)
+					runExecutableInDir(filepath.Join(absoluteBase, "fetch/air-quality"), "update-air-quality-db")
+					log.Printf("%sCOMPLETED: update-air-quality-db (air quality update)%s\n", green, reset)
 
 					// Temporarily paused due to high API cost. Existing values of accomodation raw db
 					// are used by by the locations compiler stage instead
@@ -336,6 +338,10 @@ func main() {
 					log.Println("%sCOMPLETED: flights (process compile)%s\n", green, reset)
 					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/weather"), "weather")
 					log.Println("%sCOMPLETED: process/compile/main/weather%s\n", green, reset)
+					runExecutableInDir(filepath.Join(absoluteBase, "process/calculate/daylight"), "daylight")
+					log.Printf("%sCOMPLETED: process/calculate/daylight%s\n", green, reset)
+					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/air-quality"), "air-quality")
+					log.Printf("%sCOMPLETED: process/compile/main/air-quality%s\n", green, reset)
 
 					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/locations"), "locations")
 					log.Println("%sCOMPLETED: process/compile/main/locations%s\n", green, reset)
@@ -347,6 +353,10 @@ func main() {
 					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/locations/location-images"), "location-images")
 					log.Println("%sCOMPLETED: process/compile/main/locations%s\n", green, reset)
 
+					// Estimates for cities without a fresh booking.com price, while fetching is paused
+					runExecutableInDir(filepath.Join(absoluteBase, "process/generate/accommodation-prices"), "accommodation-prices")
+					log.Printf("%sCOMPLETED: accommodation-prices (generate accommodation prices)%s\n", green, reset)
+
 					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/accommodation/booking-com"), "booking-com")
 					log.Println("%sCOMPLETED:  process/compile/main/accommodation/booking-com%s\n", green, reset)
 					// 5 nights and flights
@@ -359,6 +369,8 @@ func main() {
 
 					runExecutableInDir(filepath.Join(absoluteBase, "fetch/weather"), "update-weather-db")
 					log.Println("%sCOMPLETED: update-weather-db (weather update)%s\n", green, reset)
+					runExecutableInDir(filepath.Join(absoluteBase, "fetch/air-quality"), "update-air-quality-db")
+					log.Printf("%sCOMPLETED: update-air-quality-db (air quality update)%s\n", green, reset)
 
 					// Run weather calculation after weather update completes
 					runExecutableInDir(filepath.Join(absoluteBase, "process/calculate/weather"), "weather")
@@ -367,6 +379,10 @@ func main() {
 					// Run process/compile/main/weather after calculation
 					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/weather"), "weather")
 					log.Println("%sCOMPLETED: process/compile/main/weather%s\n", green, reset)
+					runExecutableInDir(filepath.Join(absoluteBase, "process/calculate/daylight"), "daylight")
+					log.Printf("%sCOMPLETED: process/calculate/daylight%s\n", green, reset)
+					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/air-quality"), "air-quality")
+					log.Printf("%sCOMPLETED: process/compile/main/air-quality%s\n", green, reset)
 
 					// Calcualte and Compile WPI for Locations
 					runExecutableInDir(filepath.Join(absoluteBase, "process/compile/main/locations"), "locations")
@@ -18,6 +18,8 @@ func runAllTasks(relativeBase string) {
 	fmt.Printf("%sCOMPLETED: prices (flight prices)%s\n", green, reset)
 	runExecutableInDir(filepath.Join(relativeBase, "fetch/weather"), "update-weather-db")
 	fmt.Printf("%sCOMPLETED: update-weather-db (weather update)%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "fetch/air-quality"), "update-air-quality-db")
+	fmt.Printf("%sCOMPLETED: update-air-quality-db (air quality update)%s\n", green, reset)
 	runExecutableInDir(filepath.Join(relativeBase, "fetch/accommocation/booking-com/get-properties"), "get-properties")
 	fmt.Printf("%sCOMPLETED: get-properties (properties update)%s\n", green, reset)
 
@@ -30,10 +32,17 @@ func runAllTasks(relativeBase string) {
 	fmt.Printf("%sCOMPLETED: flights (process compile)%s\n", green, reset)
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/weather"), "weather")
 	fmt.Printf("%sCOMPLETED: process/compile/main/weather%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "process/calculate/daylight"), "daylight")
+	fmt.Printf("%sCOMPLETED: process/calculate/daylight%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/air-quality"), "air-quality")
+	fmt.Printf("%sCOMPLETED: process/compile/main/air-quality%s\n", green, reset)
 
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/locations"), "locations")
 	fmt.Printf("%sCOMPLETED: process/compile/main/locations%s\n", green, reset)
 
+	runExecutableInDir(filepath.Join(relativeBase, "process/generate/accommodation-prices"), "accommodation-prices")
+	fmt.Printf("%sCOMPLETED: accommodation-prices (generate accommodation prices)%s\n", green, reset)
+
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/accommodation/booking-com"), "booking-com")
 	fmt.Printf("%sCOMPLETED:  process/compile/main/accommodation/booking-com%s\n", green, reset)
 
@@ -56,10 +65,17 @@ func runCompileTasks(relativeBase string) {
 
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/weather"), "weather")
 	fmt.Printf("%sCOMPLETED: process/compile/main/weather%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "process/calculate/daylight"), "daylight")
+	fmt.Printf("%sCOMPLETED: process/calculate/daylight%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/air-quality"), "air-quality")
+	fmt.Printf("%sCOMPLETED: process/compile/main/air-quality%s\n", green, reset)
 
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/locations"), "locations")
 	fmt.Printf("%sCOMPLETED: process/compile/main/locations%s\n", green, reset)
 
+	runExecutableInDir(filepath.Join(relativeBase, "process/generate/accommodation-prices"), "accommodation-prices")
+	fmt.Printf("%sCOMPLETED: accommodation-prices (generate accommodation prices)%s\n", green, reset)
+
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/accommodation/booking-com"), "booking-com")
 	fmt.Printf("%sCOMPLETED:  process/compile/main/accommodation/booking-com%s\n", green, reset)
 
@@ -76,6 +92,8 @@ func runWeatherTasks(relativeBase string) {
 
 	runExecutableInDir(filepath.Join(relativeBase, "fetch/weather"), "update-weather-db")
 	fmt.Printf("%sCOMPLETED: update-weather-db (weather update)%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "fetch/air-quality"), "update-air-quality-db")
+	fmt.Printf("%sCOMPLETED: update-air-quality-db (air quality update)%s\n", green, reset)
 
 	runExecutableInDir(filepath.Join(relativeBase, "process/calculate/weather"), "weather")
 	fmt.Printf("%sCOMPLETED: weather (weather calculation)%s\n", green, reset)
@@ -85,6 +103,10 @@ func runWeatherTasks(relativeBase string) {
 
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/weather"), "weather")
 	fmt.Printf("%sCOMPLETED: process/compile/main/weather%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "process/calculate/daylight"), "daylight")
+	fmt.Printf("%sCOMPLETED: process/calculate/daylight%s\n", green, reset)
+	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/air-quality"), "air-quality")
+	fmt.Printf("%sCOMPLETED: process/compile/main/air-quality%s\n", green, reset)
 	//included beacuse we always create a new completely new main.db, so need to rebuild the locations table
 	runExecutableInDir(filepath.Join(relativeBase, "process/compile/main/locations"), "locations")
 	fmt.Printf("%sCOMPLETED: process/compile/main/locations%s\n", green, reset)
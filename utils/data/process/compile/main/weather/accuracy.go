@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// addForecastConfidence sets each forecast day's ForecastConfidence from how accurate
+// forecasts for that city have been at the same lead time, falling back to the
+// accuracy over all cities. Days without enough history stay NULL.
+func addForecastConfidence(db *sql.DB, weathers []CompiledWeather, now time.Time) error {
+	var tableCount int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'forecast_accuracy'`).Scan(&tableCount)
+	if err != nil {
+		return err
+	}
+	if tableCount == 0 {
+		fmt.Println("No forecast_accuracy table, compiling without forecast confidence")
+		return nil
+	}
+
+	rows, err := db.Query(`SELECT city_name, country_code, lead_days, confidence FROM forecast_accuracy WHERE confidence IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type key struct {
+		city, country string
+		leadDays      int
+	}
+	confidence := make(map[key]float64)
+	for rows.Next() {
+		var k key
+		var c float64
+		if err := rows.Scan(&k.city, &k.country, &k.leadDays, &c); err != nil {
+			return err
+		}
+		confidence[k] = c
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for i := range weathers {
+		w := &weathers[i]
+		if w.Source != "forecast" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", w.Date)
+		if err != nil {
+			continue
+		}
+		leadDays := int(date.Sub(today).Hours() / 24)
+		if leadDays < 0 {
+			leadDays = 0
+		}
+
+		c, ok := confidence[key{w.City, w.Country, leadDays}]
+		if !ok {
+			c, ok = confidence[key{"", "", leadDays}]
+		}
+		if ok {
+			w.ForecastConfidence = sql.NullFloat64{Float64: math.Round(c*100) / 100, Valid: true}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"compile-main-db/dbschema"
+)
+
+type ClimateNormal struct {
+	MeanTemp      float64
+	RainDays      float64
+	SunshineHours float64
+	MeanWindSpeed sql.NullFloat64
+	WPI           float64
+	Scores        ComponentScores
+}
+
+// climateFallback fills the days between the end of each city's forecast and
+// the climate horizon with that month's climate normals
+func climateFallback(db *sql.DB, forecast []CompiledWeather, now time.Time, days int) ([]CompiledWeather, error) {
+	var tableCount int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'climate_normals'`).Scan(&tableCount)
+	if err != nil {
+		return nil, err
+	}
+	if tableCount == 0 {
+		fmt.Println("No climate_normals table, compiling the forecast only")
+		return nil, nil
+	}
+
+	// Rows without a wpi have not been through process/calculate/weather yet
+	rows, err := db.Query(`SELECT city_name, country_code, month, mean_temp, rain_days, sunshine_hours, mean_wind_speed, wpi,
+		temperature_score, wind_score, condition_score, precipitation_score, cloud_cover_score
+		FROM climate_normals WHERE wpi IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type cityKey struct{ city, country string }
+	normals := make(map[cityKey]map[time.Month]ClimateNormal)
+	var cities []cityKey
+	for rows.Next() {
+		var key cityKey
+		var month int
+		var n ClimateNormal
+		if err := rows.Scan(&key.city, &key.country, &month, &n.MeanTemp, &n.RainDays, &n.SunshineHours, &n.MeanWindSpeed, &n.WPI,
+			&n.Scores.Temperature, &n.Scores.Wind, &n.Scores.Condition, &n.Scores.Precipitation, &n.Scores.CloudCover); err != nil {
+			return nil, err
+		}
+		if normals[key] == nil {
+			normals[key] = make(map[time.Month]ClimateNormal)
+			cities = append(cities, key)
+		}
+		normals[key][time.Month(month)] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lastForecast := make(map[cityKey]string)
+	googleURLs := make(map[cityKey]string)
+	for _, w := range forecast {
+		key := cityKey{w.City, w.Country}
+		if w.Date > lastForecast[key] {
+			lastForecast[key] = w.Date
+		}
+		googleURLs[key] = w.GoogleURL
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	horizon := today.AddDate(0, 0, days)
+
+	var result []CompiledWeather
+	for _, key := range cities {
+		start := today
+		if last, ok := lastForecast[key]; ok {
+			if lastDate, err := time.Parse("2006-01-02", last); err == nil && !lastDate.Before(start) {
+				start = lastDate.AddDate(0, 0, 1)
+			}
+		}
+
+		googleURL, ok := googleURLs[key]
+		if !ok {
+			googleURL = fmt.Sprintf("https://www.google.com/search?q=weather+%s", url.QueryEscape(fmt.Sprintf("%s, %s", key.city, key.country)))
+		}
+
+		for day := start; day.Before(horizon); day = day.AddDate(0, 0, 1) {
+			n, ok := normals[key][day.Month()]
+			if !ok {
+				continue
+			}
+			rainShare, sunShare := climateShares(n, day)
+			condition, icon := climateCondition(rainShare, sunShare)
+			result = append(result, CompiledWeather{
+				City:                     key.city,
+				Country:                  key.country,
+				Date:                     day.Format("2006-01-02"),
+				AvgDaytimeTemp:           math.Round(n.MeanTemp*10) / 10,
+				WeatherIcon:              fmt.Sprintf("https://openweathermap.org/img/wn/%s.png", icon),
+				GoogleURL:                googleURL,
+				AvgDaytimeWPI:            math.Round(n.WPI*10) / 10,
+				Source:                   "climate",
+				AvgWindSpeed:             roundNull(n.MeanWindSpeed),
+				PrecipitationProbability: sql.NullFloat64{Float64: math.Round(rainShare * 100), Valid: true},
+				CloudCover:               sql.NullFloat64{Float64: math.Round((1 - sunShare) * 100), Valid: true},
+				Condition:                condition,
+				Scores:                   n.Scores.rounded(),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// climateShares returns the share of rainy days in the month and the share of daylight that is sunny,
+// the same way process/calculate/weather does
+func climateShares(n ClimateNormal, day time.Time) (float64, float64) {
+	daysInMonth := float64(time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day())
+	rainShare := math.Min(math.Max(n.RainDays/daysInMonth, 0), 1)
+	sunShare := math.Min(math.Max(n.SunshineHours/daysInMonth/12, 0), 1)
+	return rainShare, sunShare
+}
+
+// climateCondition picks the condition and icon of a typical day of the month: rain
+// if it rains on most days, sun if most daylight hours are sunny, otherwise a few clouds
+func climateCondition(rainShare, sunShare float64) (string, string) {
+	if rainShare > 0.5 {
+		return "Rain", "10d"
+	}
+	if sunShare > 0.5 {
+		return "Clear", "01d"
+	}
+	return "Clouds", "02d"
+}
+
+// Columns added to weather after new_main.db was first created; it is only rebuilt from scratch weekly
+var weatherMigrations = []dbschema.Column{
+	{Name: "source", Definition: "VARCHAR(16) DEFAULT 'forecast'"},
+	{Name: "avg_wind_speed", Definition: "FLOAT(10,1)"},
+	{Name: "avg_precipitation_probability", Definition: "FLOAT(10,1)"},
+	{Name: "avg_humidity", Definition: "FLOAT(10,1)"},
+	{Name: "avg_cloud_cover", Definition: "FLOAT(10,1)"},
+	{Name: "weather_condition", Definition: "VARCHAR(32)"},
+	{Name: "temperature_score", Definition: "FLOAT(10,1)"},
+	{Name: "wind_score", Definition: "FLOAT(10,1)"},
+	{Name: "condition_score", Definition: "FLOAT(10,1)"},
+	{Name: "precipitation_score", Definition: "FLOAT(10,1)"},
+	{Name: "humidity_score", Definition: "FLOAT(10,1)"},
+	{Name: "cloud_cover_score", Definition: "FLOAT(10,1)"},
+	{Name: "forecast_confidence", Definition: "FLOAT(10,2)"},
+	{Name: "sunrise", Definition: "VARCHAR(5)"},
+	{Name: "sunset", Definition: "VARCHAR(5)"},
+	{Name: "daylight_hours", Definition: "FLOAT(10,1)"},
+}
+
+// ensureWeatherColumns adds any missing weatherMigrations columns to new_main.db
+func ensureWeatherColumns(db *sql.DB) error {
+	return dbschema.EnsureColumns(db, "weather", weatherMigrations)
+}
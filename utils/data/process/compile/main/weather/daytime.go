@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+	// Destination zones must resolve even on servers without system zoneinfo
+	_ "time/tzdata"
+)
+
+// Readings between these local times make up a day's weather, inclusive
+const (
+	daytimeStart = "10:00:00"
+	daytimeEnd   = "18:00:00"
+)
+
+// reading is one forecast timestamp from current_weather
+type reading struct {
+	WeatherData
+	Timezone  string
+	Condition string
+	Time      time.Time // UTC
+}
+
+// loadReadings reads every forecast reading from current_weather
+func loadReadings(db *sql.DB) ([]reading, error) {
+	rows, err := db.Query(`
+	SELECT city_name, country_code, date, COALESCE(timezone, 'UTC'), weather_type, temperature, wind_speed, precipitation_probability, humidity, cloud_cover, wpi,
+		temperature_score, wind_score, condition_score, precipitation_score, humidity_score, cloud_cover_score,
+		weather_icon_url, google_weather_link
+	FROM current_weather
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []reading
+	for rows.Next() {
+		var r reading
+		err := rows.Scan(&r.CityName, &r.CountryCode, &r.Date, &r.Timezone, &r.Condition, &r.Temperature, &r.WindSpeed, &r.PrecipitationProbability, &r.Humidity, &r.CloudCover, &r.WPI,
+			&r.Scores.Temperature, &r.Scores.Wind, &r.Scores.Condition, &r.Scores.Precipitation, &r.Scores.Humidity, &r.Scores.CloudCover,
+			&r.WeatherIconURL, &r.GoogleWeatherLink)
+		if err != nil {
+			return nil, err
+		}
+		r.Time, err = time.Parse("2006-01-02 15:04:05", r.Date)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected date %q for %s: %v", r.Date, r.CityName, err)
+		}
+		readings = append(readings, r)
+	}
+	return readings, rows.Err()
+}
+
+// locations caches time zones by name. Unknown zones fall back to UTC with a warning,
+// so one bad tz in the airport table doesn't stop the compile.
+type locations map[string]*time.Location
+
+func (l locations) get(name string) *time.Location {
+	if loc, ok := l[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Unknown time zone %q, using UTC: %v", name, err)
+		loc = time.UTC
+	}
+	l[name] = loc
+	return loc
+}
+
+// mean averages the valid values added to it; NULL if there are none
+type mean struct {
+	sum   float64
+	count int
+}
+
+func (m *mean) add(value sql.NullFloat64) {
+	if value.Valid {
+		m.sum += value.Float64
+		m.count++
+	}
+}
+
+func (m mean) value() sql.NullFloat64 {
+	if m.count == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: m.sum / float64(m.count), Valid: true}
+}
+
+func valid(value float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: value, Valid: true}
+}
+
+// dailyAverages averages each city's daytime readings per day. Daytime and the day
+// itself are taken in the destination's own time zone, not the server's.
+func dailyAverages(readings []reading) []CompiledWeather {
+	type dayKey struct{ city, country, date string }
+	type day struct {
+		first                                                reading
+		temp, wind, precipitation, humidity, cloudCover, wpi mean
+		tempScore, windScore, condScore, precipitationScore  mean
+		humidityScore, cloudCoverScore                       mean
+		conditionHours                                       map[string]int
+		conditionIcons                                       map[string]string
+	}
+
+	zones := make(locations)
+	days := make(map[dayKey]*day)
+	var keys []dayKey
+	for _, r := range readings {
+		local := r.Time.In(zones.get(r.Timezone))
+		if clock := local.Format("15:04:05"); clock < daytimeStart || clock > daytimeEnd {
+			continue
+		}
+
+		key := dayKey{r.CityName, r.CountryCode, local.Format("2006-01-02")}
+		d, ok := days[key]
+		if !ok {
+			d = &day{first: r, conditionHours: make(map[string]int), conditionIcons: make(map[string]string)}
+			days[key] = d
+			keys = append(keys, key)
+		}
+		d.temp.add(valid(r.Temperature))
+		d.wind.add(r.WindSpeed)
+		d.precipitation.add(r.PrecipitationProbability)
+		d.humidity.add(r.Humidity)
+		d.cloudCover.add(r.CloudCover)
+		d.wpi.add(valid(r.WPI))
+		d.tempScore.add(r.Scores.Temperature)
+		d.windScore.add(r.Scores.Wind)
+		d.condScore.add(r.Scores.Condition)
+		d.precipitationScore.add(r.Scores.Precipitation)
+		d.humidityScore.add(r.Scores.Humidity)
+		d.cloudCoverScore.add(r.Scores.CloudCover)
+		d.conditionHours[r.Condition]++
+		if _, ok := d.conditionIcons[r.Condition]; !ok {
+			d.conditionIcons[r.Condition] = r.WeatherIconURL
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].city != keys[j].city {
+			return keys[i].city < keys[j].city
+		}
+		if keys[i].country != keys[j].country {
+			return keys[i].country < keys[j].country
+		}
+		return keys[i].date < keys[j].date
+	})
+
+	weathers := make([]CompiledWeather, 0, len(keys))
+	for _, key := range keys {
+		d := days[key]
+		condition := dominantCondition(d.conditionHours)
+		weathers = append(weathers, CompiledWeather{
+			City:                     key.city,
+			Country:                  key.country,
+			Date:                     key.date,
+			AvgDaytimeTemp:           math.Round(d.temp.value().Float64*10) / 10,
+			WeatherIcon:              fixWeatherIconURL(d.conditionIcons[condition]),
+			GoogleURL:                d.first.GoogleWeatherLink,
+			AvgDaytimeWPI:            math.Round(d.wpi.value().Float64*10) / 10,
+			Source:                   "forecast",
+			AvgWindSpeed:             roundNull(d.wind.value()),
+			PrecipitationProbability: roundNull(d.precipitation.value()),
+			Humidity:                 roundNull(d.humidity.value()),
+			CloudCover:               roundNull(d.cloudCover.value()),
+			Condition:                condition,
+			Scores: ComponentScores{
+				Temperature:   d.tempScore.value(),
+				Wind:          d.windScore.value(),
+				Condition:     d.condScore.value(),
+				Precipitation: d.precipitationScore.value(),
+				Humidity:      d.humidityScore.value(),
+				CloudCover:    d.cloudCoverScore.value(),
+			}.rounded(),
+		})
+	}
+	return weathers
+}
+
+// dominantCondition is the most common condition of the day, alphabetically first on a tie
+func dominantCondition(hours map[string]int) string {
+	best, bestHours := "", 0
+	for condition, count := range hours {
+		if count > bestHours || (count == bestHours && condition < best) {
+			best, bestHours = condition, count
+		}
+	}
+	return best
+}
@@ -2,22 +2,51 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/schollz/progressbar/v3"
 	"log"
-	"strconv"
+	"math"
 	"strings"
+	"time"
 )
 
 type WeatherData struct {
-	CityName          string
-	CountryCode       string
-	Date              string
-	Temperature       float64
-	WPI               float64
-	WeatherIconURL    string
-	GoogleWeatherLink string
+	CityName                 string
+	CountryCode              string
+	Date                     string // UTC
+	Temperature              float64
+	WindSpeed                sql.NullFloat64
+	PrecipitationProbability sql.NullFloat64
+	Humidity                 sql.NullFloat64
+	CloudCover               sql.NullFloat64
+	WPI                      float64
+	Scores                   ComponentScores
+	WeatherIconURL           string
+	GoogleWeatherLink        string
+}
+
+// ComponentScores are the 0 to 10 scores that process/calculate/weather weighted into the WPI.
+// Components the default profile doesn't score are NULL.
+type ComponentScores struct {
+	Temperature   sql.NullFloat64
+	Wind          sql.NullFloat64
+	Condition     sql.NullFloat64
+	Precipitation sql.NullFloat64
+	Humidity      sql.NullFloat64
+	CloudCover    sql.NullFloat64
+}
+
+func (s ComponentScores) rounded() ComponentScores {
+	return ComponentScores{
+		Temperature:   roundNull(s.Temperature),
+		Wind:          roundNull(s.Wind),
+		Condition:     roundNull(s.Condition),
+		Precipitation: roundNull(s.Precipitation),
+		Humidity:      roundNull(s.Humidity),
+		CloudCover:    roundNull(s.CloudCover),
+	}
 }
 
 type CompiledWeather struct {
@@ -28,6 +57,17 @@ type CompiledWeather struct {
 	WeatherIcon    string
 	GoogleURL      string
 	AvgDaytimeWPI  float64
+	Source         string // "forecast" or "climate"
+	// Raw daytime components, so the website can score the day with a personal weather profile
+	AvgWindSpeed             sql.NullFloat64
+	PrecipitationProbability sql.NullFloat64
+	Humidity                 sql.NullFloat64
+	CloudCover               sql.NullFloat64
+	Condition                string
+	// How the default profile scored each component, to explain AvgDaytimeWPI
+	Scores ComponentScores
+	// 0 to 1, how accurate forecasts this far ahead have been for the city
+	ForecastConfidence sql.NullFloat64
 }
 
 func fixWeatherIconURL(url string) string {
@@ -38,57 +78,48 @@ func fixWeatherIconURL(url string) string {
 }
 
 func main() {
+	climateDays := flag.Int("climate-days", 28, "Fill days after the forecast up to this many days ahead with climate normals")
+	flag.Parse()
+
 	db, err := sql.Open("sqlite3", "../../../../../../data/raw/weather/weather.db")
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
-	query := `
-	SELECT city_name, country_code, date, AVG(temperature) AS avg_temp, AVG(wpi) AS avg_wpi, weather_icon_url, google_weather_link
-	FROM current_weather
-	WHERE strftime('%H:%M:%S', date) BETWEEN '10:00:00' AND '18:00:00'
-	GROUP BY city_name, country_code, strftime('%Y-%m-%d', date)
-	`
-	rows, err := db.Query(query)
+	readings, err := loadReadings(db)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer rows.Close()
+	weathers := dailyAverages(readings)
 
-	var weathers []CompiledWeather
-	for rows.Next() {
-		var wd WeatherData
-		err := rows.Scan(&wd.CityName, &wd.CountryCode, &wd.Date, &wd.Temperature, &wd.WPI, &wd.WeatherIconURL, &wd.GoogleWeatherLink)
-		if err != nil {
-			log.Fatal(err)
-		}
-		formattedTemp, _ := strconv.ParseFloat(fmt.Sprintf("%.1f", wd.Temperature), 64)
-		formattedWPI, _ := strconv.ParseFloat(fmt.Sprintf("%.1f", wd.WPI), 64)
-		weathers = append(weathers, CompiledWeather{
-			City:           wd.CityName,
-			Country:        wd.CountryCode,
-			Date:           strings.Split(wd.Date, " ")[0],
-			AvgDaytimeTemp: formattedTemp,
-			WeatherIcon:    fixWeatherIconURL(wd.WeatherIconURL),
-			GoogleURL:      wd.GoogleWeatherLink,
-			AvgDaytimeWPI:  formattedWPI,
-		})
+	if err := addForecastConfidence(db, weathers, time.Now()); err != nil {
+		log.Fatal("Failed to add forecast confidence:", err)
 	}
 
+	climateWeathers, err := climateFallback(db, weathers, time.Now(), *climateDays)
+	if err != nil {
+		log.Fatal("Failed to build climate fallback:", err)
+	}
+	weathers = append(weathers, climateWeathers...)
+
 	compiledDB, err := sql.Open("sqlite3", "../../../../../../data/compiled/new_main.db")
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer compiledDB.Close()
 
+	if err := ensureWeatherColumns(compiledDB); err != nil {
+		log.Fatal("Failed to add new columns to weather:", err)
+	}
+
 	// Clear the existing weather data
 	_, err = compiledDB.Exec("DELETE FROM weather")
 	if err != nil {
 		log.Fatal("Failed to clear existing weather data:", err)
 	}
 
-	stmt, err := compiledDB.Prepare("INSERT INTO weather (city, country, date, avg_daytime_temp, weather_icon, google_url, avg_daytime_wpi) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := compiledDB.Prepare("INSERT INTO weather (city, country, date, avg_daytime_temp, weather_icon, google_url, avg_daytime_wpi, source, avg_wind_speed, avg_precipitation_probability, avg_humidity, avg_cloud_cover, weather_condition, temperature_score, wind_score, condition_score, precipitation_score, humidity_score, cloud_cover_score, forecast_confidence) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -96,7 +127,8 @@ func main() {
 
 	bar := progressbar.Default(int64(len(weathers)))
 	for _, w := range weathers {
-		_, err := stmt.Exec(w.City, w.Country, w.Date, w.AvgDaytimeTemp, w.WeatherIcon, w.GoogleURL, w.AvgDaytimeWPI)
+		_, err := stmt.Exec(w.City, w.Country, w.Date, w.AvgDaytimeTemp, w.WeatherIcon, w.GoogleURL, w.AvgDaytimeWPI, w.Source, w.AvgWindSpeed, w.PrecipitationProbability, w.Humidity, w.CloudCover, w.Condition,
+			w.Scores.Temperature, w.Scores.Wind, w.Scores.Condition, w.Scores.Precipitation, w.Scores.Humidity, w.Scores.CloudCover, w.ForecastConfidence)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -104,3 +136,10 @@ func main() {
 	}
 	fmt.Println("Data successfully transferred to new_main.db")
 }
+
+func roundNull(value sql.NullFloat64) sql.NullFloat64 {
+	if value.Valid {
+		value.Float64 = math.Round(value.Float64*10) / 10
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// logOutcomes keeps each stay booking.com prices for the first time next to the estimate
+// for its city from the last run, which cannot have seen it. Later runs fit on the stay,
+// so only its first appearance counts. On the first run every stay is taken as already
+// seen, having no earlier estimate to compare with.
+func logOutcomes(db *sql.DB, observations []Observation, now string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var estimates int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM estimate`).Scan(&estimates); err != nil {
+		return 0, err
+	}
+
+	seenStmt, err := tx.Prepare(`INSERT OR IGNORE INTO observation_seen (city, country, stay_date, first_seen_at) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer seenStmt.Close()
+	outcomeStmt, err := tx.Prepare(`INSERT OR IGNORE INTO estimate_outcome (
+		city, country, stay_date, estimated_pppn, pppn_low, pppn_high, estimated_at, observed_pppn, logged_at
+	)
+	SELECT city, country, ?, estimated_pppn, pppn_low, pppn_high, estimated_at, ?, ?
+	FROM estimate
+	WHERE city = ? AND country = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer outcomeStmt.Close()
+
+	logged := 0
+	for _, o := range observations {
+		stayDate := o.StayDate.Format("2006-01-02")
+		result, err := seenStmt.Exec(o.Name, o.Country, stayDate, now)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 || estimates == 0 {
+			continue
+		}
+		result, err = outcomeStmt.Exec(stayDate, o.Pppn, now, o.Name, o.Country)
+		if err != nil {
+			return 0, fmt.Errorf("failed to log outcome for %s, %s: %v", o.Name, o.Country, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			logged += int(n)
+		}
+	}
+	return logged, tx.Commit()
+}
+
+// Accuracy is how estimates did against the prices booking.com showed later
+type Accuracy struct {
+	Metrics
+	InRange float64 // Share of prices within the estimate's range
+}
+
+// readAccuracy measures the outcomes logged in the last days days
+func readAccuracy(db *sql.DB, days int) (Accuracy, error) {
+	var a Accuracy
+	var mae, mape, inRange sql.NullFloat64
+	err := db.QueryRow(`SELECT COUNT(*),
+		AVG(ABS(estimated_pppn - observed_pppn)),
+		AVG(ABS(estimated_pppn - observed_pppn) / observed_pppn),
+		AVG(CASE WHEN observed_pppn BETWEEN pppn_low AND pppn_high THEN 1.0 ELSE 0.0 END)
+		FROM estimate_outcome
+		WHERE logged_at >= datetime('now', ?) AND observed_pppn > 0`,
+		fmt.Sprintf("-%d days", days)).Scan(&a.Count, &mae, &mape, &inRange)
+	a.MAE, a.MAPE, a.InRange = mae.Float64, mape.Float64, inRange.Float64
+	return a, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestLogOutcomes logs a stay against the last estimate for its city the first time it
+// is seen, and never again
+func TestLogOutcomes(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "accommodation-prices.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := createEstimateTables(db); err != nil {
+		t.Fatal(err)
+	}
+
+	stay := func(city string, day int, pppn float64) Observation {
+		return Observation{City: City{Name: city, Country: "DE"}, StayDate: time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC), Pppn: pppn}
+	}
+	steps := []struct {
+		name         string
+		observations []Observation
+		wantLogged   int
+	}{
+		// Nothing to compare with yet, but the stays are now seen
+		{"first run", []Observation{stay("Berlin", 2, 90)}, 0},
+		{"seen before", []Observation{stay("Berlin", 2, 95), stay("Berlin", 9, 100)}, 1},
+		{"seen last run", []Observation{stay("Berlin", 9, 100)}, 0},
+		{"no estimate for the city", []Observation{stay("Hamburg", 9, 80)}, 0},
+	}
+	for i, step := range steps {
+		if i == 1 {
+			_, err := db.Exec(`INSERT INTO estimate (city, country, estimated_pppn, pppn_low, pppn_high, estimated_at)
+				VALUES ('Berlin', 'DE', 85, 70, 110, '2026-10-01 00:00:00')`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		logged, err := logOutcomes(db, step.observations, fmt.Sprintf("2026-10-%02d 00:00:00", i+1))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if logged != step.wantLogged {
+			t.Errorf("%s: logged %d outcomes, want %d", step.name, logged, step.wantLogged)
+		}
+	}
+
+	var stayDate string
+	var estimated, low, high, observed float64
+	err = db.QueryRow(`SELECT stay_date, estimated_pppn, pppn_low, pppn_high, observed_pppn FROM estimate_outcome`).
+		Scan(&stayDate, &estimated, &low, &high, &observed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stayDate != "2026-10-09" || estimated != 85 || low != 70 || high != 110 || observed != 100 {
+		t.Errorf("logged %s at %v (%v to %v), observed %v; want 2026-10-09 at 85 (70 to 110), observed 100",
+			stayDate, estimated, low, high, observed)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+/*
+accommodation-prices estimates the per-night accommodation price of every destination
+city, for cities booking.com has no price for or only an old one while fetching it is
+paused. The model is fitted on the stays booking.db has seen, from each city's
+population, country, the month of the stay and the city's own past prices.
+
+The estimates go to accommodation-prices.db, where compile/main/accommodation/booking-com
+takes them for cities without a fresh price and marks them as estimates. Each stay
+booking.com prices later is kept next to the estimate made before it, to track how
+accurate the estimates are.
+*/
+
+const (
+	bookingDBPath      = "../../../../../data/raw/accommocation/booking-com/booking.db"
+	locationsDBPath    = "../../../../../data/raw/locations/locations.db"
+	flightPricesDBPath = "../../../../../data/generated/flight-prices.db"
+	estimatesDBPath    = "../../../../../data/generated/accommodation-prices.db"
+)
+
+// nextStayDate is the Wednesday booking.com would be searched from today, as
+// fetch/accommocation/booking-com/get-properties does
+func nextStayDate(now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return today.AddDate(0, 0, (int(time.Wednesday)-int(today.Weekday())+7)%7)
+}
+
+// openIfExists opens the SQLite database at path, or returns nil if there is none
+func openIfExists(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return sql.Open("sqlite3", path)
+}
+
+func createEstimateTables(db *sql.DB) error {
+	statements := []string{`
+CREATE TABLE IF NOT EXISTS estimate (
+	city TEXT NOT NULL,
+	country TEXT NOT NULL,
+	population INTEGER,
+	stay_date TEXT,
+	estimated_pppn REAL,
+	pppn_low REAL,
+	pppn_high REAL,
+	city_observations INTEGER,
+	estimated_at TEXT,
+	PRIMARY KEY (city, country)
+)`, `
+CREATE TABLE IF NOT EXISTS observation_seen (
+	city TEXT NOT NULL,
+	country TEXT NOT NULL,
+	stay_date TEXT NOT NULL,
+	first_seen_at TEXT,
+	PRIMARY KEY (city, country, stay_date)
+)`, `
+CREATE TABLE IF NOT EXISTS estimate_outcome (
+	city TEXT NOT NULL,
+	country TEXT NOT NULL,
+	stay_date TEXT NOT NULL,
+	estimated_pppn REAL,
+	pppn_low REAL,
+	pppn_high REAL,
+	estimated_at TEXT,
+	observed_pppn REAL,
+	logged_at TEXT,
+	PRIMARY KEY (city, country, stay_date)
+)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	validationShare := flag.Float64("validation", 0.2, "Share of cities held back to evaluate the model")
+	accuracyDays := flag.Int("accuracy-days", 90, "Report the accuracy of estimates checked in the last this many days")
+	dryRun := flag.Bool("dry-run", false, "Fit and evaluate the model without saving estimates")
+	flag.Parse()
+
+	bookingDB, err := openIfExists(bookingDBPath)
+	if err != nil {
+		log.Fatalf("Error opening booking.db: %v", err)
+	}
+	if bookingDB == nil {
+		fmt.Printf("No booking.db at %s, nothing to estimate accommodation prices from.\n", bookingDBPath)
+		return
+	}
+	defer bookingDB.Close()
+
+	observations, err := readObservations(bookingDB)
+	if err != nil {
+		log.Fatalf("Error reading booking.com prices: %v", err)
+	}
+	if len(observations) == 0 {
+		fmt.Println("No booking.com stays with enough properties, nothing to estimate accommodation prices from.")
+		return
+	}
+
+	// Without locations.db every city counts as average sized
+	populations := make(Populations)
+	if locationsDB, err := openIfExists(locationsDBPath); err != nil {
+		log.Fatalf("Error opening locations.db: %v", err)
+	} else if locationsDB != nil {
+		populations, err = readPopulations(locationsDB)
+		locationsDB.Close()
+		if err != nil {
+			log.Fatalf("Error reading city populations: %v", err)
+		}
+	} else {
+		log.Printf("No locations.db at %s, estimating without populations", locationsDBPath)
+	}
+	for i := range observations {
+		observations[i].Population = populations[observations[i].key()]
+	}
+
+	// The routes' destinations, besides the cities booking.com is searched for
+	flightPricesDB, err := openIfExists(flightPricesDBPath)
+	if err != nil {
+		log.Fatalf("Error opening flight-prices.db: %v", err)
+	}
+	if flightPricesDB != nil {
+		defer flightPricesDB.Close()
+	}
+	cities, err := readCities(bookingDB, flightPricesDB)
+	if err != nil {
+		log.Fatalf("Error reading cities: %v", err)
+	}
+
+	m, evaluation, err := Train(observations, *validationShare)
+	if err != nil {
+		log.Fatalf("Error fitting accommodation price model: %v", err)
+	}
+	fmt.Printf("Fitted on %d booking.com stays in %d cities:\n%s\n%s\n", len(observations), len(m.City), m.Summary(), evaluation.Report())
+
+	if *dryRun {
+		fmt.Println("Dry run, estimates not saved.")
+		return
+	}
+
+	db, err := sql.Open("sqlite3", estimatesDBPath)
+	if err != nil {
+		log.Fatalf("Error opening accommodation-prices.db: %v", err)
+	}
+	defer db.Close()
+	if err := createEstimateTables(db); err != nil {
+		log.Fatalf("Error creating estimate tables: %v", err)
+	}
+
+	now := time.Now().UTC()
+	estimatedAt := now.Format("2006-01-02 15:04:05")
+
+	// Before the estimates they were made with are replaced
+	logged, err := logOutcomes(db, observations, estimatedAt)
+	if err != nil {
+		log.Fatalf("Error logging estimate outcomes: %v", err)
+	}
+	fmt.Printf("Logged %d booking.com prices next to their earlier estimate.\n", logged)
+
+	cityObservations := make(map[string]int)
+	for _, o := range observations {
+		cityObservations[o.key()]++
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatalf("Error beginning transaction: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM estimate`); err != nil {
+		log.Fatalf("Error clearing estimate table: %v", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO estimate (
+		city, country, population, stay_date, estimated_pppn, pppn_low, pppn_high, city_observations, estimated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		log.Fatalf("Error preparing insert statement for estimate: %v", err)
+	}
+	stayDate := nextStayDate(now)
+	for _, c := range cities {
+		c.Population = populations[c.key()]
+		price := m.Predict(c, stayDate)
+		low, high := m.Interval(price)
+		_, err := stmt.Exec(c.Name, c.Country, c.Population, stayDate.Format("2006-01-02"),
+			price, low, high, cityObservations[c.key()], estimatedAt)
+		if err != nil {
+			log.Fatalf("Error inserting estimate for %s, %s: %v", c.Name, c.Country, err)
+		}
+	}
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error committing estimates: %v", err)
+	}
+	fmt.Printf("Estimated accommodation prices for %d cities, staying from %s.\n", len(cities), stayDate.Format("2006-01-02"))
+
+	accuracy, err := readAccuracy(db, *accuracyDays)
+	if err != nil {
+		log.Fatalf("Error reading estimate accuracy: %v", err)
+	}
+	if accuracy.Count == 0 {
+		fmt.Printf("No booking.com prices to check estimates against in the last %d days.\n", *accuracyDays)
+		return
+	}
+	fmt.Printf("Accuracy over the last %d days: %d prices, MAE €%.2f, MAPE %.1f%%, %.0f%% within the range\n",
+		*accuracyDays, accuracy.Count, accuracy.MAE, accuracy.MAPE*100, accuracy.InRange*100)
+}
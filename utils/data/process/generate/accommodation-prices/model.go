@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+The model predicts the log of the per-night price as the sum of a few effects:
+
+	log(pppn) = mean + slope·(log population − mean log population) + country + month + city
+
+Each effect is the average of what the others leave unexplained for its group, shrunk
+towards 0 for groups with few observations, so one odd stay cannot move a country far.
+The city effect is what a city's own past observations say about it, which is why a city
+booking.com has priced before is estimated better than one it never has.
+*/
+
+// Shrinkage of each effect: a group with this many observations gets half its average
+const (
+	countryShrinkage = 3.0
+	monthShrinkage   = 3.0
+	cityShrinkage    = 2.0
+	slopeShrinkage   = 1.0
+)
+
+// fitIterations of backfitting are plenty for effects this few
+const fitIterations = 20
+
+// intervalCoverage is the share of observed prices an estimate's range should contain
+const intervalCoverage = 0.8
+
+// minIntervalResiduals is how many held-out stays the range needs; fewer fall back to the training stays
+const minIntervalResiduals = 10
+
+// Model is a fitted accommodation price model
+type Model struct {
+	Mean       float64
+	Slope      float64 // Per unit of log population
+	MeanLogPop float64
+	Country    map[string]float64
+	Month      map[time.Month]float64
+	City       map[string]float64
+	// Bounds on actual over estimated price, from stays the model was not fitted on
+	Low, High float64
+}
+
+// logPopulation is 0 for unknown populations, which then count as average
+func (m *Model) logPopulation(c City) float64 {
+	if c.Population <= 0 {
+		return 0
+	}
+	return math.Log(float64(c.Population)) - m.MeanLogPop
+}
+
+func (m *Model) logPredict(c City, stayDate time.Time, withCity bool) float64 {
+	logPrice := m.Mean + m.Slope*m.logPopulation(c) + m.Country[c.Country] + m.Month[stayDate.Month()]
+	if withCity {
+		logPrice += m.City[c.key()]
+	}
+	return logPrice
+}
+
+// Predict returns the per-night price of a stay in c checking in on stayDate
+func (m *Model) Predict(c City, stayDate time.Time) float64 {
+	return math.Exp(m.logPredict(c, stayDate, true))
+}
+
+// Interval returns the range the price of the stay is likely in, around price
+func (m *Model) Interval(price float64) (low, high float64) {
+	return price * m.Low, price * m.High
+}
+
+// shrunkMeans averages residuals by group, shrunk towards 0
+func shrunkMeans[K comparable](groups []K, residuals []float64, shrinkage float64) map[K]float64 {
+	sums := make(map[K]float64)
+	counts := make(map[K]float64)
+	for i, g := range groups {
+		sums[g] += residuals[i]
+		counts[g]++
+	}
+	means := make(map[K]float64, len(sums))
+	for g, sum := range sums {
+		means[g] = sum / (counts[g] + shrinkage)
+	}
+	return means
+}
+
+// fitModel fits a model to observations, by backfitting one effect at a time
+func fitModel(observations []Observation) (*Model, error) {
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("no observations to fit")
+	}
+	m := &Model{Country: map[string]float64{}, Month: map[time.Month]float64{}, City: map[string]float64{}}
+
+	var logPops []float64
+	for _, o := range observations {
+		if o.Population > 0 {
+			logPops = append(logPops, math.Log(float64(o.Population)))
+		}
+	}
+	if len(logPops) > 0 {
+		m.MeanLogPop = mean(logPops)
+	}
+
+	n := len(observations)
+	logPrices := make([]float64, n)
+	pops := make([]float64, n)
+	countries := make([]string, n)
+	months := make([]time.Month, n)
+	cities := make([]string, n)
+	for i, o := range observations {
+		logPrices[i] = math.Log(o.Pppn)
+		pops[i] = m.logPopulation(o.City)
+		countries[i] = o.Country
+		months[i] = o.StayDate.Month()
+		cities[i] = o.key()
+	}
+
+	// What is left of each log price once every effect but the one being fitted is taken off
+	residuals := make([]float64, n)
+	without := func(effect func(i int) float64) {
+		for i := range observations {
+			predicted := m.Mean + m.Slope*pops[i] + m.Country[countries[i]] + m.Month[months[i]] + m.City[cities[i]]
+			residuals[i] = logPrices[i] - predicted + effect(i)
+		}
+	}
+	for iteration := 0; iteration < fitIterations; iteration++ {
+		without(func(i int) float64 { return m.Mean })
+		m.Mean = mean(residuals)
+
+		without(func(i int) float64 { return m.Slope * pops[i] })
+		var xy, xx float64
+		for i := range residuals {
+			xy += pops[i] * residuals[i]
+			xx += pops[i] * pops[i]
+		}
+		m.Slope = xy / (xx + slopeShrinkage)
+
+		without(func(i int) float64 { return m.Country[countries[i]] })
+		m.Country = shrunkMeans(countries, residuals, countryShrinkage)
+
+		without(func(i int) float64 { return m.Month[months[i]] })
+		m.Month = shrunkMeans(months, residuals, monthShrinkage)
+
+		without(func(i int) float64 { return m.City[cities[i]] })
+		m.City = shrunkMeans(cities, residuals, cityShrinkage)
+	}
+	return m, nil
+}
+
+// baseline is what five-nights-and-flights falls back to without an accommodation price:
+// the median of the country's cities, or €40
+type baseline struct {
+	byCountry map[string]float64
+}
+
+const fallbackPppn = 40
+
+func newBaseline(observations []Observation) baseline {
+	prices := make(map[string][]float64)
+	for _, o := range observations {
+		prices[o.Country] = append(prices[o.Country], o.Pppn)
+	}
+	b := baseline{byCountry: make(map[string]float64)}
+	for country, countryPrices := range prices {
+		b.byCountry[country] = median(countryPrices)
+	}
+	return b
+}
+
+func (b baseline) Predict(c City) float64 {
+	if price, ok := b.byCountry[c.Country]; ok {
+		return price
+	}
+	return fallbackPppn
+}
+
+// Metrics measures estimates against the prices booking.com showed
+type Metrics struct {
+	Count int
+	MAE   float64
+	MAPE  float64
+}
+
+type metricsAccumulator struct {
+	count             int
+	absError, absPerc float64
+}
+
+func (a *metricsAccumulator) add(estimated, actual float64) {
+	a.count++
+	a.absError += math.Abs(estimated - actual)
+	a.absPerc += math.Abs(estimated-actual) / actual
+}
+
+func (a metricsAccumulator) metrics() Metrics {
+	if a.count == 0 {
+		return Metrics{}
+	}
+	return Metrics{Count: a.count, MAE: a.absError / float64(a.count), MAPE: a.absPerc / float64(a.count)}
+}
+
+// Evaluation is how the model and the baseline did on cities held back from fitting
+type Evaluation struct {
+	Model    Metrics
+	Baseline Metrics
+}
+
+// Report formats the evaluation as a table
+func (e Evaluation) Report() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%-22s %6s %8s %8s\n", "Held-out stays", "Stays", "MAE", "MAPE")
+	fmt.Fprintf(&s, "%-22s %6d %8.2f %7.1f%%\n", "Model", e.Model.Count, e.Model.MAE, e.Model.MAPE*100)
+	fmt.Fprintf(&s, "%-22s %6d %8.2f %7.1f%%\n", "Country median or €40", e.Baseline.Count, e.Baseline.MAE, e.Baseline.MAPE*100)
+	return s.String()
+}
+
+// heldOut reports whether a city is held back for validation. Whole cities are held back,
+// as the model is needed most for cities booking.com has no price for.
+func heldOut(c City, validationShare float64) bool {
+	h := fnv.New32a()
+	h.Write([]byte(c.key()))
+	return float64(h.Sum32()%1000) < validationShare*1000
+}
+
+// Train fits a model on every observation, after evaluating one fitted without the held
+// back cities against the baseline. The held back cities also give the estimates' range.
+func Train(observations []Observation, validationShare float64) (*Model, Evaluation, error) {
+	var train, validation []Observation
+	for _, o := range observations {
+		if heldOut(o.City, validationShare) {
+			validation = append(validation, o)
+		} else {
+			train = append(train, o)
+		}
+	}
+
+	var evaluation Evaluation
+	var ratios []float64
+	if len(train) > 0 && len(validation) > 0 {
+		m, err := fitModel(train)
+		if err != nil {
+			return nil, evaluation, err
+		}
+		b := newBaseline(train)
+		var model, base metricsAccumulator
+		for _, o := range validation {
+			estimated := math.Exp(m.logPredict(o.City, o.StayDate, false))
+			model.add(estimated, o.Pppn)
+			base.add(b.Predict(o.City), o.Pppn)
+			ratios = append(ratios, o.Pppn/estimated)
+		}
+		evaluation = Evaluation{Model: model.metrics(), Baseline: base.metrics()}
+	}
+
+	m, err := fitModel(observations)
+	if err != nil {
+		return nil, evaluation, err
+	}
+	if len(ratios) < minIntervalResiduals {
+		ratios = ratios[:0]
+		for _, o := range observations {
+			ratios = append(ratios, o.Pppn/m.Predict(o.City, o.StayDate))
+		}
+	}
+	sort.Float64s(ratios)
+	tail := (1 - intervalCoverage) / 2
+	m.Low = math.Min(1, quantile(ratios, tail))
+	m.High = math.Max(1, quantile(ratios, 1-tail))
+	return m, evaluation, nil
+}
+
+// quantile of sorted values, interpolating between neighbours
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 1
+	}
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// Summary lists the model's effects, largest first
+func (m *Model) Summary() string {
+	type effect struct {
+		name  string
+		value float64
+	}
+	var effects []effect
+	for country, value := range m.Country {
+		effects = append(effects, effect{"country " + country, value})
+	}
+	for month, value := range m.Month {
+		effects = append(effects, effect{month.String(), value})
+	}
+	sort.Slice(effects, func(i, j int) bool { return math.Abs(effects[i].value) > math.Abs(effects[j].value) })
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "Typical per night: €%.2f, ×%.2f per doubling of population\n", math.Exp(m.Mean), math.Pow(2, m.Slope))
+	for i, e := range effects {
+		if i == 8 {
+			break
+		}
+		fmt.Fprintf(&s, "  %-20s ×%.2f\n", e.name, math.Exp(e.value))
+	}
+	fmt.Fprintf(&s, "Range: ×%.2f to ×%.2f of the estimate for %.0f%% of stays\n", m.Low, m.High, intervalCoverage*100)
+	return s.String()
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+)
+
+// syntheticObservations prices stays in cities of two countries, DE twice as dear as PL,
+// and half as dear again per tenfold population
+func syntheticObservations(citiesPerCountry, staysPerCity int) []Observation {
+	var observations []Observation
+	for _, country := range []string{"DE", "PL"} {
+		for i := 0; i < citiesPerCountry; i++ {
+			population := 10000 * int(math.Pow(10, float64(i%4)))
+			for j := 0; j < staysPerCity; j++ {
+				logPrice := math.Log(40) + math.Log(1.5)*math.Log10(float64(population)/10000)
+				if country == "DE" {
+					logPrice += math.Log(2)
+				}
+				observations = append(observations, Observation{
+					City:     City{Name: fmt.Sprintf("%s city %d", country, i), Country: country, Population: population},
+					StayDate: time.Date(2026, time.Month(1+j%12), 1, 0, 0, 0, 0, time.UTC),
+					Pppn:     math.Exp(logPrice),
+				})
+			}
+		}
+	}
+	return observations
+}
+
+func TestShrunkMeans(t *testing.T) {
+	means := shrunkMeans([]string{"often", "often", "once"}, []float64{1, 1, 3}, 2)
+	if means["often"] != 0.5 || means["once"] != 1 {
+		t.Errorf("got %v, want often 0.5 and once 1", means)
+	}
+}
+
+// TestFitModelBackfitting recovers the country and population effects the prices were made with
+func TestFitModelBackfitting(t *testing.T) {
+	m, err := fitModel(syntheticObservations(20, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if perTenfold := m.Slope * math.Log(10); math.Abs(perTenfold-math.Log(1.5)) > 0.02 {
+		t.Errorf("log price per tenfold population %v, want %v", perTenfold, math.Log(1.5))
+	}
+	stayDate := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	seen := func(country string) City { return City{Name: country + " city 1", Country: country, Population: 100000} }
+	if ratio := m.Predict(seen("DE"), stayDate) / m.Predict(seen("PL"), stayDate); math.Abs(ratio-2) > 0.05 {
+		t.Errorf("a DE city is %v times as dear as a PL one, want 2", ratio)
+	}
+	if price := m.Predict(seen("PL"), stayDate); math.Abs(price-60) > 3 {
+		t.Errorf("a city of 100000 in PL costs %v, want 60", price)
+	}
+	// A city without stays has only its country's effect, which is shrunk towards none
+	unseen := func(country string) City { return City{Name: "Unseen", Country: country, Population: 100000} }
+	if ratio := m.Predict(unseen("DE"), stayDate) / m.Predict(unseen("PL"), stayDate); ratio < 1.5 || ratio > 2 {
+		t.Errorf("an unseen DE city is %v times as dear as a PL one, want a little less than 2", ratio)
+	}
+}
+
+// TestFitModelCityShrinkage moves a city's estimate only part of the way to its one stay
+func TestFitModelCityShrinkage(t *testing.T) {
+	observations := syntheticObservations(20, 6)
+	odd := Observation{City: City{Name: "Odd", Country: "PL", Population: 10000}, StayDate: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), Pppn: 160}
+	m, err := fitModel(append(observations, odd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withoutCity := math.Exp(m.logPredict(odd.City, odd.StayDate, false))
+	if price := m.Predict(odd.City, odd.StayDate); price <= withoutCity || price >= (withoutCity+odd.Pppn)/2 {
+		t.Errorf("one stay at %v moved the estimate from %v to %v, want less than halfway", odd.Pppn, withoutCity, price)
+	}
+}
+
+func TestFitModelNeedsObservations(t *testing.T) {
+	if _, err := fitModel(nil); err == nil {
+		t.Errorf("fitted a model to no observations, want an error")
+	}
+}
+
+// TestHeldOut holds back whole cities, the same ones every run
+func TestHeldOut(t *testing.T) {
+	held := 0
+	for i := 0; i < 1000; i++ {
+		c := City{Name: fmt.Sprintf("City %d", i), Country: "DE"}
+		if heldOut(c, 0.2) {
+			held++
+		}
+		if heldOut(c, 0.2) != heldOut(City{Name: c.Name, Country: "DE", Population: 5}, 0.2) {
+			t.Fatalf("%s is held out by its population", c.Name)
+		}
+		if heldOut(c, 0) || !heldOut(c, 1) {
+			t.Fatalf("%s held out with shares 0 and 1: %v and %v", c.Name, heldOut(c, 0), heldOut(c, 1))
+		}
+	}
+	if held < 150 || held > 250 {
+		t.Errorf("held out %d of 1000 cities, want about 200", held)
+	}
+}
+
+// TestTrainInterval takes the estimates' range from the held back cities when there are
+// enough of them, and from every stay otherwise
+func TestTrainInterval(t *testing.T) {
+	observations := syntheticObservations(20, 6)
+	// Prices the model cannot explain, so the range has some width
+	for i := range observations {
+		observations[i].Pppn *= 1 + 0.3*math.Sin(float64(i))
+	}
+
+	t.Run("held back cities", func(t *testing.T) {
+		m, evaluation, err := Train(observations, 0.3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evaluation.Model.Count < minIntervalResiduals || evaluation.Model.Count != evaluation.Baseline.Count {
+			t.Errorf("evaluated on %d and %d stays, want the same %d or more", evaluation.Model.Count, evaluation.Baseline.Count, minIntervalResiduals)
+		}
+		if m.Low > 1 || m.High < 1 || m.Low == m.High {
+			t.Errorf("range %v to %v, want one around 1", m.Low, m.High)
+		}
+	})
+
+	t.Run("too few held back", func(t *testing.T) {
+		m, evaluation, err := Train(observations, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evaluation.Model.Count != 0 {
+			t.Errorf("evaluated on %d stays with none held back", evaluation.Model.Count)
+		}
+		var ratios []float64
+		for _, o := range observations {
+			ratios = append(ratios, o.Pppn/m.Predict(o.City, o.StayDate))
+		}
+		sort.Float64s(ratios)
+		tail := (1 - intervalCoverage) / 2
+		if low, high := math.Min(1, quantile(ratios, tail)), math.Max(1, quantile(ratios, 1-tail)); m.Low != low || m.High != high {
+			t.Errorf("range %v to %v, want %v to %v from every stay", m.Low, m.High, low, high)
+		}
+	})
+}
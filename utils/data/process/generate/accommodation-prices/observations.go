@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+)
+
+// Like compile/main/accommodation/booking-com, a stay needs this many well reviewed
+// properties before its price counts, and the cheapest and dearest tenth are left out
+const (
+	minPropertiesPerStay = 10
+	trimmedShare         = 0.10
+)
+
+// pppnDivisor turns a stay's gross price into booking_pppn, as compile/main/accommodation/booking-com does
+const pppnDivisor = 14
+
+// City is a destination that may need an accommodation price
+type City struct {
+	Name       string
+	Country    string
+	Population int
+}
+
+func (c City) key() string {
+	return c.Name + "|" + c.Country
+}
+
+// Observation is the per-night price booking.com showed for one city's stay
+type Observation struct {
+	City
+	StayDate   time.Time // Check-in
+	Pppn       float64
+	Properties int
+}
+
+// readObservations reads booking.db's properties as one price per city and check-in
+// date: the median of the middle 80% of well reviewed properties, over pppnDivisor
+func readObservations(bookingDB *sql.DB) ([]Observation, error) {
+	rows, err := bookingDB.Query(`SELECT city, country, checkin_date, gross_price
+		FROM property
+		WHERE review_score > 7 AND gross_price > 0 AND checkin_date IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type stay struct {
+		city, country, checkin string
+	}
+	prices := make(map[stay][]float64)
+	for rows.Next() {
+		var s stay
+		var price float64
+		if err := rows.Scan(&s.city, &s.country, &s.checkin, &price); err != nil {
+			return nil, err
+		}
+		prices[s] = append(prices[s], price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var observations []Observation
+	for s, stayPrices := range prices {
+		if len(stayPrices) < minPropertiesPerStay {
+			continue
+		}
+		stayDate, err := time.Parse("2006-01-02", s.checkin)
+		if err != nil {
+			continue
+		}
+		sort.Float64s(stayPrices)
+		dropCount := int(math.Floor(float64(len(stayPrices)) * trimmedShare))
+		observations = append(observations, Observation{
+			City:       City{Name: s.city, Country: s.country},
+			StayDate:   stayDate,
+			Pppn:       median(stayPrices[dropCount:len(stayPrices)-dropCount]) / pppnDivisor,
+			Properties: len(stayPrices),
+		})
+	}
+	sort.Slice(observations, func(i, j int) bool {
+		if observations[i].key() != observations[j].key() {
+			return observations[i].key() < observations[j].key()
+		}
+		return observations[i].StayDate.Before(observations[j].StayDate)
+	})
+	return observations, nil
+}
+
+// readCities lists the cities booking.com is searched for and the destinations of
+// flight-prices.db's routes, which has none if it is missing
+func readCities(bookingDB *sql.DB, flightPricesDB *sql.DB) ([]City, error) {
+	queries := []struct {
+		db    *sql.DB
+		query string
+	}{
+		{bookingDB, `SELECT DISTINCT city, country FROM city WHERE city IS NOT NULL AND country IS NOT NULL`},
+		{flightPricesDB, `SELECT DISTINCT destination_city_name, destination_country FROM routes
+			WHERE destination_city_name <> '' AND destination_country <> ''`},
+	}
+
+	seen := make(map[string]bool)
+	var cities []City
+	for _, q := range queries {
+		if q.db == nil {
+			continue
+		}
+		rows, err := q.db.Query(q.query)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var c City
+			if err := rows.Scan(&c.Name, &c.Country); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if !seen[c.key()] {
+				seen[c.key()] = true
+				cities = append(cities, c)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return cities, nil
+}
+
+// Populations are city populations by City.key, from locations.db
+type Populations map[string]int
+
+func readPopulations(locationsDB *sql.DB) (Populations, error) {
+	rows, err := locationsDB.Query(`SELECT city_ascii, iso2, CAST(population AS INTEGER) FROM city
+		WHERE city_ascii IS NOT NULL AND iso2 IS NOT NULL AND population > 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	populations := make(Populations)
+	for rows.Next() {
+		var c City
+		if err := rows.Scan(&c.Name, &c.Country, &c.Population); err != nil {
+			return nil, err
+		}
+		if c.Population > populations[c.key()] {
+			populations[c.key()] = c.Population
+		}
+	}
+	return populations, rows.Err()
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
@@ -34,7 +34,17 @@ func main() {
 	"duration_in_minutes"	DECIMAL,
   "duration_in_hours"	DECIMAL,
   "duration_in_hours_rounded"	DECIMAL,
-  "duration_hour_dot_mins" REAL
+  "duration_hour_dot_mins" REAL,
+	"outbound_date"	DATE,
+	"outbound_price"	DECIMAL,
+	"outbound_duration_mins"	INTEGER,
+	"return_date"	DATE,
+	"return_price"	DECIMAL,
+	"return_duration_mins"	INTEGER,
+	"price_source"	TEXT DEFAULT 'predicted',
+	"price_observed_at"	TEXT,
+	"price_low"	DECIMAL,
+	"price_high"	DECIMAL,
 	PRIMARY KEY("id" AUTOINCREMENT)
 	);`,
 		`CREATE TABLE IF NOT EXISTS weather (
@@ -44,7 +54,70 @@ func main() {
 			avg_daytime_temp FLOAT(10,1),
 			weather_icon VARCHAR(255),
 			google_url VARCHAR(255),
-			avg_daytime_wpi FLOAT(10,1) 
+			avg_daytime_wpi FLOAT(10,1),
+			source VARCHAR(16) DEFAULT 'forecast',
+			avg_wind_speed FLOAT(10,1),
+			avg_precipitation_probability FLOAT(10,1),
+			avg_humidity FLOAT(10,1),
+			avg_cloud_cover FLOAT(10,1),
+			weather_condition VARCHAR(32),
+			temperature_score FLOAT(10,1),
+			wind_score FLOAT(10,1),
+			condition_score FLOAT(10,1),
+			precipitation_score FLOAT(10,1),
+			humidity_score FLOAT(10,1),
+			cloud_cover_score FLOAT(10,1),
+			forecast_confidence FLOAT(10,2),
+			sunrise VARCHAR(5),
+			sunset VARCHAR(5),
+			daylight_hours FLOAT(10,1)
+		);`,
+		`CREATE TABLE IF NOT EXISTS air_quality (
+			city VARCHAR(255),
+			country VARCHAR(255),
+			date DATE,
+			aqi INTEGER,
+			pm2_5 FLOAT(10,2),
+			pm10 FLOAT(10,2),
+			PRIMARY KEY (city, country, date)
+		);`,
+		`CREATE TABLE IF NOT EXISTS route_price_by_date (
+			origin_city_name TEXT,
+			origin_country TEXT,
+			origin_iata TEXT,
+			destination_city_name TEXT,
+			destination_country TEXT,
+			destination_iata TEXT,
+			leg TEXT,
+			date DATE,
+			price DECIMAL,
+			fetched_at TEXT
+		);`,
+		`CREATE TABLE IF NOT EXISTS route_price_history (
+			origin_city_name TEXT,
+			origin_country TEXT,
+			origin_iata TEXT,
+			destination_city_name TEXT,
+			destination_country TEXT,
+			destination_iata TEXT,
+			date DATE,
+			price DECIMAL
+		);`,
+		`CREATE TABLE IF NOT EXISTS route_price_trend (
+			origin_city_name TEXT,
+			origin_country TEXT,
+			origin_iata TEXT,
+			destination_city_name TEXT,
+			destination_country TEXT,
+			destination_iata TEXT,
+			latest_date DATE,
+			latest_price DECIMAL,
+			min_7d DECIMAL,
+			median_7d DECIMAL,
+			min_30d DECIMAL,
+			median_30d DECIMAL,
+			observations_30d INTEGER,
+			direction TEXT
 		);`,
 		`CREATE TABLE IF NOT EXISTS accommodation (
 			city VARCHAR(255) NOT NULL,
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/utils/data/process/generate/flight-prices/pricemodel"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+/*
+backtest reports how the predicted prices compared with the prices the fetcher saw for
+the same routes afterwards, by route class, airline, distance band and destination country.
+It then learns correction factors from the model's errors and saves them to
+flight-prices.db, where generate/flight-prices applies them to its next predictions.
+*/
+func main() {
+	sources := pricemodel.DefaultTrainingSources("..")
+	dbPath := flag.String("db", sources.RoutesDB, "flight-prices.db with prediction_outcome")
+	locationsDB := flag.String("locations-db", sources.LocationsDB, "Raw locations.db with airport coordinates, for distance bands")
+	days := flag.Int("days", 90, "Backtest prices seen in the last this many days")
+	maxGroups := flag.Int("max-groups", 10, "Lines per table, most outcomes first. 0 shows every group")
+	dryRun := flag.Bool("dry-run", false, "Report without saving correction factors")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", *dbPath)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", *dbPath, err)
+	}
+	defer db.Close()
+
+	outcomes, err := pricemodel.ReadOutcomes(db, *days)
+	if err != nil {
+		log.Fatalf("Error reading prediction outcomes: %v", err)
+	}
+	if len(outcomes) == 0 {
+		fmt.Printf("No prediction outcomes in the last %d days; compile/main/flights logs them once prices are seen after a prediction.\n", *days)
+		return
+	}
+
+	// Without coordinates every route is in the "unknown" distance band
+	if _, err := os.Stat(*locationsDB); err == nil {
+		locations, err := sql.Open("sqlite3", *locationsDB)
+		if err != nil {
+			log.Fatalf("Error opening %s: %v", *locationsDB, err)
+		}
+		defer locations.Close()
+		airports, err := pricemodel.LoadAirports(locations)
+		if err != nil {
+			log.Printf("Error reading airport coordinates, distances unknown: %v", err)
+		}
+		for i := range outcomes {
+			outcomes[i].DistanceKm = airports.Distance(outcomes[i].OriginIATA, outcomes[i].DestinationIATA)
+		}
+	}
+
+	fmt.Printf("Backtest of %d prices seen in the last %d days:\n\n%s\n", len(outcomes), *days, pricemodel.RunBacktest(outcomes).Report(*maxGroups))
+
+	corrections := pricemodel.LearnCorrections(outcomes)
+	fmt.Printf("Learnt %d correction factors for model version %d:\n", corrections.Count(), corrections.ModelVersion)
+	classes := make([]string, 0, len(corrections.ByClass))
+	for class := range corrections.ByClass {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		c := corrections.ByClass[class]
+		fmt.Printf("  %-28s %5.2fx from %d prices\n", class, c.Factor, c.Outcomes)
+	}
+	fmt.Printf("  and %d by route class and country, %d by route\n", len(corrections.ByClassCountry), len(corrections.ByRoute))
+
+	if *dryRun {
+		fmt.Println("Dry run, correction factors not saved.")
+		return
+	}
+	if err := pricemodel.SaveCorrections(db, corrections, time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+		log.Fatalf("Error saving correction factors: %v", err)
+	}
+	fmt.Println("Saved correction factors to price_correction.")
+}
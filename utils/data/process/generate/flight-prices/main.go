@@ -2,165 +2,163 @@ package main
 
 import (
 	"database/sql"
-	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
+	"github.com/Tris20/FairFareFinder/utils/data/process/generate/flight-prices/pricemodel"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/sajari/regression"
 )
 
-// Encoding maps and helper functions.
-var (
-	originCityMap      = make(map[string]float64)
-	destinationCityMap = make(map[string]float64)
-	airlineMap         = make(map[string]float64)
-	routeClassMap      = make(map[string]float64)
-	aircraftMap        = make(map[string]float64)
-
-	nextOriginCityCode float64 = 1
-	nextDestCityCode   float64 = 1
-	nextAirlineCode    float64 = 1
-	nextRouteClassCode float64 = 1
-	nextAircraftCode   float64 = 1
-)
+func main() {
+	modelVersion := flag.Int("model-version", 0, "Version of the trained price model to predict with (default: the latest)")
+	flag.Parse()
+
+	// Create the generated flight price db and routes table
+	CreateResultsDB()
+	// Populate the Routes table with all the flight data except prices
+	PopulateRoutesTable()
+	// Load the price model and generate predicted flight prices into the prediction table
+	GeneratePredictions(*modelVersion)
+}
 
-// encodeString returns a numeric encoding for a given category.
-func encodeString(val string, m map[string]float64, next *float64) float64 {
-	if code, ok := m[val]; ok {
-		return code
+// loadModel loads version of the price model from the registry. The first time, with an
+// empty registry, the latest model is trained and registered as train-model would.
+func loadModel(version int) (*pricemodel.Model, error) {
+	registry := pricemodel.Registry{Dir: pricemodel.DefaultRegistryDir}
+	m, err := registry.Load(version)
+	if err == nil || version != 0 || !errors.Is(err, os.ErrNotExist) {
+		return m, err
 	}
-	m[val] = *next
-	*next++
-	return m[val]
+
+	fmt.Println("No trained price model yet, training one.")
+	sources := pricemodel.DefaultTrainingSources(".")
+	routes, err := sources.Load()
+	if err != nil {
+		return nil, err
+	}
+	m, err = pricemodel.Train(routes, 0.2, 1, pricemodel.DefaultRidgeLambda)
+	if err != nil {
+		return nil, err
+	}
+	m.TrainingData = sources.Describe()
+	fmt.Println(m.Evaluation.Report())
+	if err := registry.Save(m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-// parseDuration converts a "H.MM" string into total minutes.
-// It calculates: totalMinutes = hours*60 + minutes*10.
-// If no dot is present, the value is assumed to represent hours.
-func parseDuration(duration string) (int, error) {
-	if !strings.Contains(duration, ".") {
-		hours, err := strconv.Atoi(duration)
-		if err != nil {
-			return 0, fmt.Errorf("invalid hours value in duration: %s", duration)
+// applyBoundaryRules keeps a predicted price within what is plausible for the flight's
+// duration. Prices too high to be plausible come back as 0, to be stored as NULL.
+func applyBoundaryRules(finalPrice float64, fd int) float64 {
+	pricePerMinute := finalPrice / float64(fd)
+	randomMultiplier := rand.Float64()*(1.1-0.9) + 0.9
+	if fd > 240 {
+		randomMultiplier = rand.Float64()*(1.05-0.95) + 0.95
+		if pricePerMinute < 1.4 {
+			finalPrice = 1.4 * float64(fd) * randomMultiplier
+		}
+		if pricePerMinute > 2.3 {
+			finalPrice = 2.3 * float64(fd) * randomMultiplier
+		}
+	} else if fd <= 60 {
+		if pricePerMinute < 0.9 {
+			finalPrice = 60.0 * randomMultiplier
+		}
+		if finalPrice > 210 {
+			finalPrice = 0
+		}
+	} else if fd > 60 && fd <= 120 {
+		if pricePerMinute < 0.9 {
+			finalPrice = 0.9 * float64(fd) * randomMultiplier
+		}
+		if finalPrice > 240 {
+			finalPrice = 0
+		}
+	} else if fd > 120 && fd <= 240 {
+		if pricePerMinute < 0.9 {
+			finalPrice = 0.9 * float64(fd) * randomMultiplier
+		}
+		if finalPrice > 260 {
+			finalPrice = 0
 		}
-		return hours * 60, nil
 	}
-	parts := strings.Split(duration, ".")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("unexpected duration format: %s", duration)
+	return finalPrice
+}
+
+// loadAirports reads airport coordinates for route distances. Without them distances
+// fall back to the training mean, so a missing locations.db is not fatal.
+func loadAirports(path string) pricemodel.Airports {
+	locationsDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Printf("Error opening locations database, predicting without distances: %v", err)
+		return nil
 	}
-	hours, err1 := strconv.Atoi(parts[0])
-	mins, err2 := strconv.Atoi(parts[1])
-	if err1 != nil || err2 != nil {
-		return 0, fmt.Errorf("invalid numeric values in duration: %s", duration)
+	defer locationsDB.Close()
+	airports, err := pricemodel.LoadAirports(locationsDB)
+	if err != nil {
+		log.Printf("Error reading airport coordinates, predicting without distances: %v", err)
+		return nil
 	}
-	return hours*60 + mins*10, nil
+	return airports
 }
 
-func main() {
-	// Create the generated flight price db and routes table
-	CreateResultsDB()
-	// Populate the Routes table with all the flight data except prices
-	PopulateRoutesTable()
-	// Train on dataset and then generate predicted flight prices into predcitions table
-	GeneratePredictions()
+// loadModifiers reads the price modifiers the modifiers command imported. Without them
+// predictions are the model's alone, so a missing flight_price_modifiers.db is not fatal.
+func loadModifiers(path string) pricemodel.Modifiers {
+	if _, err := os.Stat(path); err != nil {
+		log.Printf("No price modifiers at %s, predicting without them", path)
+		return nil
+	}
+	modifiersDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Printf("Error opening price modifiers database, predicting without them: %v", err)
+		return nil
+	}
+	defer modifiersDB.Close()
+	modifiers, err := pricemodel.LoadModifiers(modifiersDB)
+	if err != nil {
+		log.Printf("Error reading price modifiers: %v", err)
+	}
+	return modifiers
 }
 
-func GeneratePredictions() {
+// predictionColumns are the columns added to the prediction table since it was first created
+var predictionColumns = []dbschema.Column{
+	{Name: "model_version", Definition: "INTEGER"},
+	{Name: "price_low", Definition: "REAL"},
+	{Name: "price_high", Definition: "REAL"},
+	{Name: "model_price", Definition: "REAL"},
+	{Name: "correction", Definition: "REAL"},
+	{Name: "predicted_at", Definition: "TEXT"},
+	{Name: "modifiers", Definition: "TEXT"},
+	{Name: "modifier_factor", Definition: "REAL"},
+}
+
+func GeneratePredictions(modelVersion int) {
 	// Seed the random number generator.
 	rand.Seed(time.Now().UnixNano())
 
 	// ============================================
-	// PART 1: TRAINING PHASE – Build Regression Model
+	// PART 1: Load the regression model trained by train-model
 	// ============================================
-	// Open the training CSV file.
-	f, err := os.Open("training_data.csv")
+	regModel, err := loadModel(modelVersion)
 	if err != nil {
-		log.Fatalf("Error opening CSV file: %v", err)
+		log.Fatalf("Error loading price model: %v", err)
 	}
-	defer f.Close()
+	fmt.Printf("Predicting with price model version %d, trained %s:\n%v\n\n", regModel.Version, regModel.TrainedAt, regModel.Formula())
 
-	reader := csv.NewReader(f)
-	reader.FieldsPerRecord = -1
-	records, err := reader.ReadAll()
+	routes, err := pricemodel.DefaultTrainingSources(".").Load()
 	if err != nil {
-		log.Fatalf("Error reading CSV records: %v", err)
-	}
-
-	// Create and configure the regression model.
-	var regModel regression.Regression
-	regModel.SetObserved("actual_price")
-	regModel.SetVar(0, "bias")
-	regModel.SetVar(1, "origin_population")
-	regModel.SetVar(2, "destination_population")
-	regModel.SetVar(3, "route_frequency")
-	regModel.SetVar(4, "origin_city")
-	regModel.SetVar(5, "destination_city")
-	regModel.SetVar(6, "airline")
-	regModel.SetVar(7, "route_class")
-	regModel.SetVar(8, "aircraft")
-	regModel.SetVar(9, "seating_capacity")
-	// Changed variable name from "duration_minutes" to "duration_hour_dot_minutes"
-	regModel.SetVar(10, "duration_hour_dot_minutes")
-
-	startRow := 0
-	if strings.Contains(strings.ToLower(records[0][0]), "origin_city_name") {
-		startRow = 1
+		log.Fatalf("Error reading training data: %v", err)
 	}
 
-	// Train the regression model using CSV records.
-	for i := startRow; i < len(records); i++ {
-		rec := records[i]
-		if len(rec) < 15 {
-			continue
-		}
-		originPop, _ := strconv.Atoi(rec[3])
-		destPop, _ := strconv.Atoi(rec[7])
-		routeFreq, _ := strconv.Atoi(rec[8])
-		seatCapacity, _ := strconv.Atoi(rec[12])
-		durationStr := rec[13]
-		actualPrice, err := strconv.ParseFloat(rec[14], 64)
-		if err != nil {
-			log.Printf("Skipping record %d due to invalid price: %v", i, err)
-			continue
-		}
-		// Convert the duration string (H.MM format) to minutes.
-		durationMinutes, err := parseDuration(durationStr)
-		if err != nil {
-			log.Printf("Skipping record %d: %v", i, err)
-			continue
-		}
-
-		originCityEnc := encodeString(rec[0], originCityMap, &nextOriginCityCode)
-		destCityEnc := encodeString(rec[4], destinationCityMap, &nextDestCityCode)
-		airlineEnc := encodeString(rec[10], airlineMap, &nextAirlineCode)
-		routeClassEnc := encodeString(rec[9], routeClassMap, &nextRouteClassCode)
-		aircraftEnc := encodeString(rec[11], aircraftMap, &nextAircraftCode)
-
-		features := []float64{
-			1.0,
-			float64(originPop),
-			float64(destPop),
-			float64(routeFreq),
-			originCityEnc,
-			destCityEnc,
-			airlineEnc,
-			routeClassEnc,
-			aircraftEnc,
-			float64(seatCapacity),
-			float64(durationMinutes),
-		}
-		regModel.Train(regression.DataPoint(actualPrice, features))
-	}
-	regModel.Run()
-	fmt.Printf("Regression Formula:\n%v\n\n", regModel.Formula)
-
 	// ====================================================
 	// PART 2: PREDICTION REFINEMENT – Using CSV Records With Actual Price
 	// (Generate a table with full data where actual_price exists)
@@ -200,6 +198,10 @@ CREATE TABLE IF NOT EXISTS prediction_refinement (
 	if err != nil {
 		log.Fatalf("Error creating prediction_refinement table: %v", err)
 	}
+	// Only compare the model in use with the actual prices
+	if _, err := db.Exec(`DELETE FROM prediction_refinement`); err != nil {
+		log.Fatalf("Error clearing prediction_refinement table: %v", err)
+	}
 
 	// Prepare an INSERT statement for prediction_refinement.
 	insertRefinementStmt, err := db.Prepare(`
@@ -217,85 +219,9 @@ INSERT INTO prediction_refinement (
 	defer insertRefinementStmt.Close()
 
 	// Iterate over CSV records (with actual prices) to fill prediction_refinement.
-	for i := startRow; i < len(records); i++ {
-		rec := records[i]
-		if len(rec) < 15 {
-			continue
-		}
-
-		originPop, _ := strconv.Atoi(rec[3])
-		destPop, _ := strconv.Atoi(rec[7])
-		routeFreq, _ := strconv.Atoi(rec[8])
-		seatCapacity, _ := strconv.Atoi(rec[12])
-		durationStr := rec[13]
-		actualPrice, err := strconv.ParseFloat(rec[14], 64)
-		if err != nil {
-			log.Printf("Skipping record %d due to invalid price: %v", i, err)
-			continue
-		}
-		durationMinutes, err := parseDuration(durationStr)
-		if err != nil {
-			log.Printf("Skipping record %d: %v", i, err)
-			continue
-		}
-
-		originCityEnc := encodeString(rec[0], originCityMap, &nextOriginCityCode)
-		destCityEnc := encodeString(rec[4], destinationCityMap, &nextDestCityCode)
-		airlineEnc := encodeString(rec[10], airlineMap, &nextAirlineCode)
-		routeClassEnc := encodeString(rec[9], routeClassMap, &nextRouteClassCode)
-		aircraftEnc := encodeString(rec[11], aircraftMap, &nextAircraftCode)
-
-		features := []float64{
-			1.0,
-			float64(originPop),
-			float64(destPop),
-			float64(routeFreq),
-			originCityEnc,
-			destCityEnc,
-			airlineEnc,
-			routeClassEnc,
-			aircraftEnc,
-			float64(seatCapacity),
-			float64(durationMinutes),
-		}
-
-		predictedPrice, _ := regModel.Predict(features)
-		finalPrice := predictedPrice
-
-		// Apply boundary rules.
-		fd := durationMinutes
-		pricePerMinute := finalPrice / float64(fd)
-		randomMultiplier := rand.Float64()*(1.1-0.9) + 0.9
-		if fd > 240 {
-			randomMultiplier = rand.Float64()*(1.05-0.95) + 0.95
-			if pricePerMinute < 1.4 {
-				finalPrice = 1.4 * float64(fd) * randomMultiplier
-			}
-			if pricePerMinute > 2.3 {
-				finalPrice = 2.3 * float64(fd) * randomMultiplier
-			}
-		} else if fd <= 60 {
-			if pricePerMinute < 0.9 {
-				finalPrice = 60.0 * randomMultiplier
-			}
-			if finalPrice > 210 {
-				finalPrice = 0
-			}
-		} else if fd > 60 && fd <= 120 {
-			if pricePerMinute < 0.9 {
-				finalPrice = 0.9 * float64(fd) * randomMultiplier
-			}
-			if finalPrice > 240 {
-				finalPrice = 0
-			}
-		} else if fd > 120 && fd <= 240 {
-			if pricePerMinute < 0.9 {
-				finalPrice = 0.9 * float64(fd) * randomMultiplier
-			}
-			if finalPrice > 260 {
-				finalPrice = 0
-			}
-		}
+	for i, route := range routes {
+		actualPrice := route.ActualPrice
+		finalPrice := applyBoundaryRules(regModel.Predict(route), route.DurationMinutes)
 
 		priceDifference := finalPrice - actualPrice
 		var errorMultiple float64
@@ -316,20 +242,20 @@ INSERT INTO prediction_refinement (
 		}
 
 		_, err = insertRefinementStmt.Exec(
-			rec[0], // origin_city_name
-			rec[1], // origin_country
-			rec[2], // origin_iata
-			originPop,
-			rec[4], // destination_city_name
-			rec[5], // destination_country
-			rec[6], // destination_iata
-			destPop,
-			routeFreq,
-			rec[9],  // route_classification
-			rec[10], // most_common_airline
-			rec[11], // most_common_aircraft
-			seatCapacity,
-			rec[13], // duration_hour_dot_mins (text value from CSV)
+			route.OriginCity,
+			route.OriginCountry,
+			route.OriginIATA,
+			route.OriginPopulation,
+			route.DestinationCity,
+			route.DestinationCountry,
+			route.DestinationIATA,
+			route.DestinationPopulation,
+			route.RouteFrequency,
+			route.RouteClassification,
+			route.MostCommonAirline,
+			route.MostCommonAircraft,
+			route.SeatingCapacity,
+			route.DurationHourDotMins, // text value from CSV
 			actualPrice,
 			finalPrice,
 			priceDifference,
@@ -362,13 +288,24 @@ CREATE TABLE IF NOT EXISTS prediction (
 	most_common_aircraft TEXT,
 	most_common_aircraft_seating_capacity INTEGER,
 	duration_hour_dot_mins TEXT,
-	predicted_price REAL
+	predicted_price REAL,
+	model_version INTEGER,
+	price_low REAL,
+	price_high REAL,
+	model_price REAL,
+	correction REAL,
+	predicted_at TEXT,
+	modifiers TEXT,
+	modifier_factor REAL
 );
 `
 	_, err = db.Exec(createPredictionTableSQL)
 	if err != nil {
 		log.Fatalf("Error creating prediction table: %v", err)
 	}
+	if err := dbschema.EnsureColumns(db, "prediction", predictionColumns); err != nil {
+		log.Fatalf("Error migrating prediction table: %v", err)
+	}
 
 	// Begin a transaction for prediction inserts.
 	tx, err := db.Begin()
@@ -376,14 +313,19 @@ CREATE TABLE IF NOT EXISTS prediction (
 		log.Fatalf("Error beginning transaction: %v", err)
 	}
 
+	// Every route is predicted again, with a single model version
+	if _, err := tx.Exec(`DELETE FROM prediction`); err != nil {
+		log.Fatalf("Error clearing prediction table: %v", err)
+	}
+
 	insertPredictionStmt, err := tx.Prepare(`
 INSERT INTO prediction (
 	origin_city_name, origin_country, origin_iata, origin_population,
 	destination_city_name, destination_country, destination_iata, destination_population,
 	route_frequency, route_classification, most_common_airline, most_common_aircraft,
 	most_common_aircraft_seating_capacity, duration_hour_dot_mins,
-	predicted_price
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	predicted_price, model_version, price_low, price_high, model_price, correction, predicted_at, modifiers, modifier_factor
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 `)
 	if err != nil {
 		log.Fatalf("Error preparing insert statement for prediction: %v", err)
@@ -392,6 +334,24 @@ INSERT INTO prediction (
 
 	// Query every route from the routes table.
 	// We now select the text column "duration_hour_dot_mins".
+	// Prices are predicted for next week's trip, as of today
+	now := time.Now().UTC()
+	predictedAt := now.Format("2006-01-02 15:04:05")
+	travelDate := pricemodel.NextUsualTravelDate(now)
+	airports := loadAirports(pricemodel.DefaultTrainingSources(".").LocationsDB)
+
+	// Factors the backtest learnt from how this model's predictions compared with the
+	// prices seen later
+	corrections, err := pricemodel.LoadCorrections(db)
+	if err != nil {
+		log.Fatalf("Error reading price corrections: %v", err)
+	}
+	corrected := 0
+
+	// Holidays, events and peak seasons on the trip's dates
+	modifiers := loadModifiers("../../../../../data/generated/flight_price_modifiers.db")
+	modified := 0
+
 	routesRows, err := db.Query(`SELECT origin_city_name, origin_country, origin_iata, origin_population,
 		destination_city_name, destination_country, destination_iata, destination_population,
 		route_frequency, route_classification, most_common_airline, most_common_aircraft,
@@ -426,7 +386,7 @@ INSERT INTO prediction (
 		// Parse the duration from the text column.
 		var durationMinutes int
 		if durationHdotMins.Valid && durationHdotMins.String != "" {
-			durationMinutes, err = parseDuration(durationHdotMins.String)
+			durationMinutes, err = pricemodel.ParseDuration(durationHdotMins.String)
 			if err != nil {
 				log.Printf("Error parsing duration for route %s -> %s: %v", originIATA, destIATA, err)
 				// Use a fallback default (e.g., 120 minutes)
@@ -437,63 +397,56 @@ INSERT INTO prediction (
 			durationMinutes = 120
 		}
 
-		// Build the feature vector.
-		originCityEnc := encodeString(originCity, originCityMap, &nextOriginCityCode)
-		destCityEnc := encodeString(destCity, destinationCityMap, &nextDestCityCode)
-		airlineEnc := encodeString(commonAirline, airlineMap, &nextAirlineCode)
-		routeClassEnc := encodeString(routeClass, routeClassMap, &nextRouteClassCode)
-		aircraftEnc := encodeString(commonAircraft, aircraftMap, &nextAircraftCode)
-
-		features := []float64{
-			1.0,
-			float64(originPopulation),
-			float64(destPopulation),
-			float64(routeFreq),
-			originCityEnc,
-			destCityEnc,
-			airlineEnc,
-			routeClassEnc,
-			aircraftEnc,
-			float64(seatingCapacity),
-			float64(durationMinutes),
+		route := pricemodel.Route{
+			OriginCity:            originCity,
+			OriginCountry:         originCountry,
+			OriginIATA:            originIATA,
+			OriginPopulation:      originPopulation,
+			DestinationCity:       destCity,
+			DestinationCountry:    destCountry,
+			DestinationIATA:       destIATA,
+			DestinationPopulation: destPopulation,
+			RouteFrequency:        routeFreq,
+			RouteClassification:   routeClass,
+			MostCommonAirline:     commonAirline,
+			MostCommonAircraft:    commonAircraft,
+			SeatingCapacity:       seatingCapacity,
+			DurationMinutes:       durationMinutes,
+			DistanceKm:            airports.Distance(originIATA, destIATA),
+			TravelDate:            travelDate,
+			ObservedAt:            now,
+		}
+		// Correct the model's price and apply the trip's modifiers, then the same boundary rules.
+		modelPrice := regModel.Predict(route)
+		correction := corrections.For(route, regModel.Version)
+		if correction.Scope != "none" {
+			corrected++
 		}
+		correctedPrice := modelPrice * correction.Factor
+		modifiedPrice, applied := modifiers.Apply(route, correctedPrice)
+		if len(applied) > 0 {
+			modified++
+		}
+		// How much the modifiers moved the price, so the backtest can take it out again
+		// rather than learn a holiday's uplift into the route's correction
+		modifierFactor := 1.0
+		if len(applied) > 0 && correctedPrice > 0 {
+			modifierFactor = modifiedPrice / correctedPrice
+		}
+		finalPrice := applyBoundaryRules(modifiedPrice, durationMinutes)
 
-		predictedPrice, _ := regModel.Predict(features)
-		finalPrice := predictedPrice
-
-		// Apply the same boundary rules.
-		fd := durationMinutes
-		pricePerMinute := finalPrice / float64(fd)
-		randomMultiplier := rand.Float64()*(1.1-0.9) + 0.9
-		if fd > 240 {
-			randomMultiplier = rand.Float64()*(1.05-0.95) + 0.95
-			if pricePerMinute < 1.4 {
-				finalPrice = 1.4 * float64(fd) * randomMultiplier
-			}
-			if pricePerMinute > 2.3 {
-				finalPrice = 2.3 * float64(fd) * randomMultiplier
-			}
-		} else if fd <= 60 {
-			if pricePerMinute < 0.9 {
-				finalPrice = 60.0 * randomMultiplier
-			}
-			if finalPrice > 210 {
-				finalPrice = 0
-			}
-		} else if fd > 60 && fd <= 120 {
-			if pricePerMinute < 0.9 {
-				finalPrice = 0.9 * float64(fd) * randomMultiplier
-			}
-			if finalPrice > 240 {
-				finalPrice = 0
-			}
-		} else if fd > 120 && fd <= 240 {
-			if pricePerMinute < 0.9 {
-				finalPrice = 0.9 * float64(fd) * randomMultiplier
-			}
-			if finalPrice > 260 {
-				finalPrice = 0
-			}
+		// The modifiers applied, NULL for none
+		appliedModifiers := sql.NullString{String: applied.Describe(), Valid: len(applied) > 0}
+
+		// The boundary rules zero prices too implausible to show; store those as NULL, like
+		// any other missing price
+		predicted := sql.NullFloat64{Float64: finalPrice, Valid: finalPrice > 0}
+
+		// The range the actual price is likely in, NULL if the model has no intervals
+		var priceLow, priceHigh sql.NullFloat64
+		if low, high, ok := regModel.Interval(route, finalPrice); ok {
+			priceLow = sql.NullFloat64{Float64: low, Valid: true}
+			priceHigh = sql.NullFloat64{Float64: high, Valid: true}
 		}
 
 		// Use the original text value (or empty string) for duration_hour_dot_mins.
@@ -517,7 +470,15 @@ INSERT INTO prediction (
 			commonAircraft,
 			seatingCapacity,
 			durStr,
-			finalPrice,
+			predicted,
+			regModel.Version,
+			priceLow,
+			priceHigh,
+			modelPrice,
+			correction.Factor,
+			predictedAt,
+			appliedModifiers,
+			modifierFactor,
 		)
 		if err != nil {
 			log.Printf("Insert error in prediction for route %s -> %s: %v", originIATA, destIATA, err)
@@ -528,6 +489,62 @@ INSERT INTO prediction (
 	if err = tx.Commit(); err != nil {
 		log.Fatalf("Error committing transaction: %v", err)
 	}
+	fmt.Printf("Corrected %d predictions with factors learnt by the backtest\n", corrected)
+	fmt.Printf("Applied price modifiers to %d predictions, from %d modifiers\n", modified, len(modifiers))
+
+	if err := recordPredictionHistory(db); err != nil {
+		log.Fatalf("Error recording prediction history: %v", err)
+	}
 
 	fmt.Println("prediction_refinement and prediction tables updated in flight-prices.db")
 }
+
+// predictionHistoryDays is how long past predictions are kept for the backtest
+const predictionHistoryDays = 60
+
+// recordPredictionHistory keeps a copy of the predictions just made. compile/main/flights
+// pairs each price the fetcher sees later with the last prediction made before it.
+func recordPredictionHistory(db *sql.DB) error {
+	_, err := db.Exec(`
+CREATE TABLE IF NOT EXISTS prediction_history (
+	origin_iata TEXT,
+	destination_iata TEXT,
+	origin_country TEXT,
+	destination_country TEXT,
+	route_classification TEXT,
+	most_common_airline TEXT,
+	model_version INTEGER,
+	model_price REAL,
+	correction REAL,
+	predicted_price REAL,
+	price_low REAL,
+	price_high REAL,
+	predicted_at TEXT,
+	modifier_factor REAL
+)`)
+	if err != nil {
+		return err
+	}
+	// Histories from before modifiers have none; their predictions were the model's alone
+	if err := dbschema.EnsureColumns(db, "prediction_history", []dbschema.Column{{Name: "modifier_factor", Definition: "REAL"}}); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE INDEX IF NOT EXISTS prediction_history_route ON prediction_history (origin_iata, destination_iata, predicted_at)`,
+		`INSERT INTO prediction_history (
+		origin_iata, destination_iata, origin_country, destination_country, route_classification,
+		most_common_airline, model_version, model_price, correction, predicted_price, price_low, price_high, predicted_at, modifier_factor
+	)
+	SELECT origin_iata, destination_iata, origin_country, destination_country, route_classification,
+		most_common_airline, model_version, model_price, correction, predicted_price, price_low, price_high, predicted_at, modifier_factor
+	FROM prediction`,
+		fmt.Sprintf(`DELETE FROM prediction_history WHERE predicted_at < datetime('now', '-%d days')`, predictionHistoryDays),
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
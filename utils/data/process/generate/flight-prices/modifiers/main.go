@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/utils/data/process/generate/flight-prices/pricemodel"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+/*
+modifiers imports the price modifiers in price_modifiers.csv, school and public holidays,
+events and peak seasons, into flight_price_modifiers.db. generate/flight-prices applies
+them to its predictions from then on. Dated modifiers need adding for each new year.
+*/
+func main() {
+	csvPath := flag.String("csv", filepath.Join("..", "price_modifiers.csv"), "CSV of price modifiers")
+	dbPath := flag.String("db", "../../../../../../data/generated/flight_price_modifiers.db", "flight_price_modifiers.db to import them into")
+	dryRun := flag.Bool("dry-run", false, "Check the modifiers without importing them")
+	flag.Parse()
+
+	modifiers, err := pricemodel.ReadModifiersCSV(*csvPath)
+	if err != nil {
+		log.Fatalf("Error reading price modifiers: %v", err)
+	}
+
+	byKind := make(map[pricemodel.ModifierKind]int)
+	for _, m := range modifiers {
+		byKind[m.Kind]++
+	}
+	fmt.Printf("%d price modifiers: %d school holidays, %d public holidays, %d events, %d peak seasons\n",
+		len(modifiers), byKind[pricemodel.SchoolHoliday], byKind[pricemodel.PublicHoliday],
+		byKind[pricemodel.Event], byKind[pricemodel.PeakSeason])
+
+	// What the next predictions will be adjusted for, on some route
+	travelDate := pricemodel.NextUsualTravelDate(time.Now().UTC())
+	fmt.Printf("On the trip of %s:\n", travelDate.Format("2006-01-02"))
+	for _, m := range modifiers {
+		if m.AppliesTo(pricemodel.Route{OriginCountry: m.Country, DestinationCountry: m.Country, DestinationCity: m.City, TravelDate: travelDate}) {
+			fmt.Printf("  %-14s %-3s %s\n", m.Kind, m.Country, m)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run, price modifiers not imported.")
+		return
+	}
+	db, err := sql.Open("sqlite3", *dbPath)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", *dbPath, err)
+	}
+	defer db.Close()
+	if err := pricemodel.SaveModifiers(db, modifiers); err != nil {
+		log.Fatalf("Error saving price modifiers: %v", err)
+	}
+	fmt.Println("Imported price modifiers into price_modifier.")
+}
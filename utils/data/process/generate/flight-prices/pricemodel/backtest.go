@@ -0,0 +1,185 @@
+package pricemodel
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tris20/FairFareFinder/utils/common/dbschema"
+)
+
+// Outcome is a prediction next to the price later seen for the same route, as
+// compile/main/flights logs them in prediction_outcome. ActualPrice is the price seen,
+// at ObservedAt.
+type Outcome struct {
+	Route
+	ModelVersion   int
+	ModelPrice     float64 // The model's price, before its correction factor
+	Correction     float64
+	ModifierFactor float64 // How much the price modifiers moved the corrected price, 1 for none
+	PredictedPrice float64 // What visitors were shown
+}
+
+// ReadOutcomes reads the outcomes observed in the last days days. There are none until
+// compile/main/flights has logged some.
+func ReadOutcomes(db *sql.DB, days int) ([]Outcome, error) {
+	var exists int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'prediction_outcome'`).Scan(&exists)
+	if err != nil || exists == 0 {
+		return nil, err
+	}
+
+	// Outcomes logged before modifiers were recorded had none applied
+	columns, err := dbschema.Columns(db, "prediction_outcome")
+	if err != nil {
+		return nil, err
+	}
+	modifierFactorColumn := "1"
+	if columns["modifier_factor"] {
+		modifierFactorColumn = "COALESCE(modifier_factor, 1)"
+	}
+
+	rows, err := db.Query(`
+	SELECT origin_iata, destination_iata, COALESCE(origin_country, ''), COALESCE(destination_country, ''),
+		COALESCE(route_classification, ''), COALESCE(most_common_airline, ''), COALESCE(model_version, 0),
+		COALESCE(model_price, predicted_price), COALESCE(correction, 1), `+modifierFactorColumn+`,
+		predicted_price, observed_price, observed_at
+	FROM prediction_outcome
+	WHERE observed_at >= datetime('now', ?) AND predicted_price > 0 AND observed_price > 0`,
+		fmt.Sprintf("-%d days", days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []Outcome
+	for rows.Next() {
+		var o Outcome
+		var observedAt string
+		if err := rows.Scan(&o.OriginIATA, &o.DestinationIATA, &o.OriginCountry, &o.DestinationCountry,
+			&o.RouteClassification, &o.MostCommonAirline, &o.ModelVersion,
+			&o.ModelPrice, &o.Correction, &o.ModifierFactor, &o.PredictedPrice, &o.ActualPrice, &observedAt); err != nil {
+			return nil, err
+		}
+		// Left zero if unreadable; the price still counts, only not when it was seen
+		if t, err := time.Parse("2006-01-02 15:04:05", observedAt); err == nil {
+			o.ObservedAt = t
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
+
+// DistanceBand groups routes by length; "unknown" for airports without coordinates
+func DistanceBand(km float64) string {
+	switch {
+	case km <= 0:
+		return "unknown"
+	case km < 500:
+		return "< 500 km"
+	case km < 1500:
+		return "500-1500 km"
+	case km < 3000:
+		return "1500-3000 km"
+	default:
+		return "3000+ km"
+	}
+}
+
+// BacktestGroup is how predictions did for one group of routes
+type BacktestGroup struct {
+	Name        string
+	Shown       Metrics // The corrected prices visitors were shown
+	Model       Metrics // The model's prices before correction
+	MedianRatio float64 // Of seen to shown price; above 1 when predictions are too low
+}
+
+// Backtest is how predictions did against the prices seen later, overall and by each
+// way of grouping routes
+type Backtest struct {
+	Overall    BacktestGroup
+	Dimensions []string
+	Groups     map[string][]BacktestGroup // By dimension, most outcomes first
+}
+
+// backtestDimensions name the ways outcomes are grouped, and give each outcome's group
+var backtestDimensions = []struct {
+	name  string
+	group func(Outcome) string
+}{
+	{"Route class", func(o Outcome) string { return o.RouteClassification }},
+	{"Airline", func(o Outcome) string { return o.MostCommonAirline }},
+	{"Distance", func(o Outcome) string { return DistanceBand(o.DistanceKm) }},
+	{"Destination country", func(o Outcome) string { return o.DestinationCountry }},
+}
+
+func newBacktestGroup(name string, outcomes []Outcome) BacktestGroup {
+	var shown, model metricsAccumulator
+	ratios := make([]float64, 0, len(outcomes))
+	for _, o := range outcomes {
+		shown.add(o.PredictedPrice, o.ActualPrice)
+		model.add(o.ModelPrice, o.ActualPrice)
+		ratios = append(ratios, o.ActualPrice/o.PredictedPrice)
+	}
+	return BacktestGroup{Name: name, Shown: shown.metrics(), Model: model.metrics(), MedianRatio: median(ratios)}
+}
+
+// RunBacktest measures outcomes' predictions against the prices seen, overall and by
+// route class, airline, distance band and destination country
+func RunBacktest(outcomes []Outcome) Backtest {
+	b := Backtest{Overall: newBacktestGroup("All", outcomes), Groups: make(map[string][]BacktestGroup)}
+	for _, dimension := range backtestDimensions {
+		byGroup := make(map[string][]Outcome)
+		for _, o := range outcomes {
+			name := dimension.group(o)
+			if name == "" {
+				name = "unknown"
+			}
+			byGroup[name] = append(byGroup[name], o)
+		}
+		var groups []BacktestGroup
+		for name, groupOutcomes := range byGroup {
+			groups = append(groups, newBacktestGroup(name, groupOutcomes))
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			if groups[i].Shown.Count != groups[j].Shown.Count {
+				return groups[i].Shown.Count > groups[j].Shown.Count
+			}
+			return groups[i].Name < groups[j].Name
+		})
+		b.Dimensions = append(b.Dimensions, dimension.name)
+		b.Groups[dimension.name] = groups
+	}
+	return b
+}
+
+// Report formats the backtest as one table per dimension. maxGroups limits the lines
+// per table to the groups with the most outcomes; 0 shows them all.
+func (b Backtest) Report(maxGroups int) string {
+	var s strings.Builder
+	header := func(name string) {
+		fmt.Fprintf(&s, "%-28s %6s %10s %9s %10s %9s %8s\n", name, "Seen", "Shown MAE", "MAPE", "Model MAE", "MAPE", "Seen/est")
+	}
+	line := func(g BacktestGroup) {
+		fmt.Fprintf(&s, "%-28s %6d %10.2f %8.1f%% %10.2f %8.1f%% %7.2fx\n", g.Name, g.Shown.Count,
+			g.Shown.MAE, g.Shown.MAPE*100, g.Model.MAE, g.Model.MAPE*100, g.MedianRatio)
+	}
+
+	for _, dimension := range b.Dimensions {
+		header(dimension)
+		groups := b.Groups[dimension]
+		for i, g := range groups {
+			if maxGroups > 0 && i == maxGroups {
+				fmt.Fprintf(&s, "%-28s\n", fmt.Sprintf("... %d more", len(groups)-i))
+				break
+			}
+			line(g)
+		}
+		s.WriteString("\n")
+	}
+	header("Overall")
+	line(b.Overall)
+	return s.String()
+}
@@ -0,0 +1,88 @@
+package pricemodel
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestReadOutcomes reads outcomes logged by compile/main/flights, leaving out those seen
+// too long ago and those without a price
+func TestReadOutcomes(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "flight-prices.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// As logged before model prices, corrections and modifiers were recorded
+	_, err = db.Exec(`CREATE TABLE prediction_outcome (
+		origin_iata TEXT, destination_iata TEXT, origin_country TEXT, destination_country TEXT,
+		route_classification TEXT, most_common_airline TEXT, model_version INTEGER,
+		model_price REAL, correction REAL, predicted_price REAL, observed_price REAL, observed_at TEXT
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	recent := now.Add(-24 * time.Hour).Format("2006-01-02 15:04:05")
+	old := now.AddDate(0, 0, -40).Format("2006-01-02 15:04:05")
+	for _, row := range []struct {
+		origin   string
+		observed any
+		at       string
+	}{
+		{"BER", 120.0, recent},
+		{"HAM", 80.0, old},
+		{"MUC", nil, recent},
+	} {
+		_, err := db.Exec(`INSERT INTO prediction_outcome (origin_iata, destination_iata, route_classification, model_version,
+			predicted_price, observed_price, observed_at) VALUES (?, 'BCN', 'short', 1, 100, ?, ?)`, row.origin, row.observed, row.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outcomes, err := ReadOutcomes(db, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 {
+		t.Fatalf("read %d outcomes, want only BER's", len(outcomes))
+	}
+	o := outcomes[0]
+	if o.OriginIATA != "BER" || o.ActualPrice != 120 || o.PredictedPrice != 100 {
+		t.Errorf("read %+v", o)
+	}
+	// Without a model price the shown price stands in, uncorrected and unmodified
+	if o.ModelPrice != 100 || o.Correction != 1 || o.ModifierFactor != 1 {
+		t.Errorf("model price %v, correction %v and modifier factor %v, want 100, 1 and 1", o.ModelPrice, o.Correction, o.ModifierFactor)
+	}
+	if got := o.ObservedAt.Format("2006-01-02 15:04:05"); got != recent {
+		t.Errorf("observed at %s, want %s", got, recent)
+	}
+}
+
+// TestRunBacktest groups outcomes by each dimension, most outcomes first
+func TestRunBacktest(t *testing.T) {
+	var all []Outcome
+	all = append(all, outcomesFor("BER", "short", "ES", 1, 110, 90, 100)...)
+	all = append(all, outcomesFor("BER", "long", "", 1, 150)...)
+
+	b := RunBacktest(all)
+
+	if b.Overall.Shown.Count != 4 || b.Overall.Shown.MAE != 17.5 {
+		t.Errorf("overall %+v, want 4 outcomes with MAE 17.5", b.Overall.Shown)
+	}
+	classes := b.Groups["Route class"]
+	if len(classes) != 2 || classes[0].Name != "short" || classes[0].MedianRatio != 1 {
+		t.Errorf("route class groups %+v, want short first with a median ratio of 1", classes)
+	}
+	countries := b.Groups["Destination country"]
+	if len(countries) != 2 || countries[1].Name != "unknown" {
+		t.Errorf("destination country groups %+v, want the one without a country as unknown", countries)
+	}
+}
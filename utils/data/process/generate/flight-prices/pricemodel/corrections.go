@@ -0,0 +1,195 @@
+package pricemodel
+
+import (
+	"database/sql"
+	"math"
+)
+
+// A group needs this many outcomes before it gets a correction factor of its own
+const minCorrectionOutcomes = 3
+
+// correctionShrinkage pulls factors learnt from few outcomes towards 1: a group with this
+// many outcomes gets half its median error corrected
+const correctionShrinkage = 5.0
+
+// Factors are kept within this range, so a few odd prices cannot move a route far
+const (
+	minCorrectionFactor = 0.5
+	maxCorrectionFactor = 2.0
+)
+
+// Correction is a factor to multiply a model's price by
+type Correction struct {
+	Factor   float64
+	Outcomes int
+	Scope    string // "route", "route class and country", "route class" or "none"
+}
+
+var noCorrection = Correction{Factor: 1, Scope: "none"}
+
+// Corrections are factors learnt from how one model version's predictions compared with
+// the prices seen later. A route takes its own factor, or else that of similar routes:
+// the same route class to the same country, then the same route class.
+type Corrections struct {
+	ModelVersion   int
+	ByRoute        map[string]Correction
+	ByClassCountry map[string]Correction
+	ByClass        map[string]Correction
+}
+
+func routeCorrectionKey(r Route) string {
+	return r.OriginIATA + "|" + r.DestinationIATA
+}
+
+func classCountryCorrectionKey(r Route) string {
+	return r.RouteClassification + "|" + r.DestinationCountry
+}
+
+// For returns the correction for r's price from a model of the given version. Factors
+// learnt for another version do not apply.
+func (c Corrections) For(r Route, modelVersion int) Correction {
+	if c.ModelVersion != modelVersion {
+		return noCorrection
+	}
+	if correction, ok := c.ByRoute[routeCorrectionKey(r)]; ok {
+		return correction
+	}
+	if correction, ok := c.ByClassCountry[classCountryCorrectionKey(r)]; ok {
+		return correction
+	}
+	if correction, ok := c.ByClass[r.RouteClassification]; ok {
+		return correction
+	}
+	return noCorrection
+}
+
+// LearnCorrections learns factors from the outcomes of the newest model version among
+// them. Each is the median of seen over model price, shrunk towards 1 for groups with
+// few outcomes. The model price includes the modifiers applied to it, which are applied
+// again on top of the correction, so a route priced in the holidays does not learn them.
+func LearnCorrections(outcomes []Outcome) Corrections {
+	c := Corrections{
+		ByRoute:        make(map[string]Correction),
+		ByClassCountry: make(map[string]Correction),
+		ByClass:        make(map[string]Correction),
+	}
+	for _, o := range outcomes {
+		if o.ModelVersion > c.ModelVersion {
+			c.ModelVersion = o.ModelVersion
+		}
+	}
+
+	byRoute := make(map[string][]float64)
+	byClassCountry := make(map[string][]float64)
+	byClass := make(map[string][]float64)
+	for _, o := range outcomes {
+		if o.ModelVersion != c.ModelVersion || o.ModelPrice <= 0 {
+			continue
+		}
+		modifierFactor := o.ModifierFactor
+		if modifierFactor <= 0 {
+			modifierFactor = 1
+		}
+		ratio := o.ActualPrice / (o.ModelPrice * modifierFactor)
+		byRoute[routeCorrectionKey(o.Route)] = append(byRoute[routeCorrectionKey(o.Route)], ratio)
+		byClassCountry[classCountryCorrectionKey(o.Route)] = append(byClassCountry[classCountryCorrectionKey(o.Route)], ratio)
+		byClass[o.RouteClassification] = append(byClass[o.RouteClassification], ratio)
+	}
+
+	learn := func(groups map[string][]float64, into map[string]Correction, scope string) {
+		for key, ratios := range groups {
+			if len(ratios) < minCorrectionOutcomes {
+				continue
+			}
+			n := float64(len(ratios))
+			factor := math.Exp(math.Log(median(ratios)) * n / (n + correctionShrinkage))
+			factor = math.Max(minCorrectionFactor, math.Min(maxCorrectionFactor, factor))
+			into[key] = Correction{Factor: factor, Outcomes: len(ratios), Scope: scope}
+		}
+	}
+	learn(byRoute, c.ByRoute, "route")
+	learn(byClassCountry, c.ByClassCountry, "route class and country")
+	learn(byClass, c.ByClass, "route class")
+	return c
+}
+
+// Count is how many factors were learnt
+func (c Corrections) Count() int {
+	return len(c.ByRoute) + len(c.ByClassCountry) + len(c.ByClass)
+}
+
+// SaveCorrections replaces the factors in price_correction with c
+func SaveCorrections(db *sql.DB, c Corrections, learnedAt string) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS price_correction (
+		scope TEXT NOT NULL,
+		key TEXT NOT NULL,
+		factor REAL NOT NULL,
+		outcomes INTEGER NOT NULL,
+		model_version INTEGER NOT NULL,
+		learned_at TEXT,
+		PRIMARY KEY (scope, key)
+	)`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM price_correction`); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO price_correction (scope, key, factor, outcomes, model_version, learned_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, corrections := range []map[string]Correction{c.ByRoute, c.ByClassCountry, c.ByClass} {
+		for key, correction := range corrections {
+			if _, err := stmt.Exec(correction.Scope, key, correction.Factor, correction.Outcomes, c.ModelVersion, learnedAt); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadCorrections reads the factors in price_correction. There are none until the
+// backtest has learnt some.
+func LoadCorrections(db *sql.DB) (Corrections, error) {
+	c := Corrections{
+		ByRoute:        make(map[string]Correction),
+		ByClassCountry: make(map[string]Correction),
+		ByClass:        make(map[string]Correction),
+	}
+	var exists int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'price_correction'`).Scan(&exists)
+	if err != nil || exists == 0 {
+		return c, err
+	}
+
+	rows, err := db.Query(`SELECT scope, key, factor, outcomes, model_version FROM price_correction`)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var correction Correction
+		var key string
+		if err := rows.Scan(&correction.Scope, &key, &correction.Factor, &correction.Outcomes, &c.ModelVersion); err != nil {
+			return c, err
+		}
+		switch correction.Scope {
+		case "route":
+			c.ByRoute[key] = correction
+		case "route class and country":
+			c.ByClassCountry[key] = correction
+		case "route class":
+			c.ByClass[key] = correction
+		}
+	}
+	return c, rows.Err()
+}
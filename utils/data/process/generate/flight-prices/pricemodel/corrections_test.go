@@ -0,0 +1,129 @@
+package pricemodel
+
+import (
+	"math"
+	"testing"
+)
+
+// outcomesFor returns an outcome for each price seen on a route whose model said 100
+func outcomesFor(origin, class, country string, modelVersion int, seen ...float64) []Outcome {
+	var made []Outcome
+	for _, price := range seen {
+		made = append(made, Outcome{
+			Route: Route{
+				OriginIATA:          origin,
+				DestinationIATA:     "BCN",
+				DestinationCountry:  country,
+				RouteClassification: class,
+				ActualPrice:         price,
+			},
+			ModelVersion:   modelVersion,
+			ModelPrice:     100,
+			Correction:     1,
+			PredictedPrice: 100,
+		})
+	}
+	return made
+}
+
+// TestLearnCorrectionsShrinkage corrects half of the median error of a group with as many
+// outcomes as correctionShrinkage, and leaves groups with too few outcomes alone
+func TestLearnCorrectionsShrinkage(t *testing.T) {
+	var all []Outcome
+	all = append(all, outcomesFor("BER", "short", "ES", 2, 110, 120, 120, 120, 130)...)
+	all = append(all, outcomesFor("HAM", "long", "PT", 2, 200, 200)...)
+
+	c := LearnCorrections(all)
+
+	route := c.ByRoute["BER|BCN"]
+	if want := math.Sqrt(1.2); route.Outcomes != 5 || math.Abs(route.Factor-want) > 1e-9 {
+		t.Errorf("BER route correction %+v, want factor %v from 5 outcomes", route, want)
+	}
+	if _, ok := c.ByRoute["HAM|BCN"]; ok {
+		t.Errorf("HAM route has a correction from %d outcomes", 2)
+	}
+	if _, ok := c.ByClass["long"]; ok {
+		t.Errorf("long routes have a correction from %d outcomes", 2)
+	}
+}
+
+// TestLearnCorrectionsClamp keeps factors within minCorrectionFactor and maxCorrectionFactor
+func TestLearnCorrectionsClamp(t *testing.T) {
+	var all []Outcome
+	for i := 0; i < 100; i++ {
+		all = append(all, outcomesFor("BER", "short", "ES", 1, 1000)...)
+		all = append(all, outcomesFor("HAM", "long", "PT", 1, 10)...)
+	}
+
+	c := LearnCorrections(all)
+
+	if got := c.ByRoute["BER|BCN"].Factor; got != maxCorrectionFactor {
+		t.Errorf("prices 10 times the model's learnt %v, want %v", got, maxCorrectionFactor)
+	}
+	if got := c.ByRoute["HAM|BCN"].Factor; got != minCorrectionFactor {
+		t.Errorf("prices a tenth of the model's learnt %v, want %v", got, minCorrectionFactor)
+	}
+}
+
+// TestCorrectionsFor falls back from the route to its class and country, then its class,
+// and only applies to the model version the factors were learnt for
+func TestCorrectionsFor(t *testing.T) {
+	var all []Outcome
+	all = append(all, outcomesFor("BER", "short", "ES", 3, 150, 150, 150)...)
+	all = append(all, outcomesFor("HAM", "short", "ES", 3, 150, 150, 150)...)
+	all = append(all, outcomesFor("MUC", "short", "PT", 3, 150, 150, 150)...)
+	// Outcomes of an older model say nothing about the newest one
+	all = append(all, outcomesFor("CGN", "short", "ES", 2, 50, 50, 50)...)
+
+	c := LearnCorrections(all)
+	if c.ModelVersion != 3 {
+		t.Fatalf("learnt for model version %d, want 3", c.ModelVersion)
+	}
+
+	tests := []struct {
+		name    string
+		route   Route
+		version int
+		scope   string
+	}{
+		{"own route", Route{OriginIATA: "BER", DestinationIATA: "BCN", RouteClassification: "short", DestinationCountry: "ES"}, 3, "route"},
+		{"same class and country", Route{OriginIATA: "CGN", DestinationIATA: "BCN", RouteClassification: "short", DestinationCountry: "ES"}, 3, "route class and country"},
+		{"same class", Route{OriginIATA: "CGN", DestinationIATA: "OPO", RouteClassification: "short", DestinationCountry: "IT"}, 3, "route class"},
+		{"nothing alike", Route{OriginIATA: "CGN", DestinationIATA: "JFK", RouteClassification: "long", DestinationCountry: "US"}, 3, "none"},
+		{"another model version", Route{OriginIATA: "BER", DestinationIATA: "BCN", RouteClassification: "short", DestinationCountry: "ES"}, 2, "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.For(tt.route, tt.version)
+			if got.Scope != tt.scope {
+				t.Errorf("scope %q, want %q", got.Scope, tt.scope)
+			}
+			if tt.scope == "none" && got.Factor != 1 {
+				t.Errorf("factor %v without a correction, want 1", got.Factor)
+			}
+		})
+	}
+	if got := c.ByClassCountry["short|ES"]; got.Outcomes != 6 {
+		t.Errorf("short routes to ES learnt from %d outcomes, want the 6 of model version 3", got.Outcomes)
+	}
+}
+
+// TestLearnCorrectionsNetOfModifiers corrects only what the modifiers on a price did not
+// already account for
+func TestLearnCorrectionsNetOfModifiers(t *testing.T) {
+	all := outcomesFor("BER", "short", "ES", 1, 130, 130, 130)
+	for i := range all {
+		all[i].ModifierFactor = 1.3
+	}
+	// Outcomes logged before modifiers have no factor and count as unmodified
+	all = append(all, outcomesFor("HAM", "short", "ES", 1, 130, 130, 130)...)
+
+	c := LearnCorrections(all)
+
+	if got := c.ByRoute["BER|BCN"].Factor; math.Abs(got-1) > 1e-9 {
+		t.Errorf("prices as dear as the modifier made them learnt %v, want 1", got)
+	}
+	if got := c.ByRoute["HAM|BCN"].Factor; got <= 1 {
+		t.Errorf("unmodified prices dearer than the model's learnt %v, want more than 1", got)
+	}
+}
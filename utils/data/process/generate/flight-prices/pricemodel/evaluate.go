@@ -0,0 +1,188 @@
+package pricemodel
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Metrics measures predictions against actual prices
+type Metrics struct {
+	Count int     `json:"count"`
+	MAE   float64 `json:"mae"`  // Mean absolute error, in euros
+	MAPE  float64 `json:"mape"` // Mean absolute percentage error, 0.25 for 25%
+}
+
+// Comparison puts the model next to the baseline on the same routes
+type Comparison struct {
+	Model    Metrics `json:"model"`
+	Baseline Metrics `json:"baseline"`
+}
+
+// Evaluation is how the model and the baseline did on the validation routes,
+// overall and per route class
+type Evaluation struct {
+	Baseline     string                `json:"baseline"`
+	Overall      Comparison            `json:"overall"`
+	ByRouteClass map[string]Comparison `json:"by_route_class"`
+}
+
+// Baseline predicts the median training price of the route's class, or of all routes
+// for a class it has not seen. Anything worth using has to beat it.
+type Baseline struct {
+	ByRouteClass map[string]float64
+	Overall      float64
+}
+
+const baselineName = "route class median"
+
+func newBaseline(routes []Route) Baseline {
+	byClass := make(map[string][]float64)
+	var all []float64
+	for _, r := range routes {
+		byClass[r.RouteClassification] = append(byClass[r.RouteClassification], r.ActualPrice)
+		all = append(all, r.ActualPrice)
+	}
+	b := Baseline{ByRouteClass: make(map[string]float64), Overall: median(all)}
+	for class, prices := range byClass {
+		b.ByRouteClass[class] = median(prices)
+	}
+	return b
+}
+
+// Predict returns the baseline price for r
+func (b Baseline) Predict(r Route) float64 {
+	if price, ok := b.ByRouteClass[r.RouteClassification]; ok {
+		return price
+	}
+	return b.Overall
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
+
+// metricsAccumulator sums errors. Routes with no actual price are left out of MAPE.
+type metricsAccumulator struct {
+	count, percentCount  int
+	absError, absPercent float64
+}
+
+func (a *metricsAccumulator) add(predicted, actual float64) {
+	a.count++
+	a.absError += math.Abs(predicted - actual)
+	if actual > 0 {
+		a.percentCount++
+		a.absPercent += math.Abs(predicted-actual) / actual
+	}
+}
+
+func (a metricsAccumulator) metrics() Metrics {
+	m := Metrics{Count: a.count}
+	if a.count > 0 {
+		m.MAE = a.absError / float64(a.count)
+	}
+	if a.percentCount > 0 {
+		m.MAPE = a.absPercent / float64(a.percentCount)
+	}
+	return m
+}
+
+// Evaluate compares the model's predictions for routes, and the baseline's, with their actual prices
+func Evaluate(m *Model, baseline Baseline, routes []Route) Evaluation {
+	var modelAll, baselineAll metricsAccumulator
+	modelByClass := make(map[string]*metricsAccumulator)
+	baselineByClass := make(map[string]*metricsAccumulator)
+
+	for _, r := range routes {
+		predicted, base := m.Predict(r), baseline.Predict(r)
+		modelAll.add(predicted, r.ActualPrice)
+		baselineAll.add(base, r.ActualPrice)
+
+		class := r.RouteClassification
+		if modelByClass[class] == nil {
+			modelByClass[class] = &metricsAccumulator{}
+			baselineByClass[class] = &metricsAccumulator{}
+		}
+		modelByClass[class].add(predicted, r.ActualPrice)
+		baselineByClass[class].add(base, r.ActualPrice)
+	}
+
+	e := Evaluation{
+		Baseline:     baselineName,
+		Overall:      Comparison{Model: modelAll.metrics(), Baseline: baselineAll.metrics()},
+		ByRouteClass: make(map[string]Comparison),
+	}
+	for class := range modelByClass {
+		e.ByRouteClass[class] = Comparison{Model: modelByClass[class].metrics(), Baseline: baselineByClass[class].metrics()}
+	}
+	return e
+}
+
+// Split shuffles routes with seed and holds back validationShare of them for validation.
+// The same seed always gives the same split.
+func Split(routes []Route, validationShare float64, seed int64) (train, validation []Route) {
+	shuffled := append([]Route(nil), routes...)
+	rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	n := int(math.Round(float64(len(shuffled)) * validationShare))
+	return shuffled[n:], shuffled[:n]
+}
+
+// Train fits a model on all but validationShare of routes, and evaluates it and the
+// baseline on the rest, which also give its prediction intervals. The model is not yet versioned; Registry.Save does that.
+func Train(routes []Route, validationShare float64, seed int64, lambda float64) (*Model, error) {
+	train, validation := Split(routes, validationShare, seed)
+	if len(train) < 2 {
+		return nil, fmt.Errorf("need at least 2 training routes, have %d", len(train))
+	}
+
+	m, err := fit(train, lambda)
+	if err != nil {
+		return nil, err
+	}
+	m.TrainedAt = time.Now().UTC().Format("2006-01-02 15:04:05")
+	m.TrainingRows = len(train)
+	m.ValidationRows = len(validation)
+	m.Seed = seed
+	m.Evaluation = Evaluate(m, newBaseline(train), validation)
+	if m.Intervals, err = newIntervals(m, validation, DefaultIntervalCoverage); err != nil {
+		fmt.Printf("No prediction intervals: %v\n", err)
+	}
+	return m, nil
+}
+
+// Report formats the evaluation as a table, one line per route class
+func (e Evaluation) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-28s %6s %10s %9s %10s %9s\n", "Route class", "Routes", "Model MAE", "MAPE", "Base MAE", "MAPE")
+	line := func(name string, c Comparison) {
+		fmt.Fprintf(&b, "%-28s %6d %10.2f %8.1f%% %10.2f %8.1f%%\n", name, c.Model.Count,
+			c.Model.MAE, c.Model.MAPE*100, c.Baseline.MAE, c.Baseline.MAPE*100)
+	}
+
+	classes := make([]string, 0, len(e.ByRouteClass))
+	for class := range e.ByRouteClass {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		line(class, e.ByRouteClass[class])
+	}
+	line("All", e.Overall)
+	fmt.Fprintf(&b, "Baseline: %s\n", e.Baseline)
+	return b.String()
+}
@@ -0,0 +1,254 @@
+package pricemodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// targetSmoothing is how many routes' worth of weight the overall mean price gets when
+// target encoding a category value, so values seen on a route or two stay near it
+const targetSmoothing = 10
+
+// The categories and how they are encoded. The few route classes, weekdays and months
+// are one-hot encoded. Cities, airlines and aircraft are too many for the training data
+// to cover, so they are target encoded: replaced by the smoothed mean price of the
+// routes that have them.
+var (
+	oneHotCategories = []string{"route_class", "weekday", "month"}
+	targetCategories = []string{"origin_city", "destination_city", "airline", "aircraft"}
+	numericFeatures  = []string{
+		"origin_population",
+		"destination_population",
+		"route_frequency",
+		"seating_capacity",
+		"duration_minutes",
+		"distance_km",
+		"lead_days",
+		"has_dates",
+	}
+)
+
+// category returns r's value of a one-hot or target encoded category. Weekday and month
+// are empty when the price has no date, which encodes as none of the weekdays or months.
+func category(r Route, name string) string {
+	switch name {
+	case "route_class":
+		return r.RouteClassification
+	case "weekday":
+		if r.HasDates() {
+			return r.TravelDate.Weekday().String()
+		}
+	case "month":
+		if r.HasDates() {
+			return r.TravelDate.Month().String()
+		}
+	case "origin_city":
+		return r.OriginCity + ", " + r.OriginCountry
+	case "destination_city":
+		return r.DestinationCity + ", " + r.DestinationCountry
+	case "airline":
+		return r.MostCommonAirline
+	case "aircraft":
+		return r.MostCommonAircraft
+	}
+	return ""
+}
+
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// TargetEncoding is the smoothed mean price of each value of a category
+type TargetEncoding struct {
+	Overall float64            `json:"overall"`
+	Means   map[string]float64 `json:"means"`
+}
+
+// Column is one model input, standardized with the training mean and standard deviation
+type Column struct {
+	Name string  `json:"name"`
+	Mean float64 `json:"mean"`
+	Std  float64 `json:"std"`
+}
+
+// Pipeline turns a Route into model inputs. Everything it learns comes from the
+// training routes, so the same route always gets the same inputs.
+type Pipeline struct {
+	// The one-hot levels seen in training, less the first, which all zeros stands for
+	OneHot          map[string][]string       `json:"one_hot"`
+	TargetEncodings map[string]TargetEncoding `json:"target_encodings"`
+	// Stands in for the distance of routes whose airports have no coordinates
+	MeanDistanceKm float64 `json:"mean_distance_km"`
+	// Only the columns that varied in training; the rest can't be learned from
+	Columns []Column `json:"columns"`
+}
+
+// raw returns every candidate input of r by name, before standardizing
+func (p *Pipeline) raw(r Route) map[string]float64 {
+	distance := r.DistanceKm
+	if distance <= 0 {
+		distance = p.MeanDistanceKm
+	}
+	values := map[string]float64{
+		"origin_population":      float64(r.OriginPopulation),
+		"destination_population": float64(r.DestinationPopulation),
+		"route_frequency":        float64(r.RouteFrequency),
+		"seating_capacity":       float64(r.SeatingCapacity),
+		"duration_minutes":       float64(r.DurationMinutes),
+		"distance_km":            distance,
+		"lead_days":              r.LeadDays(),
+		"has_dates":              boolFeature(r.HasDates()),
+	}
+	for _, name := range oneHotCategories {
+		value := category(r, name)
+		for _, level := range p.OneHot[name] {
+			values[name+"="+level] = boolFeature(value == level)
+		}
+	}
+	for _, name := range targetCategories {
+		enc := p.TargetEncodings[name]
+		mean, ok := enc.Means[category(r, name)]
+		if !ok {
+			mean = enc.Overall
+		}
+		values[name+"_mean_price"] = mean
+	}
+	return values
+}
+
+// Transform returns the standardized inputs of r, in the order of p.Columns
+func (p *Pipeline) Transform(r Route) []float64 {
+	values := p.raw(r)
+	features := make([]float64, len(p.Columns))
+	for i, c := range p.Columns {
+		features[i] = (values[c.Name] - c.Mean) / c.Std
+	}
+	return features
+}
+
+// Names lists the inputs, in the order Transform returns them
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.Columns))
+	for i, c := range p.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// fitPipeline learns the encodings, the mean distance and the standardization from routes
+func fitPipeline(routes []Route) (*Pipeline, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no routes to fit features on")
+	}
+	p := &Pipeline{OneHot: make(map[string][]string), TargetEncodings: make(map[string]TargetEncoding)}
+
+	for _, name := range oneHotCategories {
+		seen := make(map[string]bool)
+		for _, r := range routes {
+			if value := category(r, name); value != "" {
+				seen[value] = true
+			}
+		}
+		levels := make([]string, 0, len(seen))
+		for level := range seen {
+			levels = append(levels, level)
+		}
+		sort.Strings(levels)
+		// Routes without a date already encode as all zeros, so every level is kept for them
+		if len(levels) > 0 && (name == "route_class" || allDated(routes)) {
+			levels = levels[1:]
+		}
+		p.OneHot[name] = levels
+	}
+
+	var prices []float64
+	for _, r := range routes {
+		prices = append(prices, r.ActualPrice)
+	}
+	overall := mean(prices)
+	for _, name := range targetCategories {
+		sums := make(map[string]float64)
+		counts := make(map[string]int)
+		for _, r := range routes {
+			value := category(r, name)
+			sums[value] += r.ActualPrice
+			counts[value]++
+		}
+		enc := TargetEncoding{Overall: overall, Means: make(map[string]float64, len(sums))}
+		for value, sum := range sums {
+			enc.Means[value] = (sum + targetSmoothing*overall) / (float64(counts[value]) + targetSmoothing)
+		}
+		p.TargetEncodings[name] = enc
+	}
+
+	var distances []float64
+	for _, r := range routes {
+		if r.DistanceKm > 0 {
+			distances = append(distances, r.DistanceKm)
+		}
+	}
+	p.MeanDistanceKm = mean(distances)
+
+	// Standardize every candidate column, leaving out those that never vary
+	candidates := append([]string(nil), numericFeatures...)
+	for _, name := range oneHotCategories {
+		for _, level := range p.OneHot[name] {
+			candidates = append(candidates, name+"="+level)
+		}
+	}
+	for _, name := range targetCategories {
+		candidates = append(candidates, name+"_mean_price")
+	}
+	rawValues := make([]map[string]float64, len(routes))
+	for i, r := range routes {
+		rawValues[i] = p.raw(r)
+	}
+	for _, name := range candidates {
+		column := make([]float64, len(routes))
+		for i := range routes {
+			column[i] = rawValues[i][name]
+		}
+		m, std := mean(column), stdDev(column)
+		if std < 1e-9 {
+			continue
+		}
+		p.Columns = append(p.Columns, Column{Name: name, Mean: m, Std: std})
+	}
+	return p, nil
+}
+
+func allDated(routes []Route) bool {
+	for _, r := range routes {
+		if !r.HasDates() {
+			return false
+		}
+	}
+	return true
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
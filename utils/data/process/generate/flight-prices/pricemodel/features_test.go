@@ -0,0 +1,111 @@
+package pricemodel
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestTargetEncodingSmoothing pulls the mean price of a rarely seen value towards the
+// overall mean, and encodes unseen values as the overall mean
+func TestTargetEncodingSmoothing(t *testing.T) {
+	routes := []Route{
+		{MostCommonAirline: "Often", ActualPrice: 100},
+		{MostCommonAirline: "Often", ActualPrice: 100},
+		{MostCommonAirline: "Often", ActualPrice: 100},
+		{MostCommonAirline: "Once", ActualPrice: 300},
+	}
+	p, err := fitPipeline(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := p.TargetEncodings["airline"]
+	if enc.Overall != 150 {
+		t.Errorf("overall mean %v, want 150", enc.Overall)
+	}
+	for airline, want := range map[string]float64{
+		"Often": (300.0 + targetSmoothing*150) / (3 + targetSmoothing),
+		"Once":  (300.0 + targetSmoothing*150) / (1 + targetSmoothing),
+	} {
+		if math.Abs(enc.Means[airline]-want) > 1e-9 {
+			t.Errorf("%s encodes as %v, want %v", airline, enc.Means[airline], want)
+		}
+	}
+	if got := p.raw(Route{MostCommonAirline: "Never"})["airline_mean_price"]; got != enc.Overall {
+		t.Errorf("unseen airline encodes as %v, want the overall mean %v", got, enc.Overall)
+	}
+}
+
+// TestTrainEncodesTrainingRoutesOnly keeps the validation routes' prices out of the
+// target encodings, so the evaluation is of routes the model has not seen
+func TestTrainEncodesTrainingRoutesOnly(t *testing.T) {
+	routes := syntheticRoutes(100)
+	// Every route flies to a city of its own, so any validation city in the encoding leaked
+	for i := range routes {
+		routes[i].DestinationCity = routes[i].DestinationIATA + string(rune('A'+i%26)) + string(rune('A'+i/26))
+	}
+	m, err := Train(routes, 0.2, 7, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	train, validation := Split(routes, 0.2, 7)
+
+	means := m.Pipeline.TargetEncodings["destination_city"].Means
+	for _, r := range validation {
+		if _, ok := means[category(r, "destination_city")]; ok {
+			t.Errorf("validation destination %s is in the target encoding", r.DestinationCity)
+		}
+	}
+	if len(means) != len(train) {
+		t.Errorf("%d destinations encoded, want the %d training routes'", len(means), len(train))
+	}
+	var prices []float64
+	for _, r := range train {
+		prices = append(prices, r.ActualPrice)
+	}
+	if overall := m.Pipeline.TargetEncodings["destination_city"].Overall; math.Abs(overall-mean(prices)) > 1e-9 {
+		t.Errorf("overall mean %v, want the training mean %v", overall, mean(prices))
+	}
+}
+
+// TestOneHotLevels drops the first route class, which all zeros stands for, but keeps
+// every weekday when some prices have no date and already encode as all zeros
+func TestOneHotLevels(t *testing.T) {
+	friday := time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC)
+	seen := friday.AddDate(0, 0, -10)
+	routes := []Route{
+		{RouteClassification: "long", TravelDate: friday, ObservedAt: seen, ActualPrice: 200},
+		{RouteClassification: "short", TravelDate: friday.AddDate(0, 0, 1), ObservedAt: seen, ActualPrice: 50},
+		{RouteClassification: "medium", ActualPrice: 100},
+	}
+	p, err := fitPipeline(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"medium", "short"}; !reflect.DeepEqual(p.OneHot["route_class"], want) {
+		t.Errorf("route class levels %v, want %v", p.OneHot["route_class"], want)
+	}
+	if want := []string{"Friday", "Saturday"}; !reflect.DeepEqual(p.OneHot["weekday"], want) {
+		t.Errorf("weekday levels %v, want %v", p.OneHot["weekday"], want)
+	}
+	// Inputs that never vary in training cannot be learned from and are left out
+	for _, name := range p.Names() {
+		if name == "seating_capacity" {
+			t.Errorf("seating_capacity is a column though it never varies")
+		}
+	}
+}
+
+// TestDistanceFallback gives routes between airports without coordinates the mean distance
+func TestDistanceFallback(t *testing.T) {
+	p, err := fitPipeline([]Route{{DistanceKm: 1000, ActualPrice: 1}, {DistanceKm: 3000, ActualPrice: 2}, {ActualPrice: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.raw(Route{})["distance_km"]; got != 2000 {
+		t.Errorf("unknown distance is %v, want the mean 2000", got)
+	}
+}
@@ -0,0 +1,69 @@
+package pricemodel
+
+import (
+	"database/sql"
+	"math"
+)
+
+// Haversine is the great-circle distance in kilometres between two coordinates,
+// as process/calculate/flights/flight-duration works it out
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0 // Earth radius in kilometers
+	lat1, lon1, lat2, lon2 = degreesToRadians(lat1), degreesToRadians(lon1), degreesToRadians(lat2), degreesToRadians(lon2)
+	dlat := lat2 - lat1
+	dlon := lon2 - lon1
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadius * c
+}
+
+func degreesToRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Coordinates is the latitude and longitude of an airport
+type Coordinates struct {
+	Lat, Lon float64
+}
+
+// Airports maps IATA codes to coordinates
+type Airports map[string]Coordinates
+
+// LoadAirports reads the coordinates of every airport in the locations database
+func LoadAirports(locationsDB *sql.DB) (Airports, error) {
+	rows, err := locationsDB.Query(`SELECT iata, lat, lon FROM airport WHERE iata IS NOT NULL AND iata <> '' AND lat IS NOT NULL AND lon IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	airports := make(Airports)
+	for rows.Next() {
+		var iata string
+		var c Coordinates
+		if err := rows.Scan(&iata, &c.Lat, &c.Lon); err != nil {
+			return nil, err
+		}
+		airports[iata] = c
+	}
+	return airports, rows.Err()
+}
+
+// Distance is the great-circle distance between two airports, 0 if either has no coordinates
+func (a Airports) Distance(originIATA, destinationIATA string) float64 {
+	origin, ok1 := a[originIATA]
+	destination, ok2 := a[destinationIATA]
+	if !ok1 || !ok2 {
+		return 0
+	}
+	return Haversine(origin.Lat, origin.Lon, destination.Lat, destination.Lon)
+}
+
+// AddDistances sets DistanceKm of every route whose airports both have coordinates
+func (a Airports) AddDistances(routes []Route) {
+	for i, r := range routes {
+		routes[i].DistanceKm = a.Distance(r.OriginIATA, r.DestinationIATA)
+	}
+}
@@ -0,0 +1,115 @@
+package pricemodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultIntervalCoverage is the share of actual prices a prediction interval should
+// hold: 0.8 puts its bounds at the 10th and 90th percentile of the model's error.
+const DefaultIntervalCoverage = 0.8
+
+// minIntervalResiduals is how many validation routes a route class needs for an interval
+// of its own. Classes with fewer use the interval of all routes.
+const minIntervalResiduals = 10
+
+// Interval bounds the actual price as multiples of the predicted price, so a prediction
+// of 100 with Low 0.8 and High 1.2 is €80–120
+type Interval struct {
+	Count int     `json:"count"`
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+}
+
+// Intervals are the model's prediction intervals, taken from the quantiles of
+// actual/predicted price on the validation routes, overall and per route class
+type Intervals struct {
+	Coverage     float64             `json:"coverage"`
+	Overall      Interval            `json:"overall"`
+	ByRouteClass map[string]Interval `json:"by_route_class"`
+}
+
+// newIntervals measures how far off the model was on routes it had not seen. Routes
+// without an actual price, or with a prediction that is not positive, say nothing
+// about the ratio and are left out.
+func newIntervals(m *Model, routes []Route, coverage float64) (*Intervals, error) {
+	var all []float64
+	byClass := make(map[string][]float64)
+	for _, r := range routes {
+		predicted := m.Predict(r)
+		if predicted <= 0 || r.ActualPrice <= 0 {
+			continue
+		}
+		ratio := r.ActualPrice / predicted
+		all = append(all, ratio)
+		byClass[r.RouteClassification] = append(byClass[r.RouteClassification], ratio)
+	}
+	if len(all) < minIntervalResiduals {
+		return nil, fmt.Errorf("need at least %d validation routes for prediction intervals, have %d", minIntervalResiduals, len(all))
+	}
+
+	tail := (1 - coverage) / 2
+	intervals := &Intervals{
+		Coverage:     coverage,
+		Overall:      newInterval(all, tail),
+		ByRouteClass: make(map[string]Interval),
+	}
+	for class, ratios := range byClass {
+		if len(ratios) >= minIntervalResiduals {
+			intervals.ByRouteClass[class] = newInterval(ratios, tail)
+		}
+	}
+	return intervals, nil
+}
+
+func newInterval(ratios []float64, tail float64) Interval {
+	sorted := append([]float64(nil), ratios...)
+	sort.Float64s(sorted)
+	return Interval{
+		Count: len(sorted),
+		Low:   math.Min(quantile(sorted, tail), 1),
+		High:  math.Max(quantile(sorted, 1-tail), 1),
+	}
+}
+
+// quantile interpolates the q quantile of sorted values
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	i := int(math.Floor(pos))
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// For returns the interval of r's route class, or of all routes if the class had too
+// few validation routes
+func (iv *Intervals) For(r Route) Interval {
+	if interval, ok := iv.ByRouteClass[r.RouteClassification]; ok {
+		return interval
+	}
+	return iv.Overall
+}
+
+// Report formats the intervals as a table, one line per route class
+func (iv *Intervals) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-28s %6s %8s %8s\n", "Route class", "Routes", "Low", "High")
+	classes := make([]string, 0, len(iv.ByRouteClass))
+	for class := range iv.ByRouteClass {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		interval := iv.ByRouteClass[class]
+		fmt.Fprintf(&b, "%-28s %6d %7.2fx %7.2fx\n", class, interval.Count, interval.Low, interval.High)
+	}
+	fmt.Fprintf(&b, "%-28s %6d %7.2fx %7.2fx\n", "All", iv.Overall.Count, iv.Overall.Low, iv.Overall.High)
+	fmt.Fprintf(&b, "Coverage: %.0f%% of actual prices\n", iv.Coverage*100)
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package pricemodel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0, 1},
+		{0.1, 1.4},
+		{0.25, 2},
+		{0.5, 3},
+		{0.9, 4.6},
+		{1, 5},
+	}
+	for _, tt := range tests {
+		if got := quantile(sorted, tt.q); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("quantile %v is %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := quantile(nil, 0.5); got != 0 {
+		t.Errorf("quantile of nothing is %v, want 0", got)
+	}
+}
+
+// flatModel predicts price for every route
+func flatModel(price float64) *Model {
+	return &Model{Pipeline: &Pipeline{}, Coefficients: []float64{price}}
+}
+
+// routesPriced returns a route of class for each price
+func routesPriced(class string, prices ...float64) []Route {
+	routes := make([]Route, len(prices))
+	for i, price := range prices {
+		routes[i] = Route{RouteClassification: class, ActualPrice: price}
+	}
+	return routes
+}
+
+// TestNewIntervals gives a route class with enough validation routes its own interval,
+// and the rest the interval of all routes
+func TestNewIntervals(t *testing.T) {
+	// Ten "long" routes at 0.5 to 1.4 times the prediction of 100
+	routes := routesPriced("long", 50, 60, 70, 80, 90, 100, 110, 120, 130, 140)
+	// Three "short" routes, too few for an interval of their own, all dearer than predicted
+	routes = append(routes, routesPriced("short", 150, 160, 170)...)
+	// No actual price says nothing about the error
+	routes = append(routes, routesPriced("short", 0)...)
+
+	intervals, err := newIntervals(flatModel(100), routes, DefaultIntervalCoverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intervals.Overall.Count != 13 {
+		t.Errorf("interval of all routes from %d, want 13", intervals.Overall.Count)
+	}
+	long := intervals.For(Route{RouteClassification: "long"})
+	if long.Count != 10 || math.Abs(long.Low-0.59) > 1e-9 || math.Abs(long.High-1.31) > 1e-9 {
+		t.Errorf("long interval %+v, want 10 routes from 0.59 to 1.31", long)
+	}
+	if short := intervals.For(Route{RouteClassification: "short"}); short != intervals.Overall {
+		t.Errorf("short interval %+v, want the overall %+v", short, intervals.Overall)
+	}
+}
+
+// TestNewIntervalsHoldPrediction keeps the predicted price within its interval, even
+// when the model was off in the same direction on every route
+func TestNewIntervalsHoldPrediction(t *testing.T) {
+	routes := routesPriced("long", 110, 120, 130, 140, 150, 160, 170, 180, 190, 200)
+	intervals, err := newIntervals(flatModel(100), routes, DefaultIntervalCoverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intervals.Overall.Low != 1 {
+		t.Errorf("low bound %v, want 1", intervals.Overall.Low)
+	}
+
+	low, high, ok := (&Model{Intervals: intervals}).Interval(Route{}, 50)
+	if !ok || low != 50 || high <= 50 {
+		t.Errorf("interval of 50 is %v to %v (%v), want from 50 up", low, high, ok)
+	}
+}
+
+func TestNewIntervalsTooFewRoutes(t *testing.T) {
+	if _, err := newIntervals(flatModel(100), routesPriced("long", 90, 110), DefaultIntervalCoverage); err == nil {
+		t.Errorf("intervals from 2 routes, want an error")
+	}
+	if _, _, ok := (&Model{}).Interval(Route{}, 100); ok {
+		t.Errorf("a model without intervals gave one")
+	}
+}
@@ -0,0 +1,97 @@
+package pricemodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Model is a trained ridge regression of flight prices, with everything needed to
+// predict from it again: the feature pipeline, the coefficients it was trained with,
+// and how well it did on routes it had not seen.
+type Model struct {
+	Version        int        `json:"version"`
+	TrainedAt      string     `json:"trained_at"`
+	TrainingData   string     `json:"training_data"`
+	TrainingRows   int        `json:"training_rows"`
+	ValidationRows int        `json:"validation_rows"`
+	Seed           int64      `json:"seed"`
+	Lambda         float64    `json:"lambda"`
+	Pipeline       *Pipeline  `json:"pipeline"`
+	Features       []string   `json:"features"`
+	Coefficients   []float64  `json:"coefficients"` // The intercept, then one per feature
+	Evaluation     Evaluation `json:"evaluation"`
+	Intervals      *Intervals `json:"intervals,omitempty"` // Missing from models trained before there were intervals
+}
+
+// Predict returns the model's raw price for r, before any boundary rules
+func (m *Model) Predict(r Route) float64 {
+	features := m.Pipeline.Transform(r)
+	price := m.Coefficients[0]
+	for i, value := range features {
+		price += m.Coefficients[i+1] * value
+	}
+	return price
+}
+
+// Interval returns the bounds around price, a prediction for r. ok is false if the
+// model has no prediction intervals.
+func (m *Model) Interval(r Route, price float64) (low, high float64, ok bool) {
+	if m.Intervals == nil || price <= 0 {
+		return 0, 0, false
+	}
+	interval := m.Intervals.For(r)
+	return price * interval.Low, price * interval.High, true
+}
+
+// Formula lists the intercept and the inputs that move the price most, per standard
+// deviation of each
+func (m *Model) Formula() string {
+	type term struct {
+		name  string
+		coeff float64
+	}
+	terms := make([]term, len(m.Features))
+	for i, name := range m.Features {
+		terms[i] = term{name, m.Coefficients[i+1]}
+	}
+	sort.Slice(terms, func(i, j int) bool { return math.Abs(terms[i].coeff) > math.Abs(terms[j].coeff) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Predicted = %.2f", m.Coefficients[0])
+	for i, t := range terms {
+		if i == 10 {
+			fmt.Fprintf(&b, " + %d smaller terms", len(terms)-i)
+			break
+		}
+		fmt.Fprintf(&b, " %+.2f*%s", t.coeff, t.name)
+	}
+	return b.String()
+}
+
+// fit trains a model on routes. The pipeline is learnt from the training routes only.
+func fit(routes []Route, lambda float64) (*Model, error) {
+	pipeline, err := fitPipeline(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	x := make([][]float64, len(routes))
+	y := make([]float64, len(routes))
+	for i, r := range routes {
+		x[i] = pipeline.Transform(r)
+		y[i] = r.ActualPrice
+	}
+	intercept, coefficients, err := fitRidge(x, y, lambda)
+	if err != nil {
+		return nil, fmt.Errorf("failed to train regression on %d routes: %v", len(routes), err)
+	}
+
+	return &Model{
+		Lambda:       lambda,
+		Pipeline:     pipeline,
+		Features:     pipeline.Names(),
+		Coefficients: append([]float64{intercept}, coefficients...),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package pricemodel
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// syntheticRoutes makes n routes whose price is mostly set by distance, with a little
+// noise that repeats every seven routes, so a model has something to learn and a route
+// class median does not
+func syntheticRoutes(n int) []Route {
+	classes := []string{"short", "medium", "long"}
+	routes := make([]Route, n)
+	for i := range routes {
+		distance := float64(300 + 400*(i%3) + 7*i)
+		routes[i] = Route{
+			OriginCity:          fmt.Sprintf("Origin %d", i%4),
+			OriginCountry:       "DE",
+			OriginIATA:          fmt.Sprintf("O%02d", i%4),
+			DestinationCity:     fmt.Sprintf("Destination %d", i%5),
+			DestinationCountry:  "ES",
+			DestinationIATA:     fmt.Sprintf("D%02d", i%5),
+			RouteFrequency:      10 + i%6,
+			RouteClassification: classes[i%3],
+			MostCommonAirline:   "Airline",
+			SeatingCapacity:     180,
+			DurationMinutes:     int(distance/8) + 30,
+			DistanceKm:          distance,
+			ActualPrice:         20 + 0.1*distance + float64(i%7),
+		}
+	}
+	return routes
+}
+
+// TestTrainBeatsBaseline trains on synthetic routes and checks the model is evaluated on
+// the held back routes, does better than the route class median there, and that the
+// same seed trains the same model
+func TestTrainBeatsBaseline(t *testing.T) {
+	routes := syntheticRoutes(200)
+
+	m, err := Train(routes, 0.2, 42, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.TrainingRows != 160 || m.ValidationRows != 40 {
+		t.Errorf("trained on %d and validated on %d routes, want 160 and 40", m.TrainingRows, m.ValidationRows)
+	}
+	if m.Evaluation.Overall.Model.Count != 40 {
+		t.Errorf("evaluated on %d routes, want 40", m.Evaluation.Overall.Model.Count)
+	}
+	model, baseline := m.Evaluation.Overall.Model.MAE, m.Evaluation.Overall.Baseline.MAE
+	if model >= baseline {
+		t.Errorf("model MAE %.2f does not beat baseline MAE %.2f", model, baseline)
+	}
+
+	again, err := Train(routes, 0.2, 42, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Coefficients, again.Coefficients) {
+		t.Errorf("the same seed trained different coefficients")
+	}
+}
+
+func TestTrainNeedsRoutes(t *testing.T) {
+	if _, err := Train(syntheticRoutes(2), 0.5, 1, DefaultRidgeLambda); err == nil {
+		t.Errorf("trained on a single route, want an error")
+	}
+}
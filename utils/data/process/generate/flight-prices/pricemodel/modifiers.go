@@ -0,0 +1,319 @@
+package pricemodel
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Modifiers adjust predicted prices for what the training prices cannot show the model:
+this year's school and public holidays, events at the destination, and peak seasons.
+They apply on top of the model's price, multipliers first and then additions, to every
+route whose trip falls on their dates.
+*/
+
+// ModifierKind is what a modifier stands for, which decides the routes it applies to
+type ModifierKind string
+
+const (
+	SchoolHoliday ModifierKind = "school_holiday" // Families leave the origin country
+	PublicHoliday ModifierKind = "public_holiday" // A holiday at either end of the route
+	Event         ModifierKind = "event"          // Something on at the destination
+	PeakSeason    ModifierKind = "peak_season"    // The destination's busy months
+)
+
+// usualTripNights is the length of the trip predictions are for, Friday to Sunday. A
+// modifier applies when any day of the trip is within its dates.
+const usualTripNights = 2
+
+// The multipliers of the modifiers on one route are kept within this range, so holidays
+// and events that overlap cannot compound without limit
+const (
+	minModifierFactor = 0.5
+	maxModifierFactor = 2.0
+)
+
+// Modifier is one adjustment to the price of trips within its dates. Dates are either
+// "2006-01-02", for one year, or "01-02", for every year; a yearly modifier may run over
+// New Year. End is inclusive.
+type Modifier struct {
+	Kind       ModifierKind
+	Name       string
+	Country    string // ISO code of the country it applies to; empty for every country
+	City       string // For events, the destination city; empty for the whole country
+	Start      string
+	End        string
+	Multiplier float64
+	Addition   float64 // Euros added after multiplying
+}
+
+// Modifiers are every modifier there is
+type Modifiers []Modifier
+
+// The layouts of dated and yearly modifier dates
+const (
+	datedLayout  = "2006-01-02"
+	yearlyLayout = "01-02"
+)
+
+// Validate reports what is wrong with m, if anything
+func (m Modifier) Validate() error {
+	switch m.Kind {
+	case SchoolHoliday, PublicHoliday, Event, PeakSeason:
+	default:
+		return fmt.Errorf("%s: unknown kind %q", m.Name, m.Kind)
+	}
+	if m.City != "" && m.Kind != Event {
+		return fmt.Errorf("%s: only events are for a city", m.Name)
+	}
+	if m.Multiplier <= 0 {
+		return fmt.Errorf("%s: multiplier must be positive, got %v", m.Name, m.Multiplier)
+	}
+	startLayout, err := dateLayout(m.Start)
+	if err != nil {
+		return fmt.Errorf("%s: %v", m.Name, err)
+	}
+	endLayout, err := dateLayout(m.End)
+	if err != nil {
+		return fmt.Errorf("%s: %v", m.Name, err)
+	}
+	if startLayout != endLayout {
+		return fmt.Errorf("%s: start %s and end %s must both be dated or both yearly", m.Name, m.Start, m.End)
+	}
+	if startLayout == datedLayout && m.End < m.Start {
+		return fmt.Errorf("%s: ends %s before it starts %s", m.Name, m.End, m.Start)
+	}
+	return nil
+}
+
+func dateLayout(date string) (string, error) {
+	for _, layout := range []string{datedLayout, yearlyLayout} {
+		if _, err := time.Parse(layout, date); err == nil {
+			return layout, nil
+		}
+	}
+	return "", fmt.Errorf("date %q is neither YYYY-MM-DD nor MM-DD", date)
+}
+
+// covers reports whether day is within m's dates
+func (m Modifier) covers(day time.Time) bool {
+	if len(m.Start) == len(datedLayout) {
+		date := day.Format(datedLayout)
+		return m.Start <= date && date <= m.End
+	}
+	monthDay := day.Format(yearlyLayout)
+	if m.Start <= m.End {
+		return m.Start <= monthDay && monthDay <= m.End
+	}
+	return monthDay >= m.Start || monthDay <= m.End
+}
+
+func matchesCountry(country, routeCountry string) bool {
+	return country == "" || strings.EqualFold(country, routeCountry)
+}
+
+// AppliesTo reports whether m changes the price of r's trip
+func (m Modifier) AppliesTo(r Route) bool {
+	var routeMatches bool
+	switch m.Kind {
+	case SchoolHoliday:
+		routeMatches = matchesCountry(m.Country, r.OriginCountry)
+	case PublicHoliday:
+		routeMatches = matchesCountry(m.Country, r.OriginCountry) || matchesCountry(m.Country, r.DestinationCountry)
+	case Event:
+		routeMatches = matchesCountry(m.Country, r.DestinationCountry) &&
+			(m.City == "" || strings.EqualFold(m.City, r.DestinationCity))
+	case PeakSeason:
+		routeMatches = matchesCountry(m.Country, r.DestinationCountry)
+	}
+	if !routeMatches || r.TravelDate.IsZero() {
+		return false
+	}
+	for night := 0; night <= usualTripNights; night++ {
+		if m.covers(r.TravelDate.AddDate(0, 0, night)) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes m's effect, such as "Oktoberfest ×1.30" or "Eurovision +€20"
+func (m Modifier) String() string {
+	var effects []string
+	if m.Multiplier != 1 {
+		effects = append(effects, fmt.Sprintf("×%.2f", m.Multiplier))
+	}
+	if m.Addition > 0 {
+		effects = append(effects, fmt.Sprintf("+€%.0f", m.Addition))
+	} else if m.Addition < 0 {
+		effects = append(effects, fmt.Sprintf("-€%.0f", -m.Addition))
+	}
+	if len(effects) == 0 {
+		return m.Name
+	}
+	return m.Name + " " + strings.Join(effects, " ")
+}
+
+// Apply returns price with every modifier for r's trip applied, and those modifiers
+func (ms Modifiers) Apply(r Route, price float64) (float64, Modifiers) {
+	var applied Modifiers
+	factor, addition := 1.0, 0.0
+	for _, m := range ms {
+		if !m.AppliesTo(r) {
+			continue
+		}
+		applied = append(applied, m)
+		factor *= m.Multiplier
+		addition += m.Addition
+	}
+	if len(applied) == 0 {
+		return price, nil
+	}
+	factor = math.Max(minModifierFactor, math.Min(maxModifierFactor, factor))
+	return math.Max(0, price*factor+addition), applied
+}
+
+// Describe lists the modifiers, for the prediction they were applied to. Empty for none.
+func (ms Modifiers) Describe() string {
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = m.String()
+	}
+	return strings.Join(names, "; ")
+}
+
+// modifierColumns are the columns of the modifiers CSV
+var modifierColumns = []string{"kind", "name", "country", "city", "start", "end", "multiplier", "addition"}
+
+// ReadModifiersCSV reads modifiers from a CSV with a header of modifierColumns. Every
+// modifier must be valid, so a mistyped date cannot quietly stop one applying.
+func ReadModifiersCSV(path string) (Modifiers, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(modifierColumns, ",") {
+		return nil, fmt.Errorf("%s must start with the header %s", path, strings.Join(modifierColumns, ","))
+	}
+
+	var ms Modifiers
+	for i, record := range records[1:] {
+		m := Modifier{
+			Kind:    ModifierKind(record[0]),
+			Name:    record[1],
+			Country: record[2],
+			City:    record[3],
+			Start:   record[4],
+			End:     record[5],
+		}
+		if m.Multiplier, err = parseOptionalFloat(record[6], 1); err != nil {
+			return nil, fmt.Errorf("%s line %d: multiplier: %v", path, i+2, err)
+		}
+		if m.Addition, err = parseOptionalFloat(record[7], 0); err != nil {
+			return nil, fmt.Errorf("%s line %d: addition: %v", path, i+2, err)
+		}
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, i+2, err)
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+func parseOptionalFloat(s string, fallback float64) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return fallback, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// SaveModifiers replaces the modifiers in price_modifier with ms
+func SaveModifiers(db *sql.DB, ms Modifiers) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS price_modifier (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		name TEXT NOT NULL,
+		country TEXT,
+		city TEXT,
+		start_date TEXT NOT NULL,
+		end_date TEXT NOT NULL,
+		multiplier REAL NOT NULL DEFAULT 1,
+		addition REAL NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM price_modifier`); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO price_modifier (kind, name, country, city, start_date, end_date, multiplier, addition) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, m := range ms {
+		if _, err := stmt.Exec(string(m.Kind), m.Name, m.Country, m.City, m.Start, m.End, m.Multiplier, m.Addition); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadModifiers reads the modifiers in price_modifier. There are none until the
+// modifiers command has imported some; invalid ones are left out with an error.
+func LoadModifiers(db *sql.DB) (Modifiers, error) {
+	var exists int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'price_modifier'`).Scan(&exists)
+	if err != nil || exists == 0 {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT kind, name, COALESCE(country, ''), COALESCE(city, ''), start_date, end_date, multiplier, addition
+		FROM price_modifier ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ms Modifiers
+	var invalid []string
+	for rows.Next() {
+		var m Modifier
+		var kind string
+		if err := rows.Scan(&kind, &m.Name, &m.Country, &m.City, &m.Start, &m.End, &m.Multiplier, &m.Addition); err != nil {
+			return nil, err
+		}
+		m.Kind = ModifierKind(kind)
+		if err := m.Validate(); err != nil {
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		ms = append(ms, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(invalid) > 0 {
+		return ms, fmt.Errorf("skipped invalid modifiers: %s", strings.Join(invalid, "; "))
+	}
+	return ms, nil
+}
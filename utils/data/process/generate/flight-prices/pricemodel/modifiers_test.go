@@ -0,0 +1,143 @@
+package pricemodel
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// day parses a "2006-01-02" date
+func day(date string) time.Time {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestModifierCovers(t *testing.T) {
+	newYear := Modifier{Start: "12-20", End: "01-06"}
+	summer := Modifier{Start: "07-01", End: "08-31"}
+	dated := Modifier{Start: "2026-10-01", End: "2026-10-04"}
+
+	tests := []struct {
+		name     string
+		modifier Modifier
+		day      string
+		want     bool
+	}{
+		{"yearly, before New Year", newYear, "2026-12-24", true},
+		{"yearly, after New Year", newYear, "2027-01-02", true},
+		{"yearly, first day", newYear, "2026-12-20", true},
+		{"yearly, last day", newYear, "2027-01-06", true},
+		{"yearly, outside over New Year", newYear, "2026-06-15", false},
+		{"yearly, the day after", newYear, "2027-01-07", false},
+		{"yearly, within the year", summer, "2026-08-15", true},
+		{"yearly, outside within the year", summer, "2026-12-24", false},
+		{"dated", dated, "2026-10-04", true},
+		{"dated, another year", dated, "2027-10-02", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.modifier.covers(day(tt.day)); got != tt.want {
+				t.Errorf("%s to %s covers %s: %v, want %v", tt.modifier.Start, tt.modifier.End, tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestModifierAppliesTo matches each kind of modifier to its end of the route, on any
+// day of the Friday to Sunday trip
+func TestModifierAppliesTo(t *testing.T) {
+	friday := day("2026-10-02")
+	route := Route{OriginCountry: "DE", DestinationCountry: "ES", DestinationCity: "Barcelona", TravelDate: friday}
+
+	tests := []struct {
+		name     string
+		modifier Modifier
+		want     bool
+	}{
+		{"school holiday in the origin country", Modifier{Kind: SchoolHoliday, Country: "DE", Start: "10-01", End: "10-10"}, true},
+		{"school holiday at the destination", Modifier{Kind: SchoolHoliday, Country: "ES", Start: "10-01", End: "10-10"}, false},
+		{"public holiday at the destination", Modifier{Kind: PublicHoliday, Country: "es", Start: "10-02", End: "10-02"}, true},
+		{"event in the city", Modifier{Kind: Event, Country: "ES", City: "barcelona", Start: "2026-10-04", End: "2026-10-04"}, true},
+		{"event in another city", Modifier{Kind: Event, Country: "ES", City: "Madrid", Start: "2026-10-04", End: "2026-10-04"}, false},
+		{"peak season everywhere", Modifier{Kind: PeakSeason, Start: "09-01", End: "10-31"}, true},
+		{"starts the day after the trip", Modifier{Kind: PeakSeason, Start: "10-05", End: "10-31"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.modifier.AppliesTo(route); got != tt.want {
+				t.Errorf("applies: %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	undated := route
+	undated.TravelDate = time.Time{}
+	if (Modifier{Kind: PeakSeason, Start: "01-01", End: "12-31"}).AppliesTo(undated) {
+		t.Errorf("a modifier applied to a price with no travel date")
+	}
+}
+
+// TestModifiersApply multiplies before adding, and keeps the combined multiplier within range
+func TestModifiersApply(t *testing.T) {
+	route := Route{DestinationCountry: "ES", TravelDate: day("2026-10-02")}
+	peak := Modifier{Kind: PeakSeason, Name: "Autumn", Start: "10-01", End: "10-31", Multiplier: 1.5, Addition: 10}
+	event := Modifier{Kind: Event, Name: "Festival", Start: "10-01", End: "10-31", Multiplier: 1.5}
+	winter := Modifier{Kind: PeakSeason, Name: "Winter", Start: "12-01", End: "12-31", Multiplier: 3}
+
+	price, applied := Modifiers{peak, winter}.Apply(route, 100)
+	if price != 160 || len(applied) != 1 || applied.Describe() != "Autumn ×1.50 +€10" {
+		t.Errorf("got %v with %q, want 160 with Autumn", price, applied.Describe())
+	}
+	if price, _ := (Modifiers{peak, event}).Apply(route, 100); math.Abs(price-(100*maxModifierFactor+10)) > 1e-9 {
+		t.Errorf("got %v, want the multiplier held at %v", price, maxModifierFactor)
+	}
+	if price, applied := (Modifiers{winter}).Apply(route, 100); price != 100 || applied != nil {
+		t.Errorf("got %v with %v when nothing applies, want 100 unchanged", price, applied)
+	}
+}
+
+func TestReadModifiersCSV(t *testing.T) {
+	header := "kind,name,country,city,start,end,multiplier,addition\n"
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{"valid", header + "event,Oktoberfest,DE,Munich,09-19,10-04,1.3,\npeak_season,Summer,ES,,07-01,08-31,,15\n", ""},
+		{"missing header", "event,Oktoberfest,DE,Munich,09-19,10-04,1.3,\n", "header"},
+		{"unknown kind", header + "festival,Oktoberfest,DE,Munich,09-19,10-04,1.3,\n", "unknown kind"},
+		{"city on a holiday", header + "public_holiday,Unity Day,DE,Berlin,10-03,10-03,1.1,\n", "only events"},
+		{"mistyped date", header + "event,Oktoberfest,DE,Munich,09-31,10-04,1.3,\n", "neither"},
+		{"dated and yearly", header + "event,Oktoberfest,DE,Munich,2026-09-19,10-04,1.3,\n", "must both be dated or both yearly"},
+		{"ends before it starts", header + "event,Oktoberfest,DE,Munich,2026-10-04,2026-09-19,1.3,\n", "before it starts"},
+		{"zero multiplier", header + "event,Oktoberfest,DE,Munich,09-19,10-04,0,\n", "positive"},
+		{"unreadable addition", header + "event,Oktoberfest,DE,Munich,09-19,10-04,1.3,lots\n", "line 2: addition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "modifiers.csv")
+			if err := os.WriteFile(path, []byte(tt.csv), 0644); err != nil {
+				t.Fatal(err)
+			}
+			ms, err := ReadModifiersCSV(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(ms) != 2 || ms[0].Addition != 0 || ms[1].Multiplier != 1 || ms[1].Addition != 15 {
+					t.Errorf("read %+v, want empty multipliers as 1 and additions as 0", ms)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
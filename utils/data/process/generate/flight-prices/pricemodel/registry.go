@@ -0,0 +1,120 @@
+package pricemodel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// DefaultRegistryDir is where trained models are kept, relative to generate/flight-prices
+const DefaultRegistryDir = "../../../../../data/generated/flight-price-models"
+
+// Registry keeps every trained model as a JSON file named after its version,
+// e.g. v003.json. Versions are never overwritten.
+type Registry struct {
+	Dir string
+}
+
+var versionFile = regexp.MustCompile(`^v(\d+)\.json$`)
+
+// Versions lists the versions in the registry, oldest first
+func (r Registry) Versions() ([]int, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int
+	for _, entry := range entries {
+		match := versionFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (r Registry) path(version int) string {
+	return filepath.Join(r.Dir, fmt.Sprintf("v%03d.json", version))
+}
+
+// Save stores m as the next version and sets m.Version
+func (r Registry) Save(m *Model) error {
+	versions, err := r.Versions()
+	if err != nil {
+		return err
+	}
+	m.Version = 1
+	if len(versions) > 0 {
+		m.Version = versions[len(versions)-1] + 1
+	}
+
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	// O_EXCL so two trainings at once can't both claim the version
+	f, err := os.OpenFile(r.path(m.Version), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save model version %d: %v", m.Version, err)
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+// Load reads one version of the model. Version 0 is the latest that the current
+// feature pipeline can use.
+func (r Registry) Load(version int) (*Model, error) {
+	if version != 0 {
+		return r.load(version)
+	}
+
+	versions, err := r.Versions()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		m, err := r.load(versions[i])
+		if errors.Is(err, errIncompatible) {
+			continue
+		}
+		return m, err
+	}
+	return nil, os.ErrNotExist
+}
+
+// errIncompatible marks models trained before the current feature pipeline
+var errIncompatible = errors.New("trained with an older feature pipeline, train a new version")
+
+func (r Registry) load(version int) (*Model, error) {
+	data, err := os.ReadFile(r.path(version))
+	if err != nil {
+		return nil, err
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to read model version %d: %v", version, err)
+	}
+	if m.Pipeline == nil {
+		return nil, fmt.Errorf("model version %d was %w", version, errIncompatible)
+	}
+	if len(m.Coefficients) != len(m.Pipeline.Columns)+1 {
+		return nil, fmt.Errorf("model version %d has %d coefficients for %d features", version, len(m.Coefficients), len(m.Pipeline.Columns))
+	}
+	return &m, nil
+}
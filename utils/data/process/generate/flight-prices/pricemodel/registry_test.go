@@ -0,0 +1,90 @@
+package pricemodel
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestRegistryRoundTrip saves two models and reads them back by version and as the latest
+func TestRegistryRoundTrip(t *testing.T) {
+	registry := Registry{Dir: filepath.Join(t.TempDir(), "models")}
+	if _, err := registry.Load(0); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("empty registry loaded with %v, want os.ErrNotExist", err)
+	}
+
+	first, err := Train(syntheticRoutes(100), 0.2, 1, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Train(syntheticRoutes(100), 0.2, 2, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*Model{first, second} {
+		if err := registry.Save(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.Version != 1 || second.Version != 2 {
+		t.Fatalf("saved as versions %d and %d, want 1 and 2", first.Version, second.Version)
+	}
+	if _, err := os.Stat(filepath.Join(registry.Dir, "v002.json")); err != nil {
+		t.Errorf("version 2 is not in v002.json: %v", err)
+	}
+
+	latest, err := registry.Load(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != 2 {
+		t.Errorf("latest is version %d, want 2", latest.Version)
+	}
+	loaded, err := registry.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Coefficients, first.Coefficients) || !reflect.DeepEqual(loaded.Pipeline, first.Pipeline) {
+		t.Errorf("version 1 read back differently from how it was saved")
+	}
+	route := syntheticRoutes(1)[0]
+	if loaded.Predict(route) != first.Predict(route) {
+		t.Errorf("version 1 predicts %v after loading, %v before", loaded.Predict(route), first.Predict(route))
+	}
+}
+
+// TestRegistrySkipsIncompatible falls back to the newest model the current pipeline can use
+func TestRegistrySkipsIncompatible(t *testing.T) {
+	registry := Registry{Dir: t.TempDir()}
+	m, err := Train(syntheticRoutes(100), 0.2, 1, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Save(m); err != nil {
+		t.Fatal(err)
+	}
+	// A model from before the feature pipeline, with no pipeline of its own
+	if err := os.WriteFile(filepath.Join(registry.Dir, "v002.json"), []byte(`{"version": 2, "coefficients": [1, 2]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := registry.Load(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != 1 {
+		t.Errorf("latest usable is version %d, want 1", latest.Version)
+	}
+	if _, err := registry.Load(2); !errors.Is(err, errIncompatible) {
+		t.Errorf("loading version 2 gave %v, want errIncompatible", err)
+	}
+	// The next model still gets a version of its own
+	if err := registry.Save(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 3 {
+		t.Errorf("saved as version %d, want 3", m.Version)
+	}
+}
@@ -0,0 +1,83 @@
+package pricemodel
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultRidgeLambda is the ridge penalty on standardized inputs. It keeps coefficients
+// finite when inputs move together, as distance and duration do.
+const DefaultRidgeLambda = 1.0
+
+// fitRidge solves ridge regression by the normal equations: the intercept is the mean
+// of y, and the coefficients solve (XᵀX + λI)β = Xᵀ(y - ȳ) by Cholesky decomposition.
+// X's columns must be standardized, so the one λ suits all of them.
+func fitRidge(x [][]float64, y []float64, lambda float64) (intercept float64, coefficients []float64, err error) {
+	if len(x) == 0 || len(x) != len(y) {
+		return 0, nil, fmt.Errorf("need as many rows as prices, have %d and %d", len(x), len(y))
+	}
+	n := len(x[0])
+	intercept = mean(y)
+
+	a := make([][]float64, n)
+	b := make([]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n)
+		a[i][i] = lambda
+	}
+	for row, features := range x {
+		residual := y[row] - intercept
+		for i := 0; i < n; i++ {
+			b[i] += features[i] * residual
+			for j := 0; j <= i; j++ {
+				a[i][j] += features[i] * features[j]
+			}
+		}
+	}
+
+	coefficients, err = solveCholesky(a, b)
+	return intercept, coefficients, err
+}
+
+// solveCholesky solves a·x = b for a symmetric positive definite a, of which only the
+// lower triangle is read
+func solveCholesky(a [][]float64, b []float64) ([]float64, error) {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+
+	// Forward substitution for L·z = b, then back substitution for Lᵀ·x = z
+	z := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * z[k]
+		}
+		z[i] = sum / l[i][i]
+	}
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := z[i]
+		for k := i + 1; k < n; k++ {
+			sum -= l[k][i] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x, nil
+}
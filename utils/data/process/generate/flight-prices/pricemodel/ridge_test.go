@@ -0,0 +1,63 @@
+package pricemodel
+
+import (
+	"math"
+	"testing"
+)
+
+// TestFitRidgeRecoversCoefficients fits y = 3 + 2a - b on centred inputs. With almost no
+// penalty ridge is least squares and finds the coefficients; a large penalty shrinks them.
+func TestFitRidgeRecoversCoefficients(t *testing.T) {
+	inputs := [][2]float64{{-2, 1}, {-1, -1}, {0, 2}, {1, 0}, {2, -2}}
+	x := make([][]float64, len(inputs))
+	y := make([]float64, len(inputs))
+	for i, in := range inputs {
+		x[i] = []float64{in[0], in[1]}
+		y[i] = 3 + 2*in[0] - in[1]
+	}
+
+	intercept, coefficients, err := fitRidge(x, y, 1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{2, -1}
+	if math.Abs(intercept-3) > 1e-6 {
+		t.Errorf("intercept %v, want 3", intercept)
+	}
+	for i := range want {
+		if math.Abs(coefficients[i]-want[i]) > 1e-6 {
+			t.Errorf("coefficient %d is %v, want %v", i, coefficients[i], want[i])
+		}
+	}
+
+	_, shrunk, err := fitRidge(x, y, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if math.Abs(shrunk[i]) >= math.Abs(want[i]) {
+			t.Errorf("coefficient %d is %v with a large penalty, want it shrunk below %v", i, shrunk[i], want[i])
+		}
+	}
+}
+
+// TestFitRidgeCollinear keeps coefficients finite when two inputs are the same, which
+// least squares alone cannot pull apart
+func TestFitRidgeCollinear(t *testing.T) {
+	x := [][]float64{{-1, -1}, {0, 0}, {1, 1}}
+	y := []float64{1, 2, 3}
+
+	_, coefficients, err := fitRidge(x, y, DefaultRidgeLambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsNaN(coefficients[0]) || math.IsInf(coefficients[0], 0) || math.Abs(coefficients[0]-coefficients[1]) > 1e-9 {
+		t.Errorf("identical inputs got coefficients %v, want them shared equally", coefficients)
+	}
+}
+
+func TestFitRidgeMismatchedRows(t *testing.T) {
+	if _, _, err := fitRidge([][]float64{{1}, {2}}, []float64{1}, 1); err == nil {
+		t.Errorf("fitted 2 rows to 1 price, want an error")
+	}
+}
@@ -0,0 +1,150 @@
+package pricemodel
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route is one flight route and what is known about it. ActualPrice is only
+// set for routes from the training data. DistanceKm is 0 when an airport has no
+// coordinates, and TravelDate and ObservedAt are zero when the price has no date.
+type Route struct {
+	OriginCity            string
+	OriginCountry         string
+	OriginIATA            string
+	OriginPopulation      int
+	DestinationCity       string
+	DestinationCountry    string
+	DestinationIATA       string
+	DestinationPopulation int
+	RouteFrequency        int
+	RouteClassification   string
+	MostCommonAirline     string
+	MostCommonAircraft    string
+	SeatingCapacity       int
+	DurationHourDotMins   string
+	DurationMinutes       int
+	DistanceKm            float64
+	TravelDate            time.Time // Day of the outbound flight
+	ObservedAt            time.Time // When the price was seen, or is predicted for
+	ActualPrice           float64
+}
+
+// HasDates reports whether the route's price is for a known travel date
+func (r Route) HasDates() bool {
+	return !r.TravelDate.IsZero() && !r.ObservedAt.IsZero()
+}
+
+// LeadDays is how many days ahead of the flight the price was seen
+func (r Route) LeadDays() float64 {
+	if !r.HasDates() {
+		return 0
+	}
+	return r.TravelDate.Sub(r.ObservedAt).Hours() / 24
+}
+
+// NextUsualTravelDate is the Friday of next week, the outbound day of the trip that
+// predictions stand in for until the fetcher has seen a price
+func NextUsualTravelDate(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 7)
+	for day.Weekday() != time.Friday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// ParseDuration converts a "H.MM" string into total minutes.
+// It calculates: totalMinutes = hours*60 + minutes*10.
+// If no dot is present, the value is assumed to represent hours.
+func ParseDuration(duration string) (int, error) {
+	if !strings.Contains(duration, ".") {
+		hours, err := strconv.Atoi(duration)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hours value in duration: %s", duration)
+		}
+		return hours * 60, nil
+	}
+	parts := strings.Split(duration, ".")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("unexpected duration format: %s", duration)
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	mins, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid numeric values in duration: %s", duration)
+	}
+	return hours*60 + mins*10, nil
+}
+
+// ReadTrainingCSV reads routes with their actual price from a CSV laid out like
+// the prediction table, with actual_price as the 15th column. Rows with an
+// unreadable price or duration are skipped, and their count returned.
+func ReadTrainingCSV(path string) ([]Route, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(records) == 0 {
+		return nil, 0, fmt.Errorf("%s is empty", path)
+	}
+
+	startRow := 0
+	if strings.Contains(strings.ToLower(records[0][0]), "origin_city_name") {
+		startRow = 1
+	}
+
+	var routes []Route
+	skipped := 0
+	for _, rec := range records[startRow:] {
+		if len(rec) < 15 {
+			skipped++
+			continue
+		}
+		actualPrice, err := strconv.ParseFloat(rec[14], 64)
+		if err != nil {
+			skipped++
+			continue
+		}
+		durationMinutes, err := ParseDuration(rec[13])
+		if err != nil {
+			skipped++
+			continue
+		}
+		originPop, _ := strconv.Atoi(rec[3])
+		destPop, _ := strconv.Atoi(rec[7])
+		routeFreq, _ := strconv.Atoi(rec[8])
+		seatCapacity, _ := strconv.Atoi(rec[12])
+
+		routes = append(routes, Route{
+			OriginCity:            rec[0],
+			OriginCountry:         rec[1],
+			OriginIATA:            rec[2],
+			OriginPopulation:      originPop,
+			DestinationCity:       rec[4],
+			DestinationCountry:    rec[5],
+			DestinationIATA:       rec[6],
+			DestinationPopulation: destPop,
+			RouteFrequency:        routeFreq,
+			RouteClassification:   rec[9],
+			MostCommonAirline:     rec[10],
+			MostCommonAircraft:    rec[11],
+			SeatingCapacity:       seatCapacity,
+			DurationHourDotMins:   rec[13],
+			DurationMinutes:       durationMinutes,
+			ActualPrice:           actualPrice,
+		})
+	}
+	return routes, skipped, nil
+}
@@ -0,0 +1,154 @@
+package pricemodel
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TrainingSources are where training routes come from. Only the CSV is required;
+// a missing database just means fewer routes or no distances.
+type TrainingSources struct {
+	CSV         string // Routes with a price, without dates
+	RoutesDB    string // flight-prices.db, for the attributes of observed routes
+	FlightsDB   string // The raw flights.db, whose price_observation has dated prices
+	LocationsDB string // The raw locations.db, for airport coordinates
+}
+
+// DefaultTrainingSources are the usual paths, relative to base, the generate/flight-prices directory
+func DefaultTrainingSources(base string) TrainingSources {
+	return TrainingSources{
+		CSV:         filepath.Join(base, "training_data.csv"),
+		RoutesDB:    filepath.Join(base, "../../../../../data/generated/flight-prices.db"),
+		FlightsDB:   filepath.Join(base, "../../../../../data/raw/flights/flights.db"),
+		LocationsDB: filepath.Join(base, "../../../../../data/raw/locations/locations.db"),
+	}
+}
+
+// Describe names the sources used, for the model artifact
+func (s TrainingSources) Describe() string {
+	names := []string{filepath.Base(s.CSV)}
+	if fileExists(s.FlightsDB) && fileExists(s.RoutesDB) {
+		names = append(names, "price_observation")
+	}
+	return strings.Join(names, " + ")
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Load reads every training route, with distances where the airports are known
+func (s TrainingSources) Load() ([]Route, error) {
+	routes, skipped, err := ReadTrainingCSV(s.CSV)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Read %d routes from %s (%d skipped)\n", len(routes), s.CSV, skipped)
+
+	if fileExists(s.FlightsDB) && fileExists(s.RoutesDB) {
+		observed, err := readObservedRoutes(s.RoutesDB, s.FlightsDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read price observations: %v", err)
+		}
+		fmt.Printf("Read %d observed prices from %s\n", len(observed), s.FlightsDB)
+		routes = append(routes, observed...)
+	}
+
+	if fileExists(s.LocationsDB) {
+		locationsDB, err := sql.Open("sqlite3", s.LocationsDB)
+		if err != nil {
+			return nil, err
+		}
+		defer locationsDB.Close()
+		airports, err := LoadAirports(locationsDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read airport coordinates: %v", err)
+		}
+		airports.AddDistances(routes)
+	}
+	return routes, nil
+}
+
+// readObservedRoutes pairs the cheapest outbound and return seen for each route on each
+// day, as compile/main/flights does for price trends, and gives them the route's
+// attributes from the routes table. The travel date is the cheapest outbound's.
+func readObservedRoutes(routesDBPath, flightsDBPath string) ([]Route, error) {
+	db, err := sql.Open("sqlite3", routesDBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// One connection, so the attached database is there for every query
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`ATTACH DATABASE ? AS raw`, flightsDBPath); err != nil {
+		return nil, err
+	}
+	var exists int
+	err = db.QueryRow(`SELECT COUNT(*) FROM raw.sqlite_master WHERE type = 'table' AND name = 'price_observation'`).Scan(&exists)
+	if err != nil || exists == 0 {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+	SELECT r.origin_city_name, r.origin_country, r.origin_iata, COALESCE(r.origin_population, 0),
+		r.destination_city_name, r.destination_country, r.destination_iata, COALESCE(r.destination_population, 0),
+		COALESCE(r.route_frequency, 0), COALESCE(r.route_classification, ''), COALESCE(r.most_common_airline, ''),
+		COALESCE(r.most_common_aircraft, ''), COALESCE(r.most_common_aircraft_seating_capacity, 0), r.duration_hour_dot_mins,
+		o.travel_date, o.observed_at, o.price + b.price
+	FROM (
+		SELECT origin_iata, destination_iata, date(observed_at) AS day, travel_date, observed_at, MIN(price) AS price
+		FROM raw.price_observation
+		WHERE leg = 'outbound' AND price > 0
+		GROUP BY origin_iata, destination_iata, day
+	) o
+	JOIN (
+		SELECT origin_iata, destination_iata, date(observed_at) AS day, MIN(price) AS price
+		FROM raw.price_observation
+		WHERE leg = 'return' AND price > 0
+		GROUP BY origin_iata, destination_iata, day
+	) b ON b.origin_iata = o.origin_iata AND b.destination_iata = o.destination_iata AND b.day = o.day
+	JOIN routes r ON r.origin_iata = o.origin_iata AND r.destination_iata = o.destination_iata`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []Route
+	for rows.Next() {
+		var r Route
+		var duration sql.NullString
+		var travelDate, observedAt string
+		if err := rows.Scan(&r.OriginCity, &r.OriginCountry, &r.OriginIATA, &r.OriginPopulation,
+			&r.DestinationCity, &r.DestinationCountry, &r.DestinationIATA, &r.DestinationPopulation,
+			&r.RouteFrequency, &r.RouteClassification, &r.MostCommonAirline, &r.MostCommonAircraft,
+			&r.SeatingCapacity, &duration, &travelDate, &observedAt, &r.ActualPrice); err != nil {
+			return nil, err
+		}
+		if !duration.Valid {
+			continue
+		}
+		r.DurationHourDotMins = duration.String
+		if r.DurationMinutes, err = ParseDuration(duration.String); err != nil {
+			continue
+		}
+		if r.TravelDate, err = time.Parse("2006-01-02", strings.Split(travelDate, "T")[0]); err != nil {
+			continue
+		}
+		if r.ObservedAt, err = time.Parse("2006-01-02 15:04:05", observedAt); err != nil {
+			continue
+		}
+		routes = append(routes, r)
+	}
+	return routes, rows.Err()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/Tris20/FairFareFinder/utils/data/process/generate/flight-prices/pricemodel"
+)
+
+/*
+train-model trains a flight price model on training_data.csv and the dated prices the
+fetcher has seen, reports how it does on the routes held back for validation against
+the route class median, and saves it to the model registry as the next version. generate/flight-prices predicts with it from
+then on, unless told to use another version with -model-version.
+*/
+func main() {
+	sources := pricemodel.DefaultTrainingSources("..")
+	flag.StringVar(&sources.CSV, "data", sources.CSV, "CSV of routes with their actual price")
+	flag.StringVar(&sources.FlightsDB, "flights-db", sources.FlightsDB, "Raw flights.db with price_observation; skipped if missing")
+	flag.StringVar(&sources.RoutesDB, "routes-db", sources.RoutesDB, "flight-prices.db with the routes table")
+	flag.StringVar(&sources.LocationsDB, "locations-db", sources.LocationsDB, "Raw locations.db with airport coordinates; skipped if missing")
+	registryDir := flag.String("registry", filepath.Join("..", pricemodel.DefaultRegistryDir), "Directory of trained model versions")
+	validationShare := flag.Float64("validation", 0.2, "Share of routes held back to evaluate the model")
+	seed := flag.Int64("seed", 1, "Seed of the train/validation split")
+	lambda := flag.Float64("lambda", pricemodel.DefaultRidgeLambda, "Ridge penalty on the standardized features")
+	dryRun := flag.Bool("dry-run", false, "Report the evaluation without saving the model")
+	flag.Parse()
+
+	if *validationShare <= 0 || *validationShare >= 1 {
+		log.Fatalf("-validation must be between 0 and 1, got %v", *validationShare)
+	}
+
+	routes, err := sources.Load()
+	if err != nil {
+		log.Fatalf("Error reading training data: %v", err)
+	}
+
+	m, err := pricemodel.Train(routes, *validationShare, *seed, *lambda)
+	if err != nil {
+		log.Fatalf("Error training model: %v", err)
+	}
+	m.TrainingData = sources.Describe()
+	fmt.Printf("Regression Formula:\n%v\n\n", m.Formula())
+	fmt.Printf("Trained on %d routes, validated on %d:\n%s\n", m.TrainingRows, m.ValidationRows, m.Evaluation.Report())
+	if m.Intervals != nil {
+		fmt.Printf("Prediction intervals, as multiples of the predicted price:\n%s\n", m.Intervals.Report())
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run, model not saved.")
+		return
+	}
+	registry := pricemodel.Registry{Dir: *registryDir}
+	if err := registry.Save(m); err != nil {
+		log.Fatalf("Error saving model: %v", err)
+	}
+	fmt.Printf("Saved model version %d to %s\n", m.Version, registry.Dir)
+}
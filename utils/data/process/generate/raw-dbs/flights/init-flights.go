@@ -52,7 +52,14 @@ func main() {
         "destination_skyscanner_id" TEXT,
         "this_weekend" REAL,    
         "next_weekend" REAL,
-        "duration" INTEGER
+        "duration" INTEGER,
+        "fetched_at" TEXT,
+        "outbound_date" TEXT,
+        "outbound_price" REAL,
+        "outbound_duration_mins" INTEGER,
+        "return_date" TEXT,
+        "return_price" REAL,
+        "return_duration_mins" INTEGER
     );
     `)
 	if err != nil {
@@ -60,4 +67,63 @@ func main() {
 	} else {
 		log.Println("Checked/created 'skyscannerprices' table successfully.")
 	}
+
+	// Prompt to continue
+	fmt.Println("Press 'Enter' to continue with the next table...")
+	bufio.NewReader(os.Stdin).ReadBytes('\n')
+
+	// Create the "route_price_by_date" table, the price of every date searched for a route
+	_, err = db.Exec(`
+    CREATE TABLE IF NOT EXISTS "route_price_by_date" (
+        "origin_city" TEXT,
+        "origin_country" TEXT,
+        "origin_iata" TEXT,
+        "origin_skyscanner_id" TEXT NOT NULL,
+        "destination_city" TEXT,
+        "destination_country" TEXT,
+        "destination_iata" TEXT,
+        "destination_skyscanner_id" TEXT NOT NULL,
+        "leg" TEXT NOT NULL,
+        "date" TEXT NOT NULL,
+        "price" REAL,
+        "fetched_at" TEXT,
+        PRIMARY KEY ("origin_skyscanner_id", "destination_skyscanner_id", "leg", "date")
+    );
+    `)
+	if err != nil {
+		log.Fatal(err)
+	} else {
+		log.Println("Checked/created 'route_price_by_date' table successfully.")
+	}
+
+	// Prompt to continue
+	fmt.Println("Press 'Enter' to continue with the next table...")
+	bufio.NewReader(os.Stdin).ReadBytes('\n')
+
+	// Create the "price_observation" table, an append-only history of every price fetched
+	_, err = db.Exec(`
+    CREATE TABLE IF NOT EXISTS "price_observation" (
+        "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+        "origin_city" TEXT,
+        "origin_country" TEXT,
+        "origin_iata" TEXT,
+        "origin_skyscanner_id" TEXT NOT NULL,
+        "destination_city" TEXT,
+        "destination_country" TEXT,
+        "destination_iata" TEXT,
+        "destination_skyscanner_id" TEXT NOT NULL,
+        "leg" TEXT NOT NULL,
+        "travel_date" TEXT NOT NULL,
+        "observed_at" TEXT NOT NULL,
+        "price" REAL NOT NULL,
+        "source" TEXT NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS "idx_price_observation_route"
+    ON "price_observation" ("origin_iata", "destination_iata", "observed_at");
+    `)
+	if err != nil {
+		log.Fatal(err)
+	} else {
+		log.Println("Checked/created 'price_observation' table successfully.")
+	}
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Columns every climate normals CSV must have. mean_wind_speed is optional.
+var requiredColumns = []string{"city", "country", "month", "mean_temp", "rain_days", "sunshine_hours"}
+
+func main() {
+	csvPath := flag.String("csv", "climate-normals.csv", "CSV of monthly climate normals to import")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", "../../../../../../data/raw/weather/weather.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// One row per city and calendar month; wpi is filled in by process/calculate/weather
+	createTableSQL := `CREATE TABLE IF NOT EXISTS climate_normals (
+		city_name TEXT NOT NULL,
+		country_code TEXT NOT NULL,
+		month INTEGER NOT NULL,
+		mean_temp REAL NOT NULL,
+		rain_days REAL NOT NULL,
+		sunshine_hours REAL NOT NULL,
+		mean_wind_speed REAL,
+		wpi REAL,
+		temperature_score REAL,
+		wind_score REAL,
+		condition_score REAL,
+		precipitation_score REAL,
+		cloud_cover_score REAL,
+		PRIMARY KEY (city_name, country_code, month)
+	);`
+
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	csvFile, err := os.Open(*csvPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer csvFile.Close()
+
+	reader := csv.NewReader(csvFile)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		log.Fatal("Failed to read CSV header:", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			log.Fatalf("CSV is missing required column %q", name)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO climate_normals (
+		city_name, country_code, month, mean_temp, rain_days, sunshine_hours, mean_wind_speed
+	) VALUES (?, ?, ?, ?, ?, ?, ?);`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stmt.Close()
+
+	imported := 0
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Line %d: %v", line, err)
+		}
+
+		normal, err := parseNormal(record, columns)
+		if err != nil {
+			log.Fatalf("Line %d: %v", line, err)
+		}
+
+		_, err = stmt.Exec(normal.City, normal.Country, normal.Month, normal.MeanTemp, normal.RainDays, normal.SunshineHours, normal.MeanWindSpeed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Imported %d climate normals from %s\n", imported, *csvPath)
+}
+
+// ClimateNormal is one city's long-term average for one calendar month
+type ClimateNormal struct {
+	City          string
+	Country       string
+	Month         int
+	MeanTemp      float64
+	RainDays      float64
+	SunshineHours float64
+	MeanWindSpeed sql.NullFloat64
+}
+
+func parseNormal(record []string, columns map[string]int) (ClimateNormal, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	number := func(name string) (float64, error) {
+		value, err := strconv.ParseFloat(field(name), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", name, field(name))
+		}
+		return value, nil
+	}
+
+	normal := ClimateNormal{City: field("city"), Country: strings.ToUpper(field("country"))}
+	if normal.City == "" || normal.Country == "" {
+		return normal, fmt.Errorf("city and country are required")
+	}
+
+	month, err := strconv.Atoi(field("month"))
+	if err != nil || month < 1 || month > 12 {
+		return normal, fmt.Errorf("invalid month %q", field("month"))
+	}
+	normal.Month = month
+
+	if normal.MeanTemp, err = number("mean_temp"); err != nil {
+		return normal, err
+	}
+	if normal.RainDays, err = number("rain_days"); err != nil {
+		return normal, err
+	}
+	if normal.SunshineHours, err = number("sunshine_hours"); err != nil {
+		return normal, err
+	}
+	if field("mean_wind_speed") != "" {
+		wind, err := number("mean_wind_speed")
+		if err != nil {
+			return normal, err
+		}
+		normal.MeanWindSpeed = sql.NullFloat64{Float64: wind, Valid: true}
+	}
+
+	return normal, nil
+}
@@ -25,7 +25,15 @@ var tableSchemas = map[string]string{
 		city TEXT NOT NULL,
 		country TEXT NOT NULL,
 		booking_url TEXT,
-		booking_pppn REAL NOT NULL
+		booking_pppn REAL NOT NULL,
+		booking_pppn_source TEXT DEFAULT 'observed',
+		booking_pppn_p10 REAL,
+		booking_pppn_p50 REAL,
+		booking_pppn_p90 REAL,
+		booking_pppn_budget REAL,
+		booking_pppn_mid_range REAL,
+		booking_pppn_premium REAL,
+		booking_properties INTEGER
 	)`,
 	"five_nights_and_flights": `
 	CREATE TABLE five_nights_and_flights (
@@ -33,7 +41,10 @@ var tableSchemas = map[string]string{
 		origin_country TEXT,
 		destination_city TEXT,
 		destination_country TEXT,
-		price_fnaf REAL
+		price_fnaf REAL,
+		price_fnaf_budget REAL,
+		price_fnaf_mid_range REAL,
+		price_fnaf_premium REAL
 	)`,
 	"flight": `
 	CREATE TABLE flight (
@@ -53,7 +64,17 @@ var tableSchemas = map[string]string{
 	"duration_in_minutes"	DECIMAL,
   "duration_in_hours"	DECIMAL,
   "duration_in_hours_rounded"	DECIMAL,
-  "duration_hour_dot_mins" REAL
+  "duration_hour_dot_mins" REAL,
+		outbound_date DATE,
+		outbound_price DECIMAL,
+		outbound_duration_mins INTEGER,
+		return_date DATE,
+		return_price DECIMAL,
+		return_duration_mins INTEGER,
+		price_source TEXT DEFAULT 'predicted',
+		price_observed_at TEXT,
+		price_low DECIMAL,
+		price_high DECIMAL
 	)`,
 	"location": `
 	CREATE TABLE location (
@@ -77,7 +98,74 @@ var tableSchemas = map[string]string{
 		avg_daytime_temp FLOAT(10,1),
 		weather_icon VARCHAR(255),
 		google_url VARCHAR(255),
-		avg_daytime_wpi FLOAT(10,1)
+		avg_daytime_wpi FLOAT(10,1),
+		source VARCHAR(16) DEFAULT 'forecast',
+		avg_wind_speed FLOAT(10,1),
+		avg_precipitation_probability FLOAT(10,1),
+		avg_humidity FLOAT(10,1),
+		avg_cloud_cover FLOAT(10,1),
+		weather_condition VARCHAR(32),
+		temperature_score FLOAT(10,1),
+		wind_score FLOAT(10,1),
+		condition_score FLOAT(10,1),
+		precipitation_score FLOAT(10,1),
+		humidity_score FLOAT(10,1),
+		cloud_cover_score FLOAT(10,1),
+		forecast_confidence FLOAT(10,2),
+		sunrise VARCHAR(5),
+		sunset VARCHAR(5),
+		daylight_hours FLOAT(10,1)
+	)`,
+	"air_quality": `
+	CREATE TABLE air_quality (
+		city VARCHAR(255),
+		country VARCHAR(255),
+		date DATE,
+		aqi INTEGER,
+		pm2_5 FLOAT(10,2),
+		pm10 FLOAT(10,2),
+		PRIMARY KEY (city, country, date)
+	)`,
+	"route_price_by_date": `
+	CREATE TABLE route_price_by_date (
+		origin_city_name TEXT,
+		origin_country TEXT,
+		origin_iata TEXT,
+		destination_city_name TEXT,
+		destination_country TEXT,
+		destination_iata TEXT,
+		leg TEXT,
+		date DATE,
+		price DECIMAL,
+		fetched_at TEXT
+	)`,
+	"route_price_history": `
+	CREATE TABLE route_price_history (
+		origin_city_name TEXT,
+		origin_country TEXT,
+		origin_iata TEXT,
+		destination_city_name TEXT,
+		destination_country TEXT,
+		destination_iata TEXT,
+		date DATE,
+		price DECIMAL
+	)`,
+	"route_price_trend": `
+	CREATE TABLE route_price_trend (
+		origin_city_name TEXT,
+		origin_country TEXT,
+		origin_iata TEXT,
+		destination_city_name TEXT,
+		destination_country TEXT,
+		destination_iata TEXT,
+		latest_date DATE,
+		latest_price DECIMAL,
+		min_7d DECIMAL,
+		median_7d DECIMAL,
+		min_30d DECIMAL,
+		median_30d DECIMAL,
+		observations_30d INTEGER,
+		direction TEXT
 	)`,
 }
 